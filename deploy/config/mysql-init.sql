@@ -1 +1,3 @@
-CREATE DATABASE IF NOT EXISTS auth;
\ No newline at end of file
+CREATE DATABASE IF NOT EXISTS auth;
+CREATE DATABASE IF NOT EXISTS archive;
+CREATE DATABASE IF NOT EXISTS chat;
\ No newline at end of file
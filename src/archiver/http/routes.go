@@ -0,0 +1,134 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	pkgarchiver "github.com/faustuzas/occa/src/pkg/archiver"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	httpmiddleware "github.com/faustuzas/occa/src/pkg/http/middleware"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+type Services struct {
+	Archive pkgarchive.Store
+
+	// ServiceMiddleware authorizes the other services, the archiver is not exposed to users
+	ServiceMiddleware httpmiddleware.Middleware
+
+	Logger   *zap.Logger
+	Registry *prometheus.Registry
+}
+
+func Configure(s Services) (http.Handler, error) {
+	rawRouter := pkghttp.NewRouterBuilder(s.Logger)
+	rawRouter.HandleFunc("/metrics", promhttp.HandlerFor(s.Registry, promhttp.HandlerOpts{}).ServeHTTP).
+		Methods(http.MethodGet)
+
+	instrumentedRouter := rawRouter.SubGroup().
+		With(httpmiddleware.BasicMetrics(s.Registry), httpmiddleware.RequestLogger(s.Logger))
+
+	instrumentedRouter.HandleJSONFunc("/health", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodGet)
+
+	// callers act on behalf of users, so they are trusted to pass the right user IDs
+	serviceRouter := instrumentedRouter.SubGroup().
+		With(s.ServiceMiddleware)
+
+	serviceRouter.HandleJSONFunc("/messages", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req pkgarchiver.StoreMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, pkgerrors.BadRequest(err)
+		}
+
+		sentAt := req.SentAt
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+
+		msg, err := s.Archive.Store(r.Context(), pkgarchive.Message{
+			SenderID:    req.SenderID,
+			RecipientID: req.RecipientID,
+			Content:     req.Content,
+			SentAt:      sentAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("storing message: %w", err)
+		}
+
+		return msg, nil
+	}).Methods(http.MethodPost)
+
+	serviceRouter.HandleJSONFunc("/messages/{id}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		id, err := pkgid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			return nil, pkgerrors.BadRequest(fmt.Errorf("invalid id: %w", err))
+		}
+
+		msg, err := s.Archive.Message(r.Context(), id)
+		if errors.Is(err, pkgarchive.ErrMessageNotFound) {
+			return nil, pkgerrors.NotFound(err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetching message: %w", err)
+		}
+
+		return msg, nil
+	}).Methods(http.MethodGet)
+
+	serviceRouter.HandleJSONFunc("/history", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		query, err := parseHistoryQuery(r)
+		if err != nil {
+			return nil, pkgerrors.BadRequest(err)
+		}
+
+		page, err := s.Archive.History(r.Context(), query)
+		if err != nil {
+			return nil, fmt.Errorf("fetching history: %w", err)
+		}
+
+		return page, nil
+	}).Methods(http.MethodGet)
+
+	return rawRouter.Build(), nil
+}
+
+func parseHistoryQuery(r *http.Request) (pkgarchive.HistoryQuery, error) {
+	values := r.URL.Query()
+
+	userID, err := pkgid.Parse(values.Get("user"))
+	if err != nil {
+		return pkgarchive.HistoryQuery{}, fmt.Errorf("invalid user parameter: %w", err)
+	}
+
+	withID, err := pkgid.Parse(values.Get("with"))
+	if err != nil {
+		return pkgarchive.HistoryQuery{}, fmt.Errorf("invalid with parameter: %w", err)
+	}
+
+	query := pkgarchive.HistoryQuery{
+		UserID: userID,
+		WithID: withID,
+		Before: values.Get("before"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return pkgarchive.HistoryQuery{}, fmt.Errorf("invalid limit parameter: %w", err)
+		}
+	}
+
+	return query, nil
+}
@@ -0,0 +1,89 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/faustuzas/occa/src/archiver/http"
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgconfig "github.com/faustuzas/occa/src/pkg/config"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	pkgnet "github.com/faustuzas/occa/src/pkg/net"
+)
+
+type Configuration struct {
+	pkgconfig.CommonConfiguration `yaml:",inline"`
+
+	HTTPListenAddress *pkgnet.ListenAddr `yaml:"listenAddress"`
+
+	Store pkgarchive.Configuration `yaml:"store"`
+
+	ServiceAuth pkgauth.ServiceAuthConfiguration `yaml:"serviceAuth"`
+}
+
+type Params struct {
+	Configuration
+
+	Logger *zap.Logger
+
+	CloseCh <-chan struct{}
+}
+
+// Start starts archiver server and blocks until close request is received.
+// Returns error in case initialisation failed.
+func Start(p Params) error {
+	services, err := p.StartServices()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err = services.CloseWithTimeout(15 * time.Second); err != nil {
+			p.Logger.Error("error while closing services", zap.Error(err))
+		}
+	}()
+
+	routes, err := http.Configure(http.Services{
+		Archive:           services.Archive,
+		ServiceMiddleware: services.ServiceMiddleware,
+		Logger:            p.Logger,
+		Registry:          services.MetricsRegistry,
+	})
+	if err != nil {
+		return fmt.Errorf("configuring routes: %v", err)
+	}
+
+	httpListener, err := p.HTTPListenAddress.Listener()
+	if err != nil {
+		return fmt.Errorf("binding to address: %w", err)
+	}
+
+	defer func() {
+		_ = httpListener.Close()
+	}()
+
+	var (
+		srv      = pkghttp.NewServer(p.Logger, httpListener, routes)
+		srvErrCh = make(chan error, 1)
+	)
+	go func() {
+		p.Logger.Info("starting server", zap.Stringer("address", p.HTTPListenAddress))
+
+		srvErrCh <- srv.Start()
+	}()
+
+	select {
+	case <-p.CloseCh:
+		p.Logger.Info("received close request, terminating")
+		if err = srv.Shutdown(context.Background()); err != nil {
+			return fmt.Errorf("shuting down server: %w", err)
+		}
+	case err = <-srvErrCh:
+		return fmt.Errorf("starting server: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+
+	multierr "github.com/hashicorp/go-multierror"
+	"github.com/prometheus/client_golang/prometheus"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	httpmiddleware "github.com/faustuzas/occa/src/pkg/http/middleware"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgio "github.com/faustuzas/occa/src/pkg/io"
+)
+
+type Services struct {
+	pkgio.Closers
+
+	Archive pkgarchive.Store
+
+	ServiceMiddleware httpmiddleware.Middleware
+
+	MetricsRegistry *prometheus.Registry
+}
+
+func (p Params) StartServices() (_ Services, err error) {
+	var (
+		registry = prometheus.NewRegistry()
+
+		inst = pkginstrument.Instrumentation{
+			Logger:     p.Logger,
+			Registerer: registry,
+		}
+
+		starters pkgio.Starters
+		closers  pkgio.Closers
+	)
+	defer func() {
+		if err != nil {
+			err = multierr.Append(err, closers.Close(context.Background()))
+		}
+	}()
+
+	serviceMiddleware, err := p.Configuration.ServiceAuth.BuildHTTPMiddleware(inst)
+	if err != nil {
+		return Services{}, fmt.Errorf("building HTTP service auth middleware: %w", err)
+	}
+
+	archive, err := p.Configuration.Store.Build()
+	if err != nil {
+		return Services{}, fmt.Errorf("building archive store: %w", err)
+	}
+	starters = append(starters, archive)
+	closers = append(closers, archive)
+
+	if err = starters.Start(context.Background()); err != nil {
+		return Services{}, fmt.Errorf("starting services: %w", err)
+	}
+
+	return Services{
+		Archive:           archive,
+		ServiceMiddleware: serviceMiddleware,
+		MetricsRegistry:   registry,
+
+		Closers: closers,
+	}, nil
+}
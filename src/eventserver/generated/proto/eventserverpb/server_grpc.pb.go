@@ -20,7 +20,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	EventServer_Connect_FullMethodName = "/eventserverpb.EventServer/Connect"
+	EventServer_Connect_FullMethodName     = "/eventserverpb.EventServer/Connect"
+	EventServer_Deliver_FullMethodName     = "/eventserverpb.EventServer/Deliver"
+	EventServer_DeliverMany_FullMethodName = "/eventserverpb.EventServer/DeliverMany"
 )
 
 // EventServerClient is the client API for EventServer service.
@@ -28,6 +30,12 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type EventServerClient interface {
 	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (EventServer_ConnectClient, error)
+	// Deliver sends the event to the connected recipient. It is internal, called by
+	// gateways with a service credential. Fails with NOT_FOUND if the recipient is not
+	// connected to the server.
+	Deliver(ctx context.Context, in *DeliverRequest, opts ...grpc.CallOption) (*DeliverResponse, error)
+	// DeliverMany sends multiple events in one call, each of them is delivered independently.
+	DeliverMany(ctx context.Context, in *DeliverManyRequest, opts ...grpc.CallOption) (*DeliverManyResponse, error)
 }
 
 type eventServerClient struct {
@@ -70,11 +78,35 @@ func (x *eventServerConnectClient) Recv() (*rteventspb.Event, error) {
 	return m, nil
 }
 
+func (c *eventServerClient) Deliver(ctx context.Context, in *DeliverRequest, opts ...grpc.CallOption) (*DeliverResponse, error) {
+	out := new(DeliverResponse)
+	err := c.cc.Invoke(ctx, EventServer_Deliver_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServerClient) DeliverMany(ctx context.Context, in *DeliverManyRequest, opts ...grpc.CallOption) (*DeliverManyResponse, error) {
+	out := new(DeliverManyResponse)
+	err := c.cc.Invoke(ctx, EventServer_DeliverMany_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EventServerServer is the server API for EventServer service.
 // All implementations must embed UnimplementedEventServerServer
 // for forward compatibility
 type EventServerServer interface {
 	Connect(*ConnectRequest, EventServer_ConnectServer) error
+	// Deliver sends the event to the connected recipient. It is internal, called by
+	// gateways with a service credential. Fails with NOT_FOUND if the recipient is not
+	// connected to the server.
+	Deliver(context.Context, *DeliverRequest) (*DeliverResponse, error)
+	// DeliverMany sends multiple events in one call, each of them is delivered independently.
+	DeliverMany(context.Context, *DeliverManyRequest) (*DeliverManyResponse, error)
 	mustEmbedUnimplementedEventServerServer()
 }
 
@@ -85,6 +117,12 @@ type UnimplementedEventServerServer struct {
 func (UnimplementedEventServerServer) Connect(*ConnectRequest, EventServer_ConnectServer) error {
 	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
+func (UnimplementedEventServerServer) Deliver(context.Context, *DeliverRequest) (*DeliverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deliver not implemented")
+}
+func (UnimplementedEventServerServer) DeliverMany(context.Context, *DeliverManyRequest) (*DeliverManyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeliverMany not implemented")
+}
 func (UnimplementedEventServerServer) mustEmbedUnimplementedEventServerServer() {}
 
 // UnsafeEventServerServer may be embedded to opt out of forward compatibility for this service.
@@ -119,13 +157,58 @@ func (x *eventServerConnectServer) Send(m *rteventspb.Event) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _EventServer_Deliver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeliverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServerServer).Deliver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventServer_Deliver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServerServer).Deliver(ctx, req.(*DeliverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventServer_DeliverMany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeliverManyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServerServer).DeliverMany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventServer_DeliverMany_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServerServer).DeliverMany(ctx, req.(*DeliverManyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // EventServer_ServiceDesc is the grpc.ServiceDesc for EventServer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var EventServer_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "eventserverpb.EventServer",
 	HandlerType: (*EventServerServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Deliver",
+			Handler:    _EventServer_Deliver_Handler,
+		},
+		{
+			MethodName: "DeliverMany",
+			Handler:    _EventServer_DeliverMany_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Connect",
@@ -2,14 +2,20 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	grpcprom "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/faustuzas/occa/src/eventserver/generated/proto/eventserverpb"
 	"github.com/faustuzas/occa/src/eventserver/services"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
 	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkggrpc "github.com/faustuzas/occa/src/pkg/grpc"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
@@ -21,6 +27,9 @@ type Services struct {
 	EventServer           services.EventServer
 	StreamAuthInterceptor grpc.StreamServerInterceptor
 
+	// ServiceAuthInterceptor guards the internal unary calls, which are made by other services
+	ServiceAuthInterceptor grpc.UnaryServerInterceptor
+
 	Instrumentation pkginstrument.Instrumentation
 }
 
@@ -33,6 +42,10 @@ func Configure(services Services) (*grpc.Server, error) {
 			metrics.StreamServerInterceptor(),
 			services.StreamAuthInterceptor,
 		),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			services.ServiceAuthInterceptor,
+		),
 	)
 
 	server := NewServer(services.Instrumentation, services.EventServer)
@@ -59,9 +72,53 @@ type Server struct {
 func (s *Server) Connect(req *eventserverpb.ConnectRequest, server eventserverpb.EventServer_ConnectServer) error {
 	id := pkgid.FromString(req.UserId)
 
-	s.i.Logger.Info("new gRPC-based user connected", zap.Stringer("id", id))
+	s.i.Logger.Info("new gRPC-based user connected", zap.Stringer("id", id),
+		zap.String("deviceId", req.DeviceId), zap.Uint64("resumeFromSequence", req.ResumeFromSequence))
+
+	opts := services.ConnectOptions{
+		ResumeAfterSequence: req.ResumeFromSequence,
+		DeviceID:            req.DeviceId,
+	}
+
+	// stream context is cancelled once the client goes away or the transport breaks
+	return s.eventServer.ServeConnection(server.Context(), id, NewGRPCConnection(server), opts)
+}
+
+func (s *Server) Deliver(ctx context.Context, req *eventserverpb.DeliverRequest) (*eventserverpb.DeliverResponse, error) {
+	if err := s.deliver(ctx, req); err != nil {
+		if errors.Is(err, services.ErrRecipientNotConnected) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, pkggrpc.Error(err)
+	}
+	return &eventserverpb.DeliverResponse{}, nil
+}
+
+func (s *Server) DeliverMany(ctx context.Context, req *eventserverpb.DeliverManyRequest) (*eventserverpb.DeliverManyResponse, error) {
+	resp := &eventserverpb.DeliverManyResponse{
+		Results: make([]*eventserverpb.DeliveryResult, 0, len(req.Deliveries)),
+	}
+	for _, delivery := range req.Deliveries {
+		result := &eventserverpb.DeliveryResult{}
+		if err := s.deliver(ctx, delivery); err != nil {
+			result.Error = err.Error()
+			result.RecipientNotConnected = errors.Is(err, services.ErrRecipientNotConnected)
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+func (s *Server) deliver(ctx context.Context, req *eventserverpb.DeliverRequest) error {
+	recipientID, err := pkgid.Parse(req.RecipientId)
+	if err != nil {
+		return pkgerrors.BadRequest(fmt.Errorf("parsing recipient id: %w", err))
+	}
+	if req.Event == nil {
+		return pkgerrors.BadRequest(fmt.Errorf("missing event"))
+	}
 
-	return s.eventServer.ServeConnection(id, NewGRPCConnection(server))
+	return s.eventServer.SendEvent(ctx, recipientID, req.Event)
 }
 
 type grpcConnection struct {
@@ -74,13 +131,6 @@ func NewGRPCConnection(sender eventserverpb.EventServer_ConnectServer) services.
 	}
 }
 
-func (g grpcConnection) SendEvent(_ context.Context, msg services.Event) error {
-	return g.sender.Send(&rteventspb.Event{
-		Payload: &rteventspb.Event_DirectMessage{
-			DirectMessage: &rteventspb.DirectMessage{
-				SenderId: msg.SenderID.String(),
-				Message:  msg.Content,
-			},
-		},
-	})
+func (g grpcConnection) SendEvent(_ context.Context, event *rteventspb.Event) error {
+	return g.sender.Send(event)
 }
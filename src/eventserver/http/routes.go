@@ -1,24 +1,22 @@
 package http
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/faustuzas/occa/src/eventserver/services"
-	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
 	pkghttp "github.com/faustuzas/occa/src/pkg/http"
 	httpmiddleware "github.com/faustuzas/occa/src/pkg/http/middleware"
 )
 
 type Services struct {
-	EventServer        services.EventServer
-	HTTPAuthMiddleware httpmiddleware.Middleware
+	EventServer          services.EventServer
+	StreamAuthMiddleware httpmiddleware.Middleware
+
+	WebSocket WebSocketConfiguration
 
 	Logger   *zap.Logger
 	Registry *prometheus.Registry
@@ -36,27 +34,15 @@ func Configure(s Services) (http.Handler, error) {
 		return pkghttp.DefaultOKResponse(), nil
 	}).Methods(http.MethodGet)
 
-	authenticatedRouter := instrumentedRouter.SubGroup().
-		With(s.HTTPAuthMiddleware)
-
-	// TODO: protobuf alternative should be added too
-	authenticatedRouter.HandleJSONFunc("/send-message", func(w http.ResponseWriter, r *http.Request) (any, error) {
-		var msg SendMessageRequest
-		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
-			return nil, err
-		}
-
-		if err := s.EventServer.SendEvent(r.Context(), services.Event{
-			SenderID:    pkgauth.PrincipalFromContext(r.Context()).ID,
-			RecipientID: msg.RecipientID,
-			Content:     msg.Content,
-			SentAt:      time.Now(),
-		}); err != nil {
-			return nil, fmt.Errorf("sending message: %w", err)
-		}
+	// streams are kept out of request metrics: their durations are meaningless and
+	// the metrics response writer hides the controls needed to manage write deadlines
+	streamRouter := rawRouter.SubGroup().
+		With(httpmiddleware.RequestLogger(s.Logger), s.StreamAuthMiddleware)
 
-		return pkghttp.DefaultOKResponse(), nil
-	}).Methods(http.MethodPost)
+	streamRouter.HandleFunc("/ws", serveWebSocket(s, s.WebSocket.upgrader())).
+		Methods(http.MethodGet)
+	streamRouter.HandleFunc("/events", serveSSE(s)).
+		Methods(http.MethodGet)
 
 	return rawRouter.Build(), nil
 }
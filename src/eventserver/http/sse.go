@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/faustuzas/occa/src/eventserver/services"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+)
+
+const (
+	sseWriteTimeout = 10 * time.Second
+
+	// sseKeepAliveInterval has to be shorter than idle timeouts of proxies in front of the server.
+	sseKeepAliveInterval = 15 * time.Second
+)
+
+// parseLastEventID reads the ID of the last event the client has seen. Browsers send
+// it in the header on reconnect, the query parameter is for clients which cannot set headers.
+func parseLastEventID(r *http.Request) (uint64, error) {
+	str := r.Header.Get("Last-Event-ID")
+	if str == "" {
+		str = r.URL.Query().Get("lastEventId")
+	}
+	if str == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event id %q", str)
+	}
+	return id, nil
+}
+
+func serveSSE(s Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lastEventID, err := parseLastEventID(r)
+		if err != nil {
+			pkghttp.RespondWithJSONError(s.Logger, w, pkgerrors.BadRequest(err))
+			return
+		}
+
+		// the stream outlives the write timeout of the server, deadlines are set per write instead
+		controller := http.NewResponseController(w)
+		if err = controller.SetWriteDeadline(time.Time{}); err != nil {
+			pkghttp.RespondWithJSONError(s.Logger, w, fmt.Errorf("clearing write deadline: %w", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// prevents nginx from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		ctx, cancel := context.WithCancel(r.Context())
+		sseConn := newSSEConnection(w, controller, cancel)
+		defer sseConn.close()
+
+		if err = sseConn.flush(); err != nil {
+			s.Logger.Warn("failed to start SSE stream", zap.Error(err))
+			return
+		}
+
+		go sseConn.keepAliveLoop(ctx)
+
+		var (
+			principal = pkgauth.PrincipalFromContext(r.Context())
+			deviceID  = r.URL.Query().Get("deviceId")
+		)
+		s.Logger.Info("new SSE-based user connected", zap.Stringer("id", principal.ID),
+			zap.String("deviceId", deviceID), zap.Uint64("lastEventId", lastEventID))
+
+		opts := services.ConnectOptions{ResumeAfterSequence: lastEventID, DeviceID: deviceID}
+		if err = s.EventServer.ServeConnection(ctx, principal.ID, sseConn, opts); err != nil {
+			s.Logger.Warn("serving SSE connection failed", zap.Stringer("id", principal.ID), zap.Error(err))
+		}
+	}
+}
+
+type sseConnection struct {
+	// response writer must not be used concurrently nor after the handler returns
+	mu         sync.Mutex
+	closed     bool
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	cancel     context.CancelFunc
+}
+
+var _ services.Connection = (*sseConnection)(nil)
+
+func newSSEConnection(w http.ResponseWriter, controller *http.ResponseController, cancel context.CancelFunc) *sseConnection {
+	return &sseConnection{
+		w:          w,
+		controller: controller,
+		cancel:     cancel,
+	}
+}
+
+func (c *sseConnection) SendEvent(ctx context.Context, event *rteventspb.Event) error {
+	data, err := protojson.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sseWriteTimeout)
+	}
+
+	// control events are not sequenced, sending them without an id keeps the
+	// last event id of the client intact
+	var frame string
+	if event.Sequence > 0 {
+		frame = fmt.Sprintf("id: %d\n", event.Sequence)
+	}
+	// compact protojson output never contains new lines, so a single data line is enough
+	frame += fmt.Sprintf("data: %s\n\n", data)
+
+	return c.write(deadline, frame)
+}
+
+// keepAliveLoop periodically sends comments, so idle streams are not cut by intermediaries.
+func (c *sseConnection) keepAliveLoop(ctx context.Context) {
+	ticker := time.NewTicker(sseKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(time.Now().Add(sseWriteTimeout), ": keep-alive\n\n"); err != nil {
+				c.close()
+				return
+			}
+		case <-ctx.Done():
+			c.close()
+			return
+		}
+	}
+}
+
+func (c *sseConnection) write(deadline time.Time, frame string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("SSE stream is closed")
+	}
+
+	if err := c.controller.SetWriteDeadline(deadline); err != nil {
+		return fmt.Errorf("setting write deadline: %w", err)
+	}
+
+	if _, err := c.w.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return c.controller.Flush()
+}
+
+func (c *sseConnection) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.controller.Flush()
+}
+
+func (c *sseConnection) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.cancel()
+}
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/eventserver/services"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+)
+
+const (
+	webSocketWriteTimeout = 10 * time.Second
+	webSocketPongTimeout  = 60 * time.Second
+	webSocketPingInterval = webSocketPongTimeout * 9 / 10
+
+	// Clients are not expected to send anything apart from control frames.
+	webSocketMaxMessageSize = 4096
+)
+
+type WebSocketConfiguration struct {
+	// AllowedOrigins lists origins which are allowed to open WebSocket connections.
+	// If empty, only same-origin requests are accepted.
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+}
+
+func (c WebSocketConfiguration) upgrader() *websocket.Upgrader {
+	upgrader := &websocket.Upgrader{}
+	if len(c.AllowedOrigins) == 0 {
+		return upgrader
+	}
+
+	allowed := map[string]struct{}{}
+	for _, o := range c.AllowedOrigins {
+		allowed[o] = struct{}{}
+	}
+
+	upgrader.CheckOrigin = func(r *http.Request) bool {
+		if _, ok := allowed["*"]; ok {
+			return true
+		}
+		_, ok := allowed[r.Header.Get("Origin")]
+		return ok
+	}
+	return upgrader
+}
+
+type frameFormat string
+
+const (
+	frameFormatJSON     frameFormat = "json"
+	frameFormatProtobuf frameFormat = "protobuf"
+)
+
+func parseFrameFormat(str string) (frameFormat, error) {
+	switch frameFormat(str) {
+	case "", frameFormatJSON:
+		return frameFormatJSON, nil
+	case frameFormatProtobuf:
+		return frameFormatProtobuf, nil
+	default:
+		return "", fmt.Errorf("unsupported frame format %q", str)
+	}
+}
+
+func (f frameFormat) encode(event *rteventspb.Event) (int, []byte, error) {
+	if f == frameFormatProtobuf {
+		data, err := proto.Marshal(event)
+		return websocket.BinaryMessage, data, err
+	}
+
+	data, err := protojson.Marshal(event)
+	return websocket.TextMessage, data, err
+}
+
+func serveWebSocket(s Services, upgrader *websocket.Upgrader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := parseFrameFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			pkghttp.RespondWithJSONError(s.Logger, w, pkgerrors.BadRequest(err))
+			return
+		}
+
+		// upgrader responds to the client by itself in case of a failure
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			s.Logger.Warn("failed to upgrade to WebSocket connection", zap.Error(err))
+			return
+		}
+
+		// hijacked connections are not tracked by the HTTP server anymore, so the
+		// connection has to cancel the context itself once it is closed
+		ctx, cancel := context.WithCancel(r.Context())
+		wsConn := newWebSocketConnection(conn, format, cancel)
+		defer wsConn.close()
+
+		go wsConn.readLoop()
+		go wsConn.pingLoop()
+
+		var (
+			principal = pkgauth.PrincipalFromContext(r.Context())
+			deviceID  = r.URL.Query().Get("deviceId")
+		)
+		s.Logger.Info("new WebSocket-based user connected", zap.Stringer("id", principal.ID), zap.String("deviceId", deviceID))
+
+		if err = s.EventServer.ServeConnection(ctx, principal.ID, wsConn, services.ConnectOptions{DeviceID: deviceID}); err != nil {
+			s.Logger.Warn("serving WebSocket connection failed", zap.Stringer("id", principal.ID), zap.Error(err))
+		}
+	}
+}
+
+type webSocketConnection struct {
+	// gorilla connections support only one concurrent writer
+	writeMu sync.Mutex
+	conn    *websocket.Conn
+	format  frameFormat
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	cancel    context.CancelFunc
+}
+
+var _ services.Connection = (*webSocketConnection)(nil)
+
+func newWebSocketConnection(conn *websocket.Conn, format frameFormat, cancel context.CancelFunc) *webSocketConnection {
+	return &webSocketConnection{
+		conn:    conn,
+		format:  format,
+		closeCh: make(chan struct{}),
+		cancel:  cancel,
+	}
+}
+
+func (c *webSocketConnection) SendEvent(ctx context.Context, event *rteventspb.Event) error {
+	msgType, data, err := c.format.encode(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(webSocketWriteTimeout)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err = c.conn.SetWriteDeadline(deadline); err != nil {
+		return fmt.Errorf("setting write deadline: %w", err)
+	}
+
+	return c.conn.WriteMessage(msgType, data)
+}
+
+// readLoop consumes incoming frames so control messages (pongs, close) are processed.
+func (c *webSocketConnection) readLoop() {
+	defer c.close()
+
+	c.conn.SetReadLimit(webSocketMaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	})
+
+	for {
+		if _, _, err := c.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *webSocketConnection) pingLoop() {
+	ticker := time.NewTicker(webSocketPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout)); err != nil {
+				c.close()
+				return
+			}
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+func (c *webSocketConnection) close() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+		c.cancel()
+		_ = c.conn.Close()
+	})
+}
@@ -11,10 +11,12 @@ import (
 
 	esgrpc "github.com/faustuzas/occa/src/eventserver/grpc"
 	"github.com/faustuzas/occa/src/eventserver/http"
+	esservices "github.com/faustuzas/occa/src/eventserver/services"
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
 	pkgconfig "github.com/faustuzas/occa/src/pkg/config"
 	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
 	esmembership "github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/eventserver/pending"
 	pkghttp "github.com/faustuzas/occa/src/pkg/http"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
@@ -24,21 +26,42 @@ import (
 type Configuration struct {
 	pkgconfig.CommonConfiguration `yaml:",inline"`
 
-	Auth pkgauth.ValidatorConfiguration `yaml:"auth"`
+	Auth        pkgauth.ValidatorConfiguration   `yaml:"auth"`
+	ServiceAuth pkgauth.ServiceAuthConfiguration `yaml:"serviceAuth"`
 
 	HTTPListenAddress *pkgnet.ListenAddr `yaml:"httpListenAddress"`
 	GRPCListenAddress *pkgnet.ListenAddr `yaml:"grpcListenAddress"`
 
-	Etcd     pkgetcd.Configuration     `yaml:"etcd"`
-	MemStore pkgmemstore.Configuration `yaml:"memstore"`
+	Membership esmembership.Configuration `yaml:"membership"`
+	Etcd       pkgetcd.Configuration      `yaml:"etcd"`
+	MemStore   pkgmemstore.Configuration  `yaml:"memstore"`
 
 	ServerID string `yaml:"serverID"`
+
+	// Capacity is the number of connections the server is meant to handle. It is published
+	// to the cluster, so clients could be spread proportionally. Zero means unknown.
+	Capacity int `yaml:"capacity"`
+
+	WebSocket http.WebSocketConfiguration `yaml:"webSocket"`
+
+	Drain  esservices.DrainConfiguration  `yaml:"drain"`
+	Replay esservices.ReplayConfiguration `yaml:"replay"`
+	Outbox esservices.OutboxConfiguration `yaml:"outbox"`
+
+	// PendingEvents has to match the configuration of the gateways, which queue the events.
+	PendingEvents pending.Configuration `yaml:"pendingEvents"`
 }
 
 func (c Configuration) Validate() error {
 	if c.ServerID == "" {
 		return fmt.Errorf("server ID cannot be empty")
 	}
+	if err := c.Outbox.Validate(); err != nil {
+		return fmt.Errorf("validating outbox configuration: %w", err)
+	}
+	if err := c.Membership.Validate(); err != nil {
+		return fmt.Errorf("validating membership configuration: %w", err)
+	}
 	return nil
 }
 
@@ -80,10 +103,11 @@ func Start(p Params) error {
 	}()
 
 	httpHandler, err := http.Configure(http.Services{
-		EventServer:        services.EventServer,
-		HTTPAuthMiddleware: services.HTTPAuthMiddleware,
-		Logger:             p.Logger,
-		Registry:           services.MetricsRegistry,
+		EventServer:          services.EventServer,
+		StreamAuthMiddleware: services.HTTPStreamAuthMiddleware,
+		WebSocket:            p.WebSocket,
+		Logger:               p.Logger,
+		Registry:             services.MetricsRegistry,
 	})
 	if err != nil {
 		return fmt.Errorf("configuring http handler: %w", err)
@@ -100,8 +124,9 @@ func Start(p Params) error {
 
 	var grpcServer *grpc.Server
 	if grpcServer, err = esgrpc.Configure(esgrpc.Services{
-		EventServer:           services.EventServer,
-		StreamAuthInterceptor: services.GRPCStreamAuthInterceptor,
+		EventServer:            services.EventServer,
+		StreamAuthInterceptor:  services.GRPCStreamAuthInterceptor,
+		ServiceAuthInterceptor: services.GRPCServiceAuthInterceptor,
 		Instrumentation: pkginstrument.Instrumentation{
 			Logger:     p.Logger,
 			Registerer: services.MetricsRegistry,
@@ -125,10 +150,14 @@ func Start(p Params) error {
 
 	lostLeaseC, err := services.MembershipManager.JoinCluster(context.Background(),
 		func(ctx context.Context) (esmembership.ServerInfo, error) {
+			load := services.EventServer.Load()
+			load.Capacity = p.Capacity
+
 			return esmembership.ServerInfo{
 				ID:          p.ServerID,
 				GRPCAddress: p.Configuration.GRPCListenAddress.String(),
 				HTTPAddress: p.Configuration.HTTPListenAddress.String(),
+				Load:        load,
 			}, nil
 		})
 	if err != nil {
@@ -156,9 +185,14 @@ func Start(p Params) error {
 	// wait until first termination trigger
 	closeErr := <-closeCh
 
-	closeCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	closeCtx, cancel := context.WithTimeout(context.Background(), p.Drain.WithDefaults().Window+15*time.Second)
 	defer cancel()
 
+	// stop receiving new connections before asking the existing ones to move
+	if err = services.MembershipManager.MarkDraining(closeCtx); err != nil {
+		p.Logger.Error("failed marking server as draining", zap.Error(err))
+	}
+
 	if err = services.EventServer.InitiateShutdown(closeCtx); err != nil {
 		p.Logger.Error("failed initiation event server shutdown", zap.Error(err))
 	}
@@ -1,6 +1,7 @@
 package eventserver
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/faustuzas/occa/src/eventserver/services"
 	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
 	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
 	httpmiddleware "github.com/faustuzas/occa/src/pkg/http/middleware"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
@@ -17,8 +19,9 @@ import (
 type Services struct {
 	pkgio.Closers
 
-	HTTPAuthMiddleware        httpmiddleware.Middleware
-	GRPCStreamAuthInterceptor grpc.StreamServerInterceptor
+	HTTPStreamAuthMiddleware   httpmiddleware.Middleware
+	GRPCStreamAuthInterceptor  grpc.StreamServerInterceptor
+	GRPCServiceAuthInterceptor grpc.UnaryServerInterceptor
 
 	EventServer       services.EventServer
 	MembershipManager membership.Manager
@@ -38,29 +41,56 @@ func (p Params) StartServices() (Services, error) {
 		closers pkgio.Closers
 	)
 
-	etcdClient, err := p.Configuration.Etcd.Build()
-	if err != nil {
-		return Services{}, fmt.Errorf("building etcd client: %w", err)
-	}
-	closers = append(closers, pkgio.CloseWithoutContext(etcdClient.Close))
-
 	memstore, err := p.MemStore.Build()
 	if err != nil {
 		return Services{}, fmt.Errorf("building memstore client: %w", err)
 	}
 	closers = append(closers, pkgio.CloseWithoutContext(memstore.Close))
 
-	hearthBeater := rtconn.NewHeartBeater(inst, p.ServerID, memstore)
-	closers = append(closers, hearthBeater)
+	membershipBackend, err := p.Membership.Build(inst, p.Etcd, memstore)
+	if err != nil {
+		return Services{}, fmt.Errorf("building membership backend: %w", err)
+	}
+	// backend can be using the memstore, so it has to go before it
+	closers = append(pkgio.Closers{membershipBackend}, closers...)
 
-	eventServer, err := services.NewEventServer(inst, hearthBeater)
+	membershipManager, err := membership.NewManager(inst, membershipBackend)
+	if err != nil {
+		return Services{}, fmt.Errorf("creating membership manager: %w", err)
+	}
+
+	hearthBeater := rtconn.NewHeartBeater(inst, p.ServerID, membershipManager.Epoch, memstore)
+	// heart beater removes connection records when closed, so it has to go before the memstore
+	closers = append(pkgio.Closers{hearthBeater}, closers...)
+
+	pendingEvents := p.PendingEvents.Build(inst, memstore)
+
+	// registry is used to suggest other servers to clients while draining,
+	// where the spread matters more than exactness
+	serverRegistry, err := membership.NewServerRegistry(inst, membershipBackend, membership.PowerOfTwoChoices{})
+	if err != nil {
+		return Services{}, fmt.Errorf("building server registry: %w", err)
+	}
+	if err = serverRegistry.Start(context.Background()); err != nil {
+		return Services{}, fmt.Errorf("starting server registry: %w", err)
+	}
+	closers = append(pkgio.Closers{serverRegistry}, closers...)
+
+	// events are sequenced and stored for replay by the relays of the gateways, the server only reads them
+	storedEvents := esreplay.NewMemStoreLog(inst, memstore, esreplay.Configuration{})
+
+	eventServer, err := services.NewEventServer(inst, p.ServerID, hearthBeater, pendingEvents, serverRegistry, storedEvents, services.Configuration{
+		Drain:  p.Drain,
+		Replay: p.Replay,
+		Outbox: p.Outbox,
+	})
 	if err != nil {
 		return Services{}, fmt.Errorf("building events server: %w", err)
 	}
 
-	httpAuthMiddleware, err := p.Configuration.Auth.BuildHTTPMiddleware(inst)
+	httpStreamAuthMiddleware, err := p.Configuration.Auth.BuildHTTPStreamMiddleware(inst)
 	if err != nil {
-		return Services{}, fmt.Errorf("building HTTP auth middleware: %w", err)
+		return Services{}, fmt.Errorf("building HTTP stream auth middleware: %w", err)
 	}
 
 	grpcAuthMiddleware, err := p.Configuration.Auth.BuildGRPCStreamInterceptor(inst)
@@ -68,17 +98,18 @@ func (p Params) StartServices() (Services, error) {
 		return Services{}, fmt.Errorf("building gRPC auth middleware: %w", err)
 	}
 
-	membershipManager, err := membership.NewManager(inst, etcdClient)
+	grpcServiceAuthInterceptor, err := p.Configuration.ServiceAuth.BuildGRPCUnaryInterceptor(inst)
 	if err != nil {
-		return Services{}, fmt.Errorf("creating membership manager: %w", err)
+		return Services{}, fmt.Errorf("building gRPC service auth middleware: %w", err)
 	}
 
 	return Services{
-		HTTPAuthMiddleware:        httpAuthMiddleware,
-		GRPCStreamAuthInterceptor: grpcAuthMiddleware,
-		EventServer:               eventServer,
-		MembershipManager:         membershipManager,
-		MetricsRegistry:           registry,
-		Closers:                   closers,
+		HTTPStreamAuthMiddleware:   httpStreamAuthMiddleware,
+		GRPCStreamAuthInterceptor:  grpcAuthMiddleware,
+		GRPCServiceAuthInterceptor: grpcServiceAuthInterceptor,
+		EventServer:                eventServer,
+		MembershipManager:          membershipManager,
+		MetricsRegistry:            registry,
+		Closers:                    closers,
 	}, nil
 }
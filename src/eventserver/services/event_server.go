@@ -2,117 +2,522 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	multierr "github.com/hashicorp/go-multierror"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/eventserver/pending"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
 	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 )
 
-type Event struct {
-	SenderID    pkgid.ID  `json:"senderID"`
-	RecipientID pkgid.ID  `json:"recipientID"`
-	Content     string    `json:"content"`
-	SentAt      time.Time `json:"sentAt"`
-}
+// ErrRecipientNotConnected is returned when the recipient has no connections to this server,
+// e.g. because it has moved to another one.
+var ErrRecipientNotConnected = errors.New("recipient is not connected to this server")
 
 type Connection interface {
-	SendEvent(ctx context.Context, msg Event) error
+	SendEvent(ctx context.Context, event *rteventspb.Event) error
+}
+
+// ConnectOptions control how a newly established connection is served.
+type ConnectOptions struct {
+	// ResumeAfterSequence requests retained events with greater sequence numbers
+	// to be replayed before live delivery. Zero disables the replay.
+	ResumeAfterSequence uint64
+
+	// DeviceID identifies the session among other sessions of the same user. A new
+	// connection from the same device replaces the previous one. If empty, the
+	// connection gets a unique session.
+	DeviceID string
 }
 
 type EventServer interface {
-	SendEvent(ctx context.Context, msg Event) error
-	ServeConnection(id pkgid.ID, connection Connection) error
+	SendEvent(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error
+
+	// ServeConnection blocks until the connection terminates. The context has to be
+	// cancelled when the underlying transport is closed.
+	ServeConnection(ctx context.Context, id pkgid.ID, connection Connection, opts ConnectOptions) error
+
 	InitiateShutdown(ctx context.Context) error
+
+	// Load reports how busy the server is, so clients can be spread across the cluster.
+	Load() membership.ServerLoad
+}
+
+// Configuration groups tunables of the event server.
+type Configuration struct {
+	Drain  DrainConfiguration
+	Replay ReplayConfiguration
+	Outbox OutboxConfiguration
 }
 
-func NewEventServer(i pkginstrument.Instrumentation, heartBeater rtconn.HeartBeater) (EventServer, error) {
+const (
+	defaultDrainWindow = 10 * time.Second
+
+	// drainFlushTimeout is how long clients are given to receive reconnect events after the drain window.
+	drainFlushTimeout = 5 * time.Second
+)
+
+// DrainConfiguration controls how clients are moved away from the server when it shuts down.
+type DrainConfiguration struct {
+	// Window is the period over which reconnect requests are spread, so the clients
+	// do not overwhelm the remaining servers by reconnecting all at once.
+	Window time.Duration `yaml:"window"`
+}
+
+func (c DrainConfiguration) WithDefaults() DrainConfiguration {
+	if c.Window <= 0 {
+		c.Window = defaultDrainWindow
+	}
+
+	return c
+}
+
+type disconnectReason string
+
+const (
+	disconnectReasonClientGone disconnectReason = "client_gone"
+	disconnectReasonSendFailed disconnectReason = "send_failed"
+	disconnectReasonReplaced   disconnectReason = "replaced"
+	disconnectReasonShutdown   disconnectReason = "shutdown"
+	disconnectReasonSlowClient disconnectReason = "slow_client"
+)
+
+func NewEventServer(
+	i pkginstrument.Instrumentation,
+	serverID string,
+	heartBeater rtconn.HeartBeater,
+	pendingEvents pending.Queue,
+	serverSelector membership.ServerSelector,
+	stored esreplay.Log,
+	cfg Configuration,
+) (EventServer, error) {
+	disconnects := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "eventserver_disconnects_total",
+		Help: "Number of terminated user connections by the reason of termination.",
+	}, []string{"reason"})
+	if err := i.Registerer.Register(disconnects); err != nil {
+		return nil, fmt.Errorf("registering disconnects metric: %w", err)
+	}
+
+	outboxDepth := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "eventserver_outbox_depth",
+		Help: "Number of events queued for writing to all connections of the server.",
+	})
+	if err := i.Registerer.Register(outboxDepth); err != nil {
+		return nil, fmt.Errorf("registering outbox depth metric: %w", err)
+	}
+
+	outboxDrops := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "eventserver_outbox_dropped_events_total",
+		Help: "Number of events dropped because of full connection outboxes by the overflow policy.",
+	}, []string{"policy"})
+	if err := i.Registerer.Register(outboxDrops); err != nil {
+		return nil, fmt.Errorf("registering outbox drops metric: %w", err)
+	}
+
 	return &eventServer{
-		connections: map[pkgid.ID]privConn{},
-		heartBeater: heartBeater,
+		serverID:       serverID,
+		connections:    map[pkgid.ID]map[string]*privConn{},
+		userLocks:      newUserLocks(),
+		heartBeater:    heartBeater,
+		pendingEvents:  pendingEvents,
+		serverSelector: serverSelector,
+		replay:         newReplayLog(stored, cfg.Replay),
+		drain:          cfg.Drain.WithDefaults(),
+		outboxCfg:      cfg.Outbox.withDefaults(),
+		disconnects:    disconnects,
+		outboxDepth:    outboxDepth,
+		outboxDrops:    outboxDrops,
 
 		i: i,
 	}, nil
 }
 
 type privConn struct {
-	conn   Connection
-	waitCh chan struct{}
+	userID    pkgid.ID
+	conn      Connection
+	sessionID string
+
+	// outbox is written to the connection by a dedicated writer, so slow clients
+	// do not block senders
+	outbox *outbox
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	// reason is set once right before closeCh is closed
+	reason disconnectReason
+}
+
+func (c *privConn) terminate(reason disconnectReason) {
+	c.closeOnce.Do(func() {
+		c.reason = reason
+		close(c.closeCh)
+	})
 }
 
 type eventServer struct {
-	mu          sync.RWMutex
-	connections map[pkgid.ID]privConn
+	serverID string
+
+	mu sync.RWMutex
+	// connections holds sessions of every connected user keyed by their device IDs
+	connections map[pkgid.ID]map[string]*privConn
+	draining    bool
+
+	// userLocks order live events of the user against its new connections, so memstore
+	// calls made while connecting are not made while holding mu
+	userLocks *userLocks
 
-	heartBeater rtconn.HeartBeater
+	heartBeater    rtconn.HeartBeater
+	pendingEvents  pending.Queue
+	serverSelector membership.ServerSelector
+	replay         *replayLog
+	drain          DrainConfiguration
+	outboxCfg      OutboxConfiguration
+
+	disconnects *prometheus.CounterVec
+	outboxDepth prometheus.Gauge
+	outboxDrops *prometheus.CounterVec
+
+	// queued mirrors outboxDepth, which cannot be read back
+	queued atomic.Int64
 
 	i pkginstrument.Instrumentation
 }
 
-func (s *eventServer) ServeConnection(userID pkgid.ID, conn Connection) error {
-	pc := privConn{
-		conn:   conn,
-		waitCh: make(chan struct{}, 1),
+func (s *eventServer) ServeConnection(ctx context.Context, userID pkgid.ID, conn Connection, opts ConnectOptions) error {
+	sessionID := opts.DeviceID
+	if sessionID == "" {
+		sessionID = pkgid.NewID().String()
 	}
 
+	pc := &privConn{
+		userID:    userID,
+		conn:      conn,
+		sessionID: sessionID,
+		outbox:    newOutbox(s.outboxCfg),
+		closeCh:   make(chan struct{}),
+	}
+
+	unlockUser := s.userLocks.lock(userID)
+
 	s.mu.Lock()
-	s.connections[userID] = pc
-	// TODO: check if conn already exists
+	if s.draining {
+		s.mu.Unlock()
+		unlockUser()
+
+		// the client has been given this server before learning about the shutdown
+		if err := conn.SendEvent(ctx, s.reconnectEvent(ctx, userID)); err != nil {
+			return fmt.Errorf("redirecting connection of draining server: %w", err)
+		}
+		return nil
+	}
+
+	sessions, ok := s.connections[userID]
+	if !ok {
+		// the heartbeat is shared by all sessions of the user on this server, it is
+		// launched and stopped under the lock, so it always matches the sessions map
+		if err := s.heartBeater.LaunchForUser(userID); err != nil {
+			s.mu.Unlock()
+			unlockUser()
+			return fmt.Errorf("failed to launch heart beater: %w", err)
+		}
+
+		sessions = map[string]*privConn{}
+		s.connections[userID] = sessions
+	}
+	if previous, ok := sessions[sessionID]; ok {
+		s.i.Logger.Info("device reconnected, replacing its previous connection",
+			zap.Stringer("userId", userID), zap.String("deviceId", sessionID))
+		previous.terminate(disconnectReasonReplaced)
+	}
+	sessions[sessionID] = pc
 	s.mu.Unlock()
 
-	if err := s.heartBeater.LaunchForUser(userID); err != nil {
-		return fmt.Errorf("failed to launch heart beater")
+	// relays stop queueing events for the user once its connection record is stored
+	if err := s.heartBeater.AwaitRecord(ctx, userID); err != nil {
+		s.i.Logger.Warn("failed to await connection record", zap.Stringer("userId", userID), zap.Error(err))
 	}
 
-	// TODO: find where connection terminates
-	<-pc.waitCh
+	// live events are queued while holding the lock of the user, so the ones missed are
+	// exactly those relayed before the connection became visible; queueing them and the
+	// pending ones before releasing the lock puts them in front of any live events
+	replayed := opts.ResumeAfterSequence
+	if opts.ResumeAfterSequence > 0 {
+		missed, err := s.replay.since(ctx, userID, opts.ResumeAfterSequence)
+		if err != nil {
+			s.i.Logger.Warn("failed to get events to replay", zap.Stringer("userId", userID), zap.Error(err))
+		}
+		if len(missed) > 0 {
+			replayed = missed[len(missed)-1].Sequence
+		}
+		pc.outbox.prefill(missed)
+		s.addQueued(len(missed))
+	}
+	s.deliverPendingEvents(ctx, userID, pc, replayed)
+	unlockUser()
 
+	go s.writeLoop(userID, pc)
+
+	select {
+	case <-ctx.Done():
+		pc.terminate(disconnectReasonClientGone)
+	case <-pc.closeCh:
+	}
+
+	s.teardown(userID, pc)
 	return nil
 }
 
-func (s *eventServer) SendEvent(ctx context.Context, msg Event) error {
-	s.mu.RLock()
-	conn, ok := s.connections[msg.RecipientID]
-	s.mu.RUnlock()
+// writeLoop writes queued events to the connection until it is terminated.
+func (s *eventServer) writeLoop(userID pkgid.ID, pc *privConn) {
+	// a write blocked on a stuck client is abandoned once the connection is terminated
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-pc.closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-	if !ok {
-		// TODO: should be recognizable error
-		return fmt.Errorf("user %s is not connected", msg.RecipientID)
+	for {
+		select {
+		case <-pc.outbox.notifyCh:
+		case <-pc.closeCh:
+			return
+		}
+
+		events, finishReason := pc.outbox.popAll()
+		s.addQueued(-len(events))
+
+		for _, event := range events {
+			if err := pc.conn.SendEvent(ctx, event); err != nil {
+				// a failed write leaves the stream in an unknown state, the client has to reconnect
+				s.i.Logger.Debug("failed to write event", zap.Stringer("userId", userID), zap.Error(err))
+				pc.terminate(disconnectReasonSendFailed)
+				return
+			}
+		}
+
+		if finishReason != "" {
+			pc.terminate(finishReason)
+			return
+		}
 	}
+}
 
-	return conn.conn.SendEvent(ctx, msg)
+func (s *eventServer) addQueued(delta int) {
+	s.queued.Add(int64(delta))
+	s.outboxDepth.Add(float64(delta))
 }
 
-func (s *eventServer) InitiateShutdown(ctx context.Context) error {
+// enqueue queues the event for the connection applying the overflow policy.
+func (s *eventServer) enqueue(pc *privConn, event *rteventspb.Event) bool {
+	switch pc.outbox.push(event) {
+	case pushed:
+		s.addQueued(1)
+		return true
+	case pushedDroppingOldest:
+		s.outboxDrops.WithLabelValues(string(OverflowPolicyDropOldest)).Inc()
+		return true
+	case droppedNewest:
+		s.outboxDrops.WithLabelValues(string(OverflowPolicyDropNewest)).Inc()
+		return false
+	case overflowed:
+		s.outboxDrops.WithLabelValues(string(OverflowPolicyDisconnect)).Inc()
+		pc.terminate(disconnectReasonSlowClient)
+		return false
+	default:
+		return false
+	}
+}
+
+// teardown forgets the terminated connection. The heartbeat is stopped, and with it
+// the connection record removed, once the last session of the user is gone.
+func (s *eventServer) teardown(userID pkgid.ID, pc *privConn) {
+	s.disconnects.WithLabelValues(string(pc.reason)).Inc()
+	s.i.Logger.Info("user disconnected", zap.Stringer("userId", userID),
+		zap.String("deviceId", pc.sessionID), zap.String("reason", string(pc.reason)))
+
+	// events which the writer has not managed to write are gone with the connection
+	leftovers, _ := pc.outbox.popAll()
+	s.addQueued(-len(leftovers))
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	errCh := make(chan error, len(s.connections))
-	for _, conn := range s.connections {
-		go func(conn privConn) {
-			// TODO: sent reconnect event
-			//errCh <- conn.conn.SendEvent(ctx, )
+	sessions := s.connections[userID]
+	if sessions[pc.sessionID] != pc {
+		// the session was taken over by a newer connection of the same device
+		return
+	}
 
-			errCh <- nil
-			close(conn.waitCh)
-		}(conn)
+	delete(sessions, pc.sessionID)
+	if len(sessions) > 0 {
+		return
 	}
 
-	var err error
-	for i := 0; i < len(s.connections); i++ {
+	delete(s.connections, userID)
+	if err := s.heartBeater.StopForUser(userID); err != nil {
+		s.i.Logger.Warn("failed to stop heart beater", zap.Stringer("userId", userID), zap.Error(err))
+	}
+}
+
+// deliverPendingEvents queues events stored while the user was offline, skipping those already
+// replayed up to the given sequence number. The caller has to hold the lock of the user, otherwise
+// live events relayed in between would overtake the pending ones.
+func (s *eventServer) deliverPendingEvents(ctx context.Context, userID pkgid.ID, pc *privConn, replayed uint64) {
+	events, err := s.pendingEvents.Drain(ctx, userID)
+	if err != nil {
+		s.i.Logger.Error("failed to fetch pending events", zap.Stringer("userId", userID), zap.Error(err))
+		return
+	}
+
+	for _, event := range events {
+		if event.Sequence > 0 && event.Sequence <= replayed {
+			continue
+		}
+
+		s.replay.remember(userID, event)
+		s.enqueue(pc, event)
+	}
+}
+
+func (s *eventServer) SendEvent(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+	unlockUser := s.userLocks.lock(recipientID)
+	defer unlockUser()
+
+	sessions := s.sessionsOf(recipientID)
+	if len(sessions) == 0 {
+		return fmt.Errorf("%w: %s", ErrRecipientNotConnected, recipientID)
+	}
+	s.replay.remember(recipientID, event)
+
+	// the event is considered delivered if at least one of the user's devices has accepted it
+	delivered := false
+	for _, pc := range sessions {
+		if s.enqueue(pc, event) {
+			delivered = true
+		}
+	}
+
+	if !delivered {
+		return fmt.Errorf("outboxes of user %s are full", recipientID)
+	}
+	return nil
+}
+
+func (s *eventServer) sessionsOf(userID pkgid.ID) []*privConn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.replay.sweep(func(id pkgid.ID) bool {
+		return len(s.connections[id]) > 0
+	})
+
+	sessions := make([]*privConn, 0, len(s.connections[userID]))
+	for _, pc := range s.connections[userID] {
+		sessions = append(sessions, pc)
+	}
+	return sessions
+}
+
+// InitiateShutdown asks connected clients to reconnect elsewhere. Requests are spread
+// evenly over the drain window and the connections are terminated right after them.
+func (s *eventServer) InitiateShutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+
+	var all []*privConn
+	for _, sessions := range s.connections {
+		for _, pc := range sessions {
+			all = append(all, pc)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(all) == 0 {
+		return nil
+	}
+
+	s.i.Logger.Info("draining connections", zap.Int("count", len(all)), zap.Duration("window", s.drain.Window))
+
+	var (
+		interval = s.drain.Window / time.Duration(len(all))
+		start    = time.Now()
+	)
+	for idx, pc := range all {
+		timer := time.NewTimer(time.Until(start.Add(time.Duration(idx) * interval)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			for _, rest := range all[idx:] {
+				rest.terminate(disconnectReasonShutdown)
+			}
+			return fmt.Errorf("draining connections: %w", ctx.Err())
+		}
+
+		// the connection is terminated once the writer gets to the reconnect event
+		pc.outbox.finishWith(s.reconnectEvent(ctx, pc.userID), disconnectReasonShutdown)
+		s.addQueued(1)
+	}
+
+	// clients too slow to receive the reconnect event in time are cut off
+	flushTimer := time.NewTimer(drainFlushTimeout)
+	defer flushTimer.Stop()
+
+	for idx, pc := range all {
 		select {
-		case e := <-errCh:
-			if e != nil {
-				err = multierr.Append(err, e)
+		case <-pc.closeCh:
+		case <-flushTimer.C:
+			for _, rest := range all[idx:] {
+				rest.terminate(disconnectReasonShutdown)
 			}
+			return nil
 		case <-ctx.Done():
-			return ctx.Err()
+			for _, rest := range all[idx:] {
+				rest.terminate(disconnectReasonShutdown)
+			}
+			return fmt.Errorf("draining connections: %w", ctx.Err())
 		}
 	}
 
-	return err
+	return nil
+}
+
+// reconnectEvent suggests another server to the client if there is one available.
+func (s *eventServer) reconnectEvent(ctx context.Context, userID pkgid.ID) *rteventspb.Event {
+	server, err := s.serverSelector.SelectServerForConnection(ctx, userID)
+	if err != nil || server.ID == s.serverID {
+		return rteventspb.NewReconnectEvent("", "", "")
+	}
+
+	return rteventspb.NewReconnectEvent(server.ID, server.GRPCAddress, server.HTTPAddress)
+}
+
+func (s *eventServer) Load() membership.ServerLoad {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	connections := 0
+	for _, sessions := range s.connections {
+		connections += len(sessions)
+	}
+
+	return membership.ServerLoad{
+		Connections:  connections,
+		QueuedEvents: int(s.queued.Load()),
+	}
 }
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/eventserver/pending"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
+	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestEventServer_FansOutToAllSessions(t *testing.T) {
+	var (
+		heartBeater = &countingHeartBeater{}
+		userID      = pkgid.NewID()
+
+		phone  = newCollectingConnection()
+		laptop = newCollectingConnection()
+	)
+
+	server := newTestEventServer(t, heartBeater, stubSelector{})
+
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, phone, ConnectOptions{DeviceID: "phone"})
+	}()
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, laptop, ConnectOptions{DeviceID: "laptop"})
+	}()
+
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hi")) == nil &&
+			len(phone.received()) > 0 && len(laptop.received()) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 1, heartBeater.launches(userID), "heartbeat has to be shared between sessions")
+}
+
+func TestEventServer_ResumesWithEventsRelayedThroughOtherServers(t *testing.T) {
+	var (
+		heartBeater = &countingHeartBeater{}
+		queue       = &storedQueue{heartBeater: heartBeater}
+		stored      = newStoredEvents(t)
+		userID      = pkgid.NewID()
+		phone       = newCollectingConnection()
+		laptop      = newCollectingConnection()
+	)
+
+	var events []*rteventspb.Event
+	for _, content := range []string{"first", "second", "third"} {
+		events = append(events, record(t, stored, userID, rteventspb.NewDirectMessageEvent(pkgid.NewID(), content)))
+	}
+
+	server := newTestEventServerWithQueue(t, heartBeater, queue, stored, stubSelector{})
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, phone, ConnectOptions{DeviceID: "phone"})
+	}()
+
+	// the second event has only been relayed to another server of the user
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, events[0]) == nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, server.SendEvent(context.Background(), userID, events[2]))
+
+	// a relay has found the user offline for a moment and queued the last event too
+	queue.add(events[2])
+
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, laptop, ConnectOptions{DeviceID: "laptop", ResumeAfterSequence: 1})
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(laptop.received()) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "second", laptop.received()[0].GetDirectMessage().GetMessage())
+	require.Equal(t, "third", laptop.received()[1].GetDirectMessage().GetMessage())
+
+	require.Never(t, func() bool {
+		return len(laptop.received()) > 2
+	}, 50*time.Millisecond, 10*time.Millisecond, "replayed pending events must not be delivered again")
+}
+
+func TestEventServer_DrainsPendingEventsOnceConnectionIsStored(t *testing.T) {
+	var (
+		heartBeater = &countingHeartBeater{}
+		userID      = pkgid.NewID()
+		conn        = newCollectingConnection()
+		queue       = &storedQueue{
+			heartBeater: heartBeater,
+			events:      []*rteventspb.Event{rteventspb.NewDirectMessageEvent(pkgid.NewID(), "pending")},
+		}
+	)
+
+	server := newTestEventServerWithQueue(t, heartBeater, queue, newStoredEvents(t), stubSelector{})
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, conn, ConnectOptions{})
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(conn.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "pending", conn.received()[0].GetDirectMessage().GetMessage())
+
+	// relays queue events until they can resolve the user, so draining earlier could miss some
+	require.True(t, queue.drainedAfterRecord(userID))
+}
+
+func TestEventServer_SameDeviceReplacesConnection(t *testing.T) {
+	var (
+		userID = pkgid.NewID()
+
+		oldConn = newCollectingConnection()
+		newConn = newCollectingConnection()
+	)
+
+	server := newTestEventServer(t, &countingHeartBeater{}, stubSelector{})
+
+	oldDone := make(chan struct{})
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, oldConn, ConnectOptions{DeviceID: "phone"})
+		close(oldDone)
+	}()
+
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, &rteventspb.Event{}) == nil
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return len(oldConn.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, newConn, ConnectOptions{DeviceID: "phone"})
+	}()
+
+	select {
+	case <-oldDone:
+	case <-time.After(time.Second):
+		require.Fail(t, "replaced connection was not released")
+	}
+
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, &rteventspb.Event{}) == nil
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return len(newConn.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, oldConn.received(), 1)
+}
+
+func TestEventServer_TeardownAfterLastSession(t *testing.T) {
+	var (
+		heartBeater = &countingHeartBeater{}
+		userID      = pkgid.NewID()
+	)
+
+	server := newTestEventServer(t, heartBeater, stubSelector{})
+
+	var (
+		phoneCtx, disconnectPhone   = context.WithCancel(context.Background())
+		laptopCtx, disconnectLaptop = context.WithCancel(context.Background())
+
+		phoneDone  = make(chan struct{})
+		laptopDone = make(chan struct{})
+	)
+	go func() {
+		_ = server.ServeConnection(phoneCtx, userID, newCollectingConnection(), ConnectOptions{DeviceID: "phone"})
+		close(phoneDone)
+	}()
+	go func() {
+		_ = server.ServeConnection(laptopCtx, userID, newCollectingConnection(), ConnectOptions{DeviceID: "laptop"})
+		close(laptopDone)
+	}()
+
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, &rteventspb.Event{}) == nil
+	}, time.Second, 10*time.Millisecond)
+
+	disconnectPhone()
+	<-phoneDone
+	require.Zero(t, heartBeater.stops(userID), "heartbeat has to live while any session is connected")
+	require.NoError(t, server.SendEvent(context.Background(), userID, &rteventspb.Event{}))
+
+	disconnectLaptop()
+	<-laptopDone
+	require.Equal(t, 1, heartBeater.stops(userID))
+	require.ErrorIs(t, server.SendEvent(context.Background(), userID, &rteventspb.Event{}), ErrRecipientNotConnected)
+}
+
+func TestEventServer_FailedSendTerminatesConnection(t *testing.T) {
+	userID := pkgid.NewID()
+
+	server := newTestEventServer(t, &countingHeartBeater{}, stubSelector{})
+
+	done := make(chan struct{})
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, failingConnection{}, ConnectOptions{})
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, &rteventspb.Event{}) != nil
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "connection was not terminated")
+	}
+}
+
+func TestEventServer_DrainAsksClientsToReconnect(t *testing.T) {
+	var (
+		selector = stubSelector{server: membership.ServerInfo{ID: "other", GRPCAddress: "other:9000", HTTPAddress: "other:8080"}}
+		server   = newTestEventServer(t, &countingHeartBeater{}, selector)
+
+		users = []pkgid.ID{pkgid.NewID(), pkgid.NewID()}
+		conns = []*collectingConnection{newCollectingConnection(), newCollectingConnection()}
+		done  = make(chan struct{}, len(conns))
+	)
+
+	for idx := range conns {
+		go func(userID pkgid.ID, conn *collectingConnection) {
+			_ = server.ServeConnection(context.Background(), userID, conn, ConnectOptions{})
+			done <- struct{}{}
+		}(users[idx], conns[idx])
+	}
+
+	for _, userID := range users {
+		require.Eventually(t, func() bool {
+			return server.SendEvent(context.Background(), userID, &rteventspb.Event{}) == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	require.NoError(t, server.InitiateShutdown(context.Background()))
+	for range conns {
+		<-done
+	}
+
+	for _, conn := range conns {
+		events := conn.received()
+		reconnect := events[len(events)-1].GetReconnect()
+		require.Equal(t, "other", reconnect.GetServerId())
+		require.Equal(t, "other:9000", reconnect.GetGrpcAddress())
+	}
+
+	// late connections are redirected right away
+	late := newCollectingConnection()
+	require.NoError(t, server.ServeConnection(context.Background(), pkgid.NewID(), late, ConnectOptions{}))
+	require.NotNil(t, late.received()[0].GetReconnect())
+}
+
+func TestEventServer_ReportsLoad(t *testing.T) {
+	var (
+		server = newTestEventServer(t, &countingHeartBeater{}, stubSelector{})
+		userID = pkgid.NewID()
+	)
+
+	for _, device := range []string{"phone", "laptop"} {
+		go func(device string) {
+			_ = server.ServeConnection(context.Background(), userID, newCollectingConnection(), ConnectOptions{DeviceID: device})
+		}(device)
+	}
+
+	require.Eventually(t, func() bool {
+		return server.Load().Connections == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return server.Load().QueuedEvents == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func newTestEventServer(t *testing.T, heartBeater rtconn.HeartBeater, selector membership.ServerSelector) EventServer {
+	return newTestEventServerWithQueue(t, heartBeater, noopQueue{}, newStoredEvents(t), selector)
+}
+
+func newTestEventServerWithQueue(
+	t *testing.T, heartBeater rtconn.HeartBeater, queue pending.Queue, stored esreplay.Log, selector membership.ServerSelector,
+) EventServer {
+	i := pkginstrument.Instrumentation{
+		Logger:     pkgtest.Instrumentation.Logger,
+		Registerer: prometheus.NewRegistry(),
+	}
+
+	server, err := NewEventServer(i, "self", heartBeater, queue, selector, stored,
+		Configuration{Drain: DrainConfiguration{Window: 50 * time.Millisecond}})
+	require.NoError(t, err)
+	return server
+}
+
+type stubSelector struct {
+	server membership.ServerInfo
+}
+
+func (s stubSelector) SelectServerForConnection(context.Context, pkgid.ID) (membership.ServerInfo, error) {
+	if s.server.ID == "" {
+		return membership.ServerInfo{}, errors.New("no servers")
+	}
+	return s.server, nil
+}
+
+type failingConnection struct{}
+
+func (failingConnection) SendEvent(context.Context, *rteventspb.Event) error {
+	return errors.New("broken pipe")
+}
+
+type collectingConnection struct {
+	mu     sync.Mutex
+	events []*rteventspb.Event
+}
+
+func newCollectingConnection() *collectingConnection {
+	return &collectingConnection{}
+}
+
+func (c *collectingConnection) SendEvent(_ context.Context, event *rteventspb.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events = append(c.events, event)
+	return nil
+}
+
+func (c *collectingConnection) received() []*rteventspb.Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*rteventspb.Event(nil), c.events...)
+}
+
+type countingHeartBeater struct {
+	rtconn.HeartBeater
+
+	mu       sync.Mutex
+	launched map[pkgid.ID]int
+	stopped  map[pkgid.ID]int
+	awaited  map[pkgid.ID]int
+}
+
+func (b *countingHeartBeater) LaunchForUser(userID pkgid.ID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.launched == nil {
+		b.launched = map[pkgid.ID]int{}
+	}
+	b.launched[userID]++
+	return nil
+}
+
+func (b *countingHeartBeater) StopForUser(userID pkgid.ID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped == nil {
+		b.stopped = map[pkgid.ID]int{}
+	}
+	b.stopped[userID]++
+	return nil
+}
+
+func (b *countingHeartBeater) AwaitRecord(_ context.Context, userID pkgid.ID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.awaited == nil {
+		b.awaited = map[pkgid.ID]int{}
+	}
+	b.awaited[userID]++
+	return nil
+}
+
+func (b *countingHeartBeater) launches(userID pkgid.ID) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.launched[userID]
+}
+
+func (b *countingHeartBeater) stops(userID pkgid.ID) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stopped[userID]
+}
+
+func (b *countingHeartBeater) awaits(userID pkgid.ID) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.awaited[userID]
+}
+
+type noopQueue struct {
+	pending.Queue
+}
+
+func (noopQueue) Drain(context.Context, pkgid.ID) ([]*rteventspb.Event, error) {
+	return nil, nil
+}
+
+// storedQueue checks whether the connection record has been awaited before draining.
+type storedQueue struct {
+	pending.Queue
+
+	heartBeater *countingHeartBeater
+
+	mu      sync.Mutex
+	events  []*rteventspb.Event
+	drained map[pkgid.ID]bool
+}
+
+func (q *storedQueue) add(event *rteventspb.Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.events = append(q.events, event)
+}
+
+func (q *storedQueue) Drain(_ context.Context, userID pkgid.ID) ([]*rteventspb.Event, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.drained == nil {
+		q.drained = map[pkgid.ID]bool{}
+	}
+	q.drained[userID] = q.heartBeater.awaits(userID) > 0
+
+	events := q.events
+	q.events = nil
+	return events, nil
+}
+
+func (q *storedQueue) drainedAfterRecord(userID pkgid.ID) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.drained[userID]
+}
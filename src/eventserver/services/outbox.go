@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+)
+
+const defaultOutboxSize = 256
+
+type OverflowPolicy string
+
+const (
+	// OverflowPolicyDropOldest discards the oldest queued event to make room for the new one.
+	OverflowPolicyDropOldest OverflowPolicy = "dropOldest"
+	// OverflowPolicyDropNewest discards the new event.
+	OverflowPolicyDropNewest OverflowPolicy = "dropNewest"
+	// OverflowPolicyDisconnect terminates the connection, the client catches up after reconnecting.
+	OverflowPolicyDisconnect OverflowPolicy = "disconnect"
+)
+
+// OutboxConfiguration controls the queue of events waiting to be written to a single connection.
+type OutboxConfiguration struct {
+	// Size is the maximum number of queued events per connection.
+	Size int `yaml:"size"`
+
+	// OverflowPolicy decides what happens when a slow client lets the queue fill up.
+	OverflowPolicy OverflowPolicy `yaml:"overflowPolicy"`
+}
+
+func (c OutboxConfiguration) withDefaults() OutboxConfiguration {
+	if c.Size <= 0 {
+		c.Size = defaultOutboxSize
+	}
+
+	if c.OverflowPolicy == "" {
+		c.OverflowPolicy = OverflowPolicyDropOldest
+	}
+
+	return c
+}
+
+func (c OutboxConfiguration) Validate() error {
+	switch c.OverflowPolicy {
+	case "", OverflowPolicyDropOldest, OverflowPolicyDropNewest, OverflowPolicyDisconnect:
+		return nil
+	default:
+		return fmt.Errorf("unknown overflow policy %q", c.OverflowPolicy)
+	}
+}
+
+type pushResult int
+
+const (
+	pushed pushResult = iota
+	pushedDroppingOldest
+	droppedNewest
+	overflowed
+	// closing means the connection is about to be terminated and takes no more events
+	closing
+)
+
+// outbox is a bounded queue of events waiting to be written to a connection.
+type outbox struct {
+	mu     sync.Mutex
+	events []*rteventspb.Event
+
+	// finishReason is set when the connection has to be terminated after the queued events are written
+	finishReason disconnectReason
+
+	size   int
+	policy OverflowPolicy
+
+	// notifyCh has a capacity of one and signals the writer that events are queued
+	notifyCh chan struct{}
+}
+
+func newOutbox(cfg OutboxConfiguration) *outbox {
+	return &outbox{
+		size:     cfg.Size,
+		policy:   cfg.OverflowPolicy,
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+// push queues the event applying the overflow policy if the queue is full.
+func (o *outbox) push(event *rteventspb.Event) pushResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.finishReason != "" {
+		return closing
+	}
+
+	result := pushed
+	if len(o.events) >= o.size {
+		switch o.policy {
+		case OverflowPolicyDropNewest:
+			return droppedNewest
+		case OverflowPolicyDisconnect:
+			return overflowed
+		default:
+			o.events = o.events[1:]
+			result = pushedDroppingOldest
+		}
+	}
+
+	o.events = append(o.events, event)
+	o.notify()
+	return result
+}
+
+// prefill queues events ignoring the limit. It is meant for events which have to be
+// delivered before any live ones, e.g. replayed after reconnecting.
+func (o *outbox) prefill(events []*rteventspb.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events = append(o.events, events...)
+	o.notify()
+}
+
+// finishWith queues the last event after which the connection is terminated for the reason.
+func (o *outbox) finishWith(event *rteventspb.Event, reason disconnectReason) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events = append(o.events, event)
+	o.finishReason = reason
+	o.notify()
+}
+
+// popAll removes and returns all queued events together with the reason to terminate
+// the connection after writing them, if it was requested.
+func (o *outbox) popAll() ([]*rteventspb.Event, disconnectReason) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	events := o.events
+	o.events = nil
+	return events, o.finishReason
+}
+
+func (o *outbox) notify() {
+	select {
+	case o.notifyCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestOutbox_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy    OverflowPolicy
+		result    pushResult
+		sequences []uint64
+	}{
+		{policy: OverflowPolicyDropOldest, result: pushedDroppingOldest, sequences: []uint64{2, 3}},
+		{policy: OverflowPolicyDropNewest, result: droppedNewest, sequences: []uint64{1, 2}},
+		{policy: OverflowPolicyDisconnect, result: overflowed, sequences: []uint64{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			o := newOutbox(OutboxConfiguration{Size: 2, OverflowPolicy: tt.policy})
+
+			require.Equal(t, pushed, o.push(&rteventspb.Event{Sequence: 1}))
+			require.Equal(t, pushed, o.push(&rteventspb.Event{Sequence: 2}))
+			require.Equal(t, tt.result, o.push(&rteventspb.Event{Sequence: 3}))
+
+			events, reason := o.popAll()
+			require.Empty(t, reason)
+
+			var sequences []uint64
+			for _, event := range events {
+				sequences = append(sequences, event.Sequence)
+			}
+			require.Equal(t, tt.sequences, sequences)
+		})
+	}
+}
+
+func TestOutbox_FinishRejectsNewEvents(t *testing.T) {
+	o := newOutbox(OutboxConfiguration{}.withDefaults())
+
+	o.finishWith(&rteventspb.Event{}, disconnectReasonShutdown)
+	require.Equal(t, closing, o.push(&rteventspb.Event{}))
+
+	events, reason := o.popAll()
+	require.Len(t, events, 1)
+	require.Equal(t, disconnectReasonShutdown, reason)
+}
+
+func TestEventServer_SlowClientIsDisconnected(t *testing.T) {
+	i := pkginstrument.Instrumentation{
+		Logger:     pkgtest.Instrumentation.Logger,
+		Registerer: prometheus.NewRegistry(),
+	}
+
+	server, err := NewEventServer(i, "self", &countingHeartBeater{}, noopQueue{}, stubSelector{}, newStoredEvents(t),
+		Configuration{Outbox: OutboxConfiguration{Size: 1, OverflowPolicy: OverflowPolicyDisconnect}})
+	require.NoError(t, err)
+
+	var (
+		userID = pkgid.NewID()
+		conn   = &blockingConnection{release: make(chan struct{})}
+		done   = make(chan struct{})
+	)
+	defer close(conn.release)
+
+	go func() {
+		_ = server.ServeConnection(context.Background(), userID, conn, ConnectOptions{})
+		close(done)
+	}()
+
+	// the first event blocks the writer, the second fills the outbox and the third overflows it
+	require.Eventually(t, func() bool {
+		return server.SendEvent(context.Background(), userID, &rteventspb.Event{}) == nil
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_ = server.SendEvent(context.Background(), userID, &rteventspb.Event{})
+
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
+
+type blockingConnection struct {
+	release chan struct{}
+}
+
+func (c *blockingConnection) SendEvent(ctx context.Context, _ *rteventspb.Event) error {
+	select {
+	case <-c.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
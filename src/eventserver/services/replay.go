@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+const (
+	defaultReplayBufferSize = 100
+
+	// memoryRetention is for how long events of a user without activity are kept in memory.
+	memoryRetention = 10 * time.Minute
+)
+
+type ReplayConfiguration struct {
+	// BufferSize is the number of the latest events of a user kept in memory of the server.
+	BufferSize int `yaml:"bufferSize"`
+}
+
+func (c ReplayConfiguration) withDefaults() ReplayConfiguration {
+	if c.BufferSize <= 0 {
+		c.BufferSize = defaultReplayBufferSize
+	}
+
+	return c
+}
+
+// replayLog caches the latest sequenced events delivered by the server in memory, so clients
+// reconnecting after a short break can mostly catch up without reaching out to the memstore.
+// Sequences are assigned by the relays, which also store the events for all servers.
+type replayLog struct {
+	mu        sync.Mutex
+	users     map[pkgid.ID]*userReplayLog
+	lastSweep time.Time
+
+	stored esreplay.Log
+	cfg    ReplayConfiguration
+
+	now func() time.Time
+}
+
+type userReplayLog struct {
+	// events are ordered by sequence numbers
+	events       []*rteventspb.Event
+	lastActivity time.Time
+}
+
+func newReplayLog(stored esreplay.Log, cfg ReplayConfiguration) *replayLog {
+	return &replayLog{
+		users:  map[pkgid.ID]*userReplayLog{},
+		stored: stored,
+		cfg:    cfg.withDefaults(),
+		now:    time.Now,
+	}
+}
+
+// remember keeps the event in memory unless it has not been sequenced, like ephemeral events.
+func (l *replayLog) remember(userID pkgid.ID, event *rteventspb.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log, ok := l.users[userID]
+	if !ok {
+		log = &userReplayLog{}
+		l.users[userID] = log
+	}
+
+	if event.Sequence == 0 {
+		return
+	}
+
+	// concurrently relayed events might arrive slightly out of order
+	idx := sort.Search(len(log.events), func(i int) bool {
+		return log.events[i].Sequence > event.Sequence
+	})
+	if idx > 0 && log.events[idx-1].Sequence == event.Sequence {
+		// redelivered by a retrying relay
+		return
+	}
+	log.events = append(log.events, nil)
+	copy(log.events[idx+1:], log.events[idx:])
+	log.events[idx] = event
+
+	if len(log.events) > l.cfg.BufferSize {
+		log.events = log.events[len(log.events)-l.cfg.BufferSize:]
+	}
+	log.lastActivity = l.now()
+}
+
+// since returns recorded events of the user with sequence numbers greater than the given one.
+func (l *replayLog) since(ctx context.Context, userID pkgid.ID, sequence uint64) ([]*rteventspb.Event, error) {
+	last, err := l.stored.LastSequence(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting last sequence number: %w", err)
+	}
+
+	if events, ok := l.sinceInMemory(userID, sequence, last); ok {
+		return events, nil
+	}
+
+	events, err := l.stored.Since(ctx, userID, sequence)
+	if err != nil {
+		return nil, fmt.Errorf("getting stored events: %w", err)
+	}
+	return events, nil
+}
+
+// sinceInMemory returns events from the memory buffer if it holds every event up to the last
+// sequenced one. Events relayed while the user was connected to other servers only are missing.
+func (l *replayLog) sinceInMemory(userID pkgid.ID, sequence, last uint64) ([]*rteventspb.Event, bool) {
+	if last < sequence {
+		return nil, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log, ok := l.users[userID]
+	if !ok {
+		return nil, false
+	}
+
+	var events []*rteventspb.Event
+	for _, event := range log.events {
+		if event.Sequence > sequence {
+			events = append(events, event)
+		}
+	}
+
+	// sequences in the buffer are unique, so the count tells whether there are gaps
+	if uint64(len(events)) != last-sequence || (len(events) > 0 && events[len(events)-1].Sequence != last) {
+		return nil, false
+	}
+	log.lastActivity = l.now()
+	return events, true
+}
+
+// sweep forgets users which were inactive for longer than the memory retention
+// period, unless isConnected reports them as still connected.
+func (l *replayLog) sweep(isConnected func(pkgid.ID) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) < memoryRetention {
+		return
+	}
+	l.lastSweep = now
+
+	for userID, log := range l.users {
+		if now.Sub(log.lastActivity) > memoryRetention && !isConnected(userID) {
+			delete(l.users, userID)
+		}
+	}
+}
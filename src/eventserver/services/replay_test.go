@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestReplayLog_ReplaysFromMemory(t *testing.T) {
+	var (
+		stored = newStoredEvents(t)
+		log    = newReplayLog(stored, ReplayConfiguration{BufferSize: 10})
+		userID = pkgid.NewID()
+	)
+
+	var delivered []*rteventspb.Event
+	for i := 0; i < 15; i++ {
+		event := record(t, stored, userID, rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hi"))
+		log.remember(userID, event)
+		delivered = append(delivered, event)
+	}
+
+	events, err := log.since(context.Background(), userID, 12)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for idx, event := range events {
+		require.Same(t, delivered[12+idx], event, "events have to be replayed from memory")
+	}
+}
+
+func TestReplayLog_FallsBackToMemStore(t *testing.T) {
+	var (
+		stored = newStoredEvents(t)
+		log    = newReplayLog(stored, ReplayConfiguration{})
+		userID = pkgid.NewID()
+	)
+
+	for i := 0; i < 6; i++ {
+		record(t, stored, userID, &rteventspb.Event{})
+	}
+
+	// the server has not seen the user yet, e.g. after reconnecting to another server
+	events, err := log.since(context.Background(), userID, 4)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(5), events[0].Sequence)
+	require.Equal(t, uint64(6), events[1].Sequence)
+}
+
+func TestReplayLog_FallsBackToMemStoreOnGaps(t *testing.T) {
+	var (
+		stored = newStoredEvents(t)
+		log    = newReplayLog(stored, ReplayConfiguration{})
+		userID = pkgid.NewID()
+	)
+
+	// the second event has been relayed through another server of the user
+	for i := 0; i < 3; i++ {
+		event := record(t, stored, userID, &rteventspb.Event{})
+		if i != 1 {
+			log.remember(userID, event)
+		}
+	}
+
+	events, err := log.since(context.Background(), userID, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, uint64(2), events[1].Sequence)
+
+	// the latest event has not reached this server yet
+	record(t, stored, userID, &rteventspb.Event{})
+
+	events, err = log.since(context.Background(), userID, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(4), events[1].Sequence)
+}
+
+func TestReplayLog_RemembersSequencedEventsOnce(t *testing.T) {
+	var (
+		stored = newStoredEvents(t)
+		log    = newReplayLog(stored, ReplayConfiguration{})
+		userID = pkgid.NewID()
+	)
+
+	event := record(t, stored, userID, &rteventspb.Event{})
+	log.remember(userID, event)
+	log.remember(userID, event)
+	log.remember(userID, rteventspb.NewTypingEvent(pkgid.NewID(), true, time.Second))
+
+	events, ok := log.sinceInMemory(userID, 0, 1)
+	require.True(t, ok)
+	require.Len(t, events, 1)
+}
+
+func TestReplayLog_SweepKeepsConnectedUsers(t *testing.T) {
+	var (
+		stored    = newStoredEvents(t)
+		log       = newReplayLog(stored, ReplayConfiguration{})
+		now       = time.Now()
+		connected = pkgid.NewID()
+		gone      = pkgid.NewID()
+	)
+	log.now = func() time.Time { return now }
+
+	for _, userID := range []pkgid.ID{connected, gone} {
+		log.remember(userID, record(t, stored, userID, &rteventspb.Event{}))
+	}
+
+	now = now.Add(2 * memoryRetention)
+	log.sweep(func(userID pkgid.ID) bool { return userID == connected })
+
+	_, ok := log.sinceInMemory(connected, 0, 1)
+	require.True(t, ok)
+	_, ok = log.sinceInMemory(gone, 0, 1)
+	require.False(t, ok)
+}
+
+// record sequences the event the way relays do before sending it to the servers.
+func record(t *testing.T, stored esreplay.Log, userID pkgid.ID, event *rteventspb.Event) *rteventspb.Event {
+	sequenced, err := stored.Record(context.Background(), userID, event)
+	require.NoError(t, err)
+	return sequenced
+}
+
+// newStoredEvents returns a replay log backed by a memstore mock which keeps per-user
+// sequence counters and event lists.
+func newStoredEvents(t *testing.T) esreplay.Log {
+	var (
+		store = pkgmemstore.NewMockStore(gomock.NewController(t))
+
+		mu       sync.Mutex
+		counters = map[string]int64{}
+		lists    = map[string][][]byte{}
+	)
+
+	store.EXPECT().
+		IncrementCollectionCounter(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, key string, _ time.Duration) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			counters[key]++
+			return counters[key], nil
+		}).
+		AnyTimes()
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, key string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if counters[key] == 0 {
+				return nil, pkgmemstore.ErrNotFound
+			}
+			return []byte(strconv.FormatInt(counters[key], 10)), nil
+		}).
+		AnyTimes()
+	store.EXPECT().
+		AppendToCollectionList(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, key string, value []byte, _ int, _ time.Duration) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			lists[key] = append(lists[key], value)
+			return nil
+		}).
+		AnyTimes()
+	store.EXPECT().
+		GetCollectionList(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, key string) ([][]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return append([][]byte(nil), lists[key]...), nil
+		}).
+		AnyTimes()
+
+	return esreplay.NewMemStoreLog(pkgtest.Instrumentation, store, esreplay.Configuration{})
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"sync"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+// userLocks serializes work on the same user without blocking the others. Locks are
+// dropped once nobody holds or waits for them.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[pkgid.ID]*userLock
+}
+
+type userLock struct {
+	sync.Mutex
+
+	// refs is the number of holders and waiters, guarded by userLocks.mu
+	refs int
+}
+
+func newUserLocks() *userLocks {
+	return &userLocks{
+		locks: map[pkgid.ID]*userLock{},
+	}
+}
+
+// lock blocks until the lock of the user is acquired and returns the function releasing it.
+func (l *userLocks) lock(userID pkgid.ID) (unlock func()) {
+	l.mu.Lock()
+	ul, ok := l.locks[userID]
+	if !ok {
+		ul = &userLock{}
+		l.locks[userID] = ul
+	}
+	ul.refs++
+	l.mu.Unlock()
+
+	ul.Lock()
+	return func() {
+		ul.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if ul.refs--; ul.refs == 0 {
+			delete(l.locks, userID)
+		}
+	}
+}
@@ -2,19 +2,29 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	"github.com/faustuzas/occa/src/gateway/services"
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
 	esmembership "github.com/faustuzas/occa/src/pkg/eventserver/membership"
 	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	"github.com/faustuzas/occa/src/pkg/groups"
 	pkghttp "github.com/faustuzas/occa/src/pkg/http"
 	httpmiddleware "github.com/faustuzas/occa/src/pkg/http/middleware"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgslices "github.com/faustuzas/occa/src/pkg/slices"
 )
 
 type Services struct {
@@ -22,7 +32,15 @@ type Services struct {
 	AuthMiddleware      httpmiddleware.Middleware
 	ActiveUsersTracker  services.ActiveUsersTracker
 	EventServerSelector esmembership.ServerSelector
+	EventServerRegistry esmembership.HealthChecker
 	RTEventRelay        services.RealTimeEventRelay
+	GroupManager        services.GroupManager
+	ContactManager      services.ContactManager
+	ReceiptTracker      services.ReceiptTracker
+	TypingNotifier      services.TypingNotifier
+	DeadLetters         services.DeadLetterStore
+	AdminMiddleware     httpmiddleware.Middleware
+	Archiver            archiverclient.Client
 
 	Logger   *zap.Logger
 	Registry *prometheus.Registry
@@ -37,6 +55,10 @@ func Configure(s Services) (http.Handler, error) {
 		With(httpmiddleware.BasicMetrics(s.Registry), httpmiddleware.RequestLogger(s.Logger))
 
 	instrumentedRouter.HandleJSONFunc("/health", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		// without up-to-date event servers, clients would be sent to the servers which are gone
+		if err := s.EventServerRegistry.CheckHealth(); err != nil {
+			return nil, err
+		}
 		return pkghttp.DefaultOKResponse(), nil
 	}).Methods(http.MethodGet)
 
@@ -74,14 +96,25 @@ func Configure(s Services) (http.Handler, error) {
 		With(s.AuthMiddleware)
 
 	authenticatedRouter.HandleJSONFunc("/heartbeat", func(w http.ResponseWriter, r *http.Request) (any, error) {
-		if err := s.ActiveUsersTracker.HeartBeat(r.Context()); err != nil {
+		// heartbeats without a body come from clients which do not track idleness
+		req := HeartbeatRequest{Active: true}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			return nil, pkgerrors.BadRequest(err)
+		}
+
+		if err := s.ActiveUsersTracker.HeartBeat(r.Context(), req.Active); err != nil {
 			return nil, fmt.Errorf("hearth beating user: %w", err)
 		}
 		return pkghttp.DefaultOKResponse(), nil
 	}).Methods(http.MethodPost)
 
 	authenticatedRouter.HandleJSONFunc("/active-users", func(w http.ResponseWriter, r *http.Request) (any, error) {
-		users, err := s.ActiveUsersTracker.ActiveUsers(r.Context())
+		contactIDs, err := s.ContactManager.ContactIDs(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("getting contacts: %w", err)
+		}
+
+		users, err := s.ActiveUsersTracker.ActiveUsers(r.Context(), contactIDs)
 		if err != nil {
 			return nil, fmt.Errorf("getting active users: %w", err)
 		}
@@ -91,14 +124,46 @@ func Configure(s Services) (http.Handler, error) {
 		}, nil
 	}).Methods(http.MethodGet)
 
+	authenticatedRouter.HandleJSONFunc("/presence", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID := pkgauth.PrincipalFromContext(r.Context()).ID
+		if user := r.URL.Query().Get("user"); user != "" {
+			otherID, err := pkgid.Parse(user)
+			if err != nil {
+				return nil, pkgerrors.BadRequest(fmt.Errorf("invalid user parameter: %w", err))
+			}
+
+			// presence is shared with contacts only, the same as in active users
+			if otherID != userID {
+				if err = s.ContactManager.CheckIsContact(r.Context(), otherID); err != nil {
+					return nil, err
+				}
+			}
+			userID = otherID
+		}
+
+		return s.ActiveUsersTracker.Presence(r.Context(), userID)
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/presence", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req services.PresenceUpdate
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, pkgerrors.BadRequest(err)
+		}
+
+		if err := s.ActiveUsersTracker.SetPresence(r.Context(), req); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPut)
+
 	authenticatedRouter.HandleJSONFunc("/select-server", func(w http.ResponseWriter, r *http.Request) (any, error) {
-		server, err := s.EventServerSelector.SelectServerForConnection(r.Context())
+		principal := pkgauth.PrincipalFromContext(r.Context())
+
+		server, err := s.EventServerSelector.SelectServerForConnection(r.Context(), principal.ID)
 		if err != nil {
 			return nil, fmt.Errorf("selecting event server: %w", err)
 		}
 
-		principal := pkgauth.PrincipalFromContext(r.Context())
-
 		s.Logger.Info("selected server for user",
 			zap.Stringer("userId", principal.ID),
 			zap.String("username", principal.UserName),
@@ -113,6 +178,10 @@ func Configure(s Services) (http.Handler, error) {
 			return nil, err
 		}
 
+		if err := s.ContactManager.CheckCanMessage(r.Context(), req.RecipientID); err != nil {
+			return nil, err
+		}
+
 		var (
 			principal = pkgauth.PrincipalFromContext(r.Context())
 			message   = rteventspb.NewDirectMessageEvent(principal.ID, req.Message)
@@ -121,8 +190,400 @@ func Configure(s Services) (http.Handler, error) {
 			return nil, fmt.Errorf("forwaring real time event: %w", err)
 		}
 
-		return pkghttp.DefaultOKResponse(), nil
+		return SendMessageResponse{
+			MessageID: message.GetDirectMessage().GetMessageId(),
+		}, nil
 	})
 
+	authenticatedRouter.HandleJSONFunc("/typing", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req TypingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+
+		if err := s.ContactManager.CheckCanMessage(r.Context(), req.RecipientID); err != nil {
+			return nil, err
+		}
+
+		if err := s.TypingNotifier.Typing(r.Context(), req.RecipientID, req.Typing); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/receipts", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req ReceiptRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+
+		if err := s.ContactManager.CheckCanMessage(r.Context(), req.SenderID); err != nil {
+			return nil, err
+		}
+
+		if err := s.ReceiptTracker.Acknowledge(r.Context(), req.Kind, req.SenderID, req.MessageID); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/receipts", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		withID, err := pkgid.Parse(r.URL.Query().Get("with"))
+		if err != nil {
+			return nil, pkgerrors.BadRequest(fmt.Errorf("invalid with parameter: %w", err))
+		}
+
+		// blocked users should not learn whether their messages are being read
+		if err = s.ContactManager.CheckCanMessage(r.Context(), withID); err != nil {
+			return nil, err
+		}
+
+		return s.ReceiptTracker.Conversation(r.Context(), withID)
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/history", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		withID, err := pkgid.Parse(r.URL.Query().Get("with"))
+		if err != nil {
+			return nil, pkgerrors.BadRequest(fmt.Errorf("invalid with parameter: %w", err))
+		}
+
+		page, err := s.Archiver.History(r.Context(), pkgarchive.HistoryQuery{
+			UserID: pkgauth.PrincipalFromContext(r.Context()).ID,
+			WithID: withID,
+			Before: r.URL.Query().Get("before"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetching history: %w", err)
+		}
+
+		return HistoryResponse{
+			Messages:   page.Messages,
+			NextCursor: page.NextCursor,
+		}, nil
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/groups", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req CreateGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+
+		group, err := s.GroupManager.Create(r.Context(), req.Name)
+		if err != nil {
+			return nil, err
+		}
+		return GroupResponse{Group: group}, nil
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/groups", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userGroups, err := s.GroupManager.Groups(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		return GroupsResponse{Groups: userGroups}, nil
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		group, members, err := s.GroupManager.Group(r.Context(), groupID)
+		if err != nil {
+			return nil, err
+		}
+		return GroupResponse{Group: group, Members: members}, nil
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		var req RenameGroupRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+
+		if err = s.GroupManager.Rename(r.Context(), groupID, req.Name); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPatch)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}/archive", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.GroupManager.Archive(r.Context(), groupID); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}/members", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		var req AddGroupMemberRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+		if req.Role == "" {
+			req.Role = groups.RoleMember
+		}
+
+		_, members, err := s.GroupManager.Group(r.Context(), groupID)
+		if err != nil {
+			return nil, err
+		}
+
+		// members can message each other, so users who have blocked each other cannot be brought together
+		memberIDs := pkgslices.Map(members, func(m groups.Member) pkgid.ID { return m.UserID })
+		if err = s.ContactManager.CheckCanJoin(r.Context(), req.UserID, memberIDs); err != nil {
+			return nil, err
+		}
+
+		if err = s.GroupManager.AddMember(r.Context(), groupID, req.UserID, req.Role); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}/members/{userId}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := idFromPath(r, "userId")
+		if err != nil {
+			return nil, err
+		}
+
+		var req SetGroupMemberRoleRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+
+		if err = s.GroupManager.SetMemberRole(r.Context(), groupID, userID, req.Role); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPut)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}/members/{userId}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := idFromPath(r, "userId")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.GroupManager.RemoveMember(r.Context(), groupID, userID); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodDelete)
+
+	authenticatedRouter.HandleJSONFunc("/groups/{id}/messages", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		groupID, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		var req SendGroupMessageRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+
+		if err = s.GroupManager.SendMessage(r.Context(), groupID, req.Message); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/contacts", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userContacts, err := s.ContactManager.Contacts(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		return ContactsResponse{Contacts: userContacts}, nil
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/contacts", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req ContactRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, pkgerrors.BadRequest(err)
+		}
+
+		return s.ContactManager.Request(r.Context(), req.UserID)
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/contacts/{userId}/accept", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := idFromPath(r, "userId")
+		if err != nil {
+			return nil, err
+		}
+
+		return s.ContactManager.Accept(r.Context(), userID)
+	}).Methods(http.MethodPost)
+
+	authenticatedRouter.HandleJSONFunc("/contacts/{userId}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := idFromPath(r, "userId")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.ContactManager.Remove(r.Context(), userID); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodDelete)
+
+	authenticatedRouter.HandleJSONFunc("/blocks", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		blocked, err := s.ContactManager.Blocked(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		return BlocksResponse{Blocked: blocked}, nil
+	}).Methods(http.MethodGet)
+
+	authenticatedRouter.HandleJSONFunc("/blocks/{userId}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := idFromPath(r, "userId")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.ContactManager.Block(r.Context(), userID); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPut)
+
+	authenticatedRouter.HandleJSONFunc("/blocks/{userId}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := idFromPath(r, "userId")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.ContactManager.Unblock(r.Context(), userID); err != nil {
+			return nil, err
+		}
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodDelete)
+
+	// admin endpoints are meant for on-call, so they are authorized with the service token
+	adminRouter := instrumentedRouter.SubGroup().
+		With(s.AdminMiddleware)
+
+	adminRouter.HandleJSONFunc("/admin/dead-letters", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		letters, err := s.DeadLetters.List(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("listing dead letters: %w", err)
+		}
+
+		resp := DeadLettersResponse{DeadLetters: make([]DeadLetter, 0, len(letters))}
+		for _, letter := range letters {
+			l, err := toDeadLetter(letter)
+			if err != nil {
+				return nil, err
+			}
+			resp.DeadLetters = append(resp.DeadLetters, l)
+		}
+		return resp, nil
+	}).Methods(http.MethodGet)
+
+	adminRouter.HandleJSONFunc("/admin/dead-letters", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		purged, err := s.DeadLetters.Purge(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("purging dead letters: %w", err)
+		}
+
+		s.Logger.Info("purged dead letters", zap.Int("count", purged))
+		return PurgeDeadLettersResponse{Purged: purged}, nil
+	}).Methods(http.MethodDelete)
+
+	adminRouter.HandleJSONFunc("/admin/dead-letters/{id}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		id, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		letter, err := s.DeadLetters.Get(r.Context(), id)
+		if err != nil {
+			return nil, deadLetterError(err)
+		}
+		return toDeadLetter(letter)
+	}).Methods(http.MethodGet)
+
+	adminRouter.HandleJSONFunc("/admin/dead-letters/{id}", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		id, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.DeadLetters.Remove(r.Context(), id); err != nil {
+			return nil, deadLetterError(err)
+		}
+
+		s.Logger.Info("purged dead letter", zap.Stringer("deadLetterId", id))
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodDelete)
+
+	adminRouter.HandleJSONFunc("/admin/dead-letters/{id}/redrive", func(w http.ResponseWriter, r *http.Request) (any, error) {
+		id, err := idFromPath(r, "id")
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.RTEventRelay.Redrive(r.Context(), id); err != nil {
+			return nil, deadLetterError(err)
+		}
+
+		s.Logger.Info("redrove dead letter", zap.Stringer("deadLetterId", id))
+		return pkghttp.DefaultOKResponse(), nil
+	}).Methods(http.MethodPost)
+
 	return rawRouter.Build(), nil
 }
+
+func idFromPath(r *http.Request, name string) (pkgid.ID, error) {
+	id, err := pkgid.Parse(mux.Vars(r)[name])
+	if err != nil {
+		return pkgid.ID{}, pkgerrors.BadRequest(fmt.Errorf("invalid %s: %w", name, err))
+	}
+	return id, nil
+}
+
+func deadLetterError(err error) error {
+	if errors.Is(err, services.ErrDeadLetterNotFound) {
+		return pkgerrors.NotFound(err)
+	}
+	return err
+}
+
+func toDeadLetter(letter services.DeadLetter) (DeadLetter, error) {
+	event, err := protojson.Marshal(letter.Event)
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("marshalling event: %w", err)
+	}
+
+	return DeadLetter{
+		ID:          letter.ID,
+		RecipientID: letter.RecipientID,
+		Event:       event,
+		Reason:      letter.Reason,
+		Attempts:    letter.Attempts,
+		FailedAt:    letter.FailedAt,
+	}, nil
+}
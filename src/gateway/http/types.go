@@ -1,8 +1,15 @@
 package http
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/faustuzas/occa/src/gateway/services"
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	"github.com/faustuzas/occa/src/pkg/contacts"
+	"github.com/faustuzas/occa/src/pkg/groups"
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	"github.com/faustuzas/occa/src/pkg/receipts"
 )
 
 type RegistrationRequest struct {
@@ -24,6 +31,11 @@ type LoginResponse struct {
 	Error string `json:"error,omitempty"`
 }
 
+type HeartbeatRequest struct {
+	// Active tells whether the user has interacted with the client since the previous heartbeat.
+	Active bool `json:"active"`
+}
+
 type ActiveUsersResponse struct {
 	ActiveUsers []services.ActiveUser `json:"activeUsers"`
 }
@@ -36,3 +48,83 @@ type SendMessageRequest struct {
 	RecipientID pkgid.ID `json:"recipientId"`
 	Message     string   `json:"message"`
 }
+
+type SendMessageResponse struct {
+	MessageID string `json:"messageId"`
+}
+
+type TypingRequest struct {
+	RecipientID pkgid.ID `json:"recipientId"`
+	Typing      bool     `json:"typing"`
+}
+
+// ReceiptRequest acknowledges the message identified by the ID it has been delivered with.
+type ReceiptRequest struct {
+	Kind      receipts.Kind `json:"kind"`
+	SenderID  pkgid.ID      `json:"senderId"`
+	MessageID pkgid.ID      `json:"messageId"`
+}
+
+type HistoryResponse struct {
+	Messages   []pkgarchive.Message `json:"messages"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
+
+type DeadLetter struct {
+	ID          pkgid.ID        `json:"id"`
+	RecipientID pkgid.ID        `json:"recipientId"`
+	Event       json.RawMessage `json:"event"`
+	Reason      string          `json:"reason"`
+	Attempts    int             `json:"attempts"`
+	FailedAt    time.Time       `json:"failedAt"`
+}
+
+type DeadLettersResponse struct {
+	DeadLetters []DeadLetter `json:"deadLetters"`
+}
+
+type PurgeDeadLettersResponse struct {
+	Purged int `json:"purged"`
+}
+
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type RenameGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type GroupResponse struct {
+	Group   groups.Group    `json:"group"`
+	Members []groups.Member `json:"members,omitempty"`
+}
+
+type GroupsResponse struct {
+	Groups []groups.Group `json:"groups"`
+}
+
+type AddGroupMemberRequest struct {
+	UserID pkgid.ID    `json:"userId"`
+	Role   groups.Role `json:"role"`
+}
+
+type SetGroupMemberRoleRequest struct {
+	Role groups.Role `json:"role"`
+}
+
+type SendGroupMessageRequest struct {
+	Message string `json:"message"`
+}
+
+type ContactRequest struct {
+	UserID pkgid.ID `json:"userId"`
+}
+
+type ContactsResponse struct {
+	Contacts []contacts.Contact `json:"contacts"`
+}
+
+type BlocksResponse struct {
+	Blocked []contacts.Block `json:"blocked"`
+}
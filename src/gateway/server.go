@@ -8,12 +8,21 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/faustuzas/occa/src/gateway/http"
+	gwservices "github.com/faustuzas/occa/src/gateway/services"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
 	pkgconfig "github.com/faustuzas/occa/src/pkg/config"
+	"github.com/faustuzas/occa/src/pkg/contacts"
 	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
+	esclient "github.com/faustuzas/occa/src/pkg/eventserver/client"
+	esmembership "github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/eventserver/pending"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
+	"github.com/faustuzas/occa/src/pkg/groups"
 	pkghttp "github.com/faustuzas/occa/src/pkg/http"
 	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
 	pkgnet "github.com/faustuzas/occa/src/pkg/net"
+	"github.com/faustuzas/occa/src/pkg/receipts"
 )
 
 type Configuration struct {
@@ -21,10 +30,26 @@ type Configuration struct {
 
 	HTTPListenAddress *pkgnet.ListenAddr `yaml:"listenAddress"`
 
-	MemStore   pkgmemstore.Configuration       `yaml:"memstore"`
-	Auth       pkgauth.ValidatorConfiguration  `yaml:"auth"`
-	Registerer pkgauth.RegistererConfiguration `yaml:"registerer"`
-	Etcd       pkgetcd.Configuration           `yaml:"etcd"`
+	MemStore    pkgmemstore.Configuration        `yaml:"memstore"`
+	Auth        pkgauth.ValidatorConfiguration   `yaml:"auth"`
+	ServiceAuth pkgauth.ServiceAuthConfiguration `yaml:"serviceAuth"`
+	Registerer  pkgauth.RegistererConfiguration  `yaml:"registerer"`
+	Etcd        pkgetcd.Configuration            `yaml:"etcd"`
+	Archiver    archiverclient.Configuration     `yaml:"archiver"`
+	Groups      groups.Configuration             `yaml:"groups"`
+	Receipts    receipts.Configuration           `yaml:"receipts"`
+	Contacts    contacts.Configuration           `yaml:"contacts"`
+
+	PendingEvents pending.Configuration              `yaml:"pendingEvents"`
+	Replay        esreplay.Configuration             `yaml:"replay"`
+	Relay         gwservices.RelayConfiguration      `yaml:"relay"`
+	DeadLetters   gwservices.DeadLetterConfiguration `yaml:"deadLetters"`
+	Typing        gwservices.TypingConfiguration     `yaml:"typing"`
+	Presence      gwservices.PresenceConfiguration   `yaml:"presence"`
+
+	EventServerSelection esmembership.SelectionConfiguration `yaml:"eventServerSelection"`
+	Membership           esmembership.Configuration          `yaml:"membership"`
+	EventServerClient    esclient.Configuration              `yaml:"eventServerClient"`
 }
 
 type Params struct {
@@ -53,6 +78,15 @@ func Start(p Params) error {
 		AuthMiddleware:      services.HTTPAuthMiddleware,
 		ActiveUsersTracker:  services.ActiveUserTracker,
 		EventServerSelector: services.EventServerRegistry,
+		EventServerRegistry: services.EventServerRegistry,
+		RTEventRelay:        services.RTEventsRelay,
+		GroupManager:        services.GroupManager,
+		ContactManager:      services.ContactManager,
+		ReceiptTracker:      services.ReceiptTracker,
+		TypingNotifier:      services.TypingNotifier,
+		DeadLetters:         services.DeadLetters,
+		AdminMiddleware:     services.AdminMiddleware,
+		Archiver:            services.Archiver,
 		Logger:              p.Logger,
 		Registry:            services.MetricsRegistry,
 	})
@@ -8,6 +8,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/faustuzas/occa/src/gateway/services"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
 	pkgclock "github.com/faustuzas/occa/src/pkg/clock"
 	esclient "github.com/faustuzas/occa/src/pkg/eventserver/client"
@@ -25,7 +26,14 @@ type Services struct {
 	AuthRegisterer      pkgauth.Registerer
 	ActiveUserTracker   services.ActiveUsersTracker
 	RTEventsRelay       services.RealTimeEventRelay
+	GroupManager        services.GroupManager
+	ContactManager      services.ContactManager
+	ReceiptTracker      services.ReceiptTracker
+	TypingNotifier      services.TypingNotifier
+	DeadLetters         services.DeadLetterStore
+	AdminMiddleware     httpmiddleware.Middleware
 	EventServerRegistry *esmembership.ServerRegistry
+	Archiver            archiverclient.Client
 
 	MetricsRegistry *prometheus.Registry
 }
@@ -61,6 +69,11 @@ func (p Params) StartServices() (_ Services, err error) {
 		return Services{}, fmt.Errorf("building HTTP auth middleware: %w", err)
 	}
 
+	adminMiddleware, err := p.ServiceAuth.BuildHTTPMiddleware(inst)
+	if err != nil {
+		return Services{}, fmt.Errorf("building HTTP admin middleware: %w", err)
+	}
+
 	tokenIssuer, err := p.Registerer.TokenIssuer.Build()
 	if err != nil {
 		return Services{}, fmt.Errorf("building JWT token issuer: %w", err)
@@ -73,25 +86,91 @@ func (p Params) StartServices() (_ Services, err error) {
 	starters = append(starters, usersDB)
 	closers = append(closers, usersDB)
 
-	activeUsersTracker, err := services.NewActiveUsersTracker(memStore, clock)
+	activeUsersTracker, err := services.NewActiveUsersTracker(memStore, clock, p.Presence)
 	if err != nil {
 		return Services{}, fmt.Errorf("building active users tracker: %w", err)
 	}
 
-	etcdClient, err := p.Etcd.Build()
+	membershipBackend, err := p.Membership.Build(inst, p.Etcd, memStore)
 	if err != nil {
-		return Services{}, fmt.Errorf("building etcd client: %w", err)
+		return Services{}, fmt.Errorf("building event server membership backend: %w", err)
 	}
 
-	eventServersRegistry := esmembership.NewServerRegistry(inst, etcdClient)
+	selectionStrategy, err := p.EventServerSelection.Build()
+	if err != nil {
+		return Services{}, fmt.Errorf("building event server selection strategy: %w", err)
+	}
+
+	eventServersRegistry, err := esmembership.NewServerRegistry(inst, membershipBackend, selectionStrategy)
+	if err != nil {
+		return Services{}, fmt.Errorf("building event server registry: %w", err)
+	}
 	starters = append(starters, eventServersRegistry)
-	closers = append(closers, eventServersRegistry)
+	closers = append(closers, eventServersRegistry, membershipBackend)
 
-	esPool := esclient.NewPool(inst, eventServersRegistry)
+	serviceCredentials, err := p.ServiceAuth.BuildGRPCCredentials()
+	if err != nil {
+		return Services{}, fmt.Errorf("building service credentials: %w", err)
+	}
+
+	esPool, err := esclient.NewPool(inst, eventServersRegistry, serviceCredentials, p.EventServerClient)
+	if err != nil {
+		return Services{}, fmt.Errorf("building event server client pool: %w", err)
+	}
+	eventServersRegistry.OnServerRemoved(esPool.Evict)
 	closers = append(closers, esPool)
 
-	rtServerResolver := rtconn.NewServerResolver(inst, memStore)
-	rtRelay := services.NewRealTimeEventRelay(inst, rtServerResolver, esPool)
+	serviceHeaders, err := p.ServiceAuth.BuildHTTPHeaders()
+	if err != nil {
+		return Services{}, fmt.Errorf("building service HTTP headers: %w", err)
+	}
+
+	archiver, err := p.Archiver.Build(serviceHeaders)
+	if err != nil {
+		return Services{}, fmt.Errorf("building archiver client: %w", err)
+	}
+
+	// with consistent hashing users are expected on their hash server even if the connection record is missing
+	var affinity rtconn.AffinityLocator
+	if p.EventServerSelection.Strategy == esmembership.SelectionStrategyConsistentHash {
+		affinity = eventServersRegistry
+	}
+
+	rtServerResolver := rtconn.NewServerResolver(inst, memStore, affinity)
+	pendingEvents := p.PendingEvents.Build(inst, memStore)
+	replayLog := p.Replay.Build(inst, memStore)
+	deadLetters := p.DeadLetters.Build(inst, memStore)
+	rtRelay, err := services.NewRealTimeEventRelay(inst, rtServerResolver, eventServersRegistry, esPool, archiver, pendingEvents, replayLog, deadLetters, p.Relay)
+	if err != nil {
+		return Services{}, fmt.Errorf("building real-time event relay: %w", err)
+	}
+
+	groupsDB, err := p.Groups.Build()
+	if err != nil {
+		return Services{}, fmt.Errorf("building groups db connection: %w", err)
+	}
+	starters = append(starters, groupsDB)
+	closers = append(closers, groupsDB)
+
+	contactsDB, err := p.Contacts.Build()
+	if err != nil {
+		return Services{}, fmt.Errorf("building contacts db connection: %w", err)
+	}
+	starters = append(starters, contactsDB)
+	closers = append(closers, contactsDB)
+
+	onlineUsers := rtconn.NewOnlineUsers(inst, memStore)
+	presenceNotifier := services.NewPresenceNotifier(inst, onlineUsers, activeUsersTracker, services.NewContactsAudience(contactsDB), rtRelay)
+	starters = append(starters, presenceNotifier)
+	// notifier uses the stores while handling changes, so it has to stop before them
+	closers = append(pkgio.Closers{presenceNotifier}, closers...)
+
+	receiptsDB, err := p.Receipts.Build()
+	if err != nil {
+		return Services{}, fmt.Errorf("building receipts db connection: %w", err)
+	}
+	starters = append(starters, receiptsDB)
+	closers = append(closers, receiptsDB)
 
 	if err = starters.Start(context.Background()); err != nil {
 		return Services{}, fmt.Errorf("starting services: %w", err)
@@ -102,7 +181,14 @@ func (p Params) StartServices() (_ Services, err error) {
 		HTTPAuthMiddleware:  httpAuthMiddleware,
 		AuthRegisterer:      pkgauth.NewRegisterer(usersDB, tokenIssuer),
 		RTEventsRelay:       rtRelay,
+		DeadLetters:         deadLetters,
+		GroupManager:        services.NewGroupManager(groupsDB, rtRelay),
+		ContactManager:      services.NewContactManager(contactsDB),
+		ReceiptTracker:      services.NewReceiptTracker(receiptsDB, archiver, rtRelay, clock),
+		TypingNotifier:      services.NewTypingNotifier(memStore, rtRelay, clock, p.Typing),
+		AdminMiddleware:     adminMiddleware,
 		EventServerRegistry: eventServersRegistry,
+		Archiver:            archiver,
 		MetricsRegistry:     registry,
 
 		Closers: closers,
@@ -3,88 +3,373 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
 	pkgclock "github.com/faustuzas/occa/src/pkg/clock"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
-	pkgslices "github.com/faustuzas/occa/src/pkg/slices"
 )
 
 const (
+	// activeUserTTL is how long the user is considered connected after the last heartbeat
 	activeUserTTL         = 30 * time.Second
 	activeUsersCollection = "active_users"
+
+	// presenceRetention is how long presence is kept after the last heartbeat, so the last-seen
+	// time and the chosen status survive the user going offline
+	presenceRetention = 30 * 24 * time.Hour
+
+	maxStatusMessageLength = 140
+	maxPresenceUpdates     = 5
+
+	defaultAwayAfter = 5 * time.Minute
+)
+
+// PresenceStatus is the status the user has chosen or, as seen by others, the effective status.
+type PresenceStatus string
+
+const (
+	PresenceOnline       PresenceStatus = "online"
+	PresenceAway         PresenceStatus = "away"
+	PresenceDoNotDisturb PresenceStatus = "doNotDisturb"
+	// PresenceInvisible users are shown offline to others.
+	PresenceInvisible PresenceStatus = "invisible"
+	// PresenceOffline is never chosen, users without recent heartbeats are offline.
+	PresenceOffline PresenceStatus = "offline"
+)
+
+func (s PresenceStatus) Validate() error {
+	switch s {
+	case PresenceOnline, PresenceAway, PresenceDoNotDisturb, PresenceInvisible:
+		return nil
+	default:
+		return fmt.Errorf("unknown presence status %q", s)
+	}
+}
+
+// LastSeenVisibility controls who else can see when the user was last seen.
+type LastSeenVisibility string
+
+const (
+	LastSeenEveryone LastSeenVisibility = "everyone"
+	LastSeenNobody   LastSeenVisibility = "nobody"
 )
 
+func (v LastSeenVisibility) Validate() error {
+	switch v {
+	case LastSeenEveryone, LastSeenNobody:
+		return nil
+	default:
+		return fmt.Errorf("unknown last seen visibility %q", v)
+	}
+}
+
+type PresenceConfiguration struct {
+	// AwayAfter is how long online users may stay idle before they are shown away.
+	AwayAfter time.Duration `yaml:"awayAfter"`
+}
+
+func (c PresenceConfiguration) withDefaults() PresenceConfiguration {
+	if c.AwayAfter <= 0 {
+		c.AwayAfter = defaultAwayAfter
+	}
+
+	return c
+}
+
+// ActiveUser is the presence of the user as seen by the authenticated user.
 type ActiveUser struct {
-	ID       pkgid.ID  `json:"id"`
-	Username string    `json:"username"`
-	LastSeen time.Time `json:"lastSeen"`
+	ID            pkgid.ID       `json:"id"`
+	Username      string         `json:"username"`
+	Status        PresenceStatus `json:"status"`
+	StatusMessage string         `json:"statusMessage,omitempty"`
+
+	// LastSeen is nil when the user hides it.
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+}
+
+// PresenceUpdate changes the presence settings of the user, nil fields are left as they are.
+type PresenceUpdate struct {
+	Status             *PresenceStatus     `json:"status,omitempty"`
+	StatusMessage      *string             `json:"statusMessage,omitempty"`
+	LastSeenVisibility *LastSeenVisibility `json:"lastSeenVisibility,omitempty"`
+}
+
+func (u PresenceUpdate) Validate() error {
+	if u.Status != nil {
+		if err := u.Status.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if u.StatusMessage != nil && len([]rune(*u.StatusMessage)) > maxStatusMessageLength {
+		return fmt.Errorf("status message is longer than %d characters", maxStatusMessageLength)
+	}
+
+	if u.LastSeenVisibility != nil {
+		if err := u.LastSeenVisibility.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (u *ActiveUser) marshall() ([]byte, error) {
-	return json.Marshal(u)
+// presence is what is stored about the user in the active users collection.
+type presence struct {
+	ID       pkgid.ID `json:"id"`
+	Username string   `json:"username"`
+
+	// LastSeen is the time of the last heartbeat, LastActive of the last heartbeat the
+	// user was interacting with the client.
+	LastSeen   time.Time `json:"lastSeen"`
+	LastActive time.Time `json:"lastActive"`
+
+	Status             PresenceStatus     `json:"status"`
+	StatusMessage      string             `json:"statusMessage,omitempty"`
+	LastSeenVisibility LastSeenVisibility `json:"lastSeenVisibility"`
 }
 
-func (u *ActiveUser) unmarshall(data []byte) error {
-	return json.Unmarshal(data, u)
+func (p *presence) marshall() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *presence) unmarshall(data []byte) error {
+	if err := json.Unmarshal(data, p); err != nil {
+		return err
+	}
+
+	// records written before presence settings existed
+	if p.Status == "" {
+		p.Status = PresenceOnline
+	}
+	if p.LastSeenVisibility == "" {
+		p.LastSeenVisibility = LastSeenEveryone
+	}
+	if p.LastActive.IsZero() {
+		p.LastActive = p.LastSeen
+	}
+	return nil
 }
 
 type ActiveUsersTracker interface {
-	// HeartBeat marks the authenticated user as active.
-	HeartBeat(ctx context.Context) error
+	// HeartBeat marks the authenticated user as connected. Active tells whether the user has
+	// interacted with the client since the previous heartbeat.
+	HeartBeat(ctx context.Context, active bool) error
+
+	// ActiveUsers returns the users among the given ones which are online as seen by the authenticated user.
+	ActiveUsers(ctx context.Context, userIDs []pkgid.ID) ([]ActiveUser, error)
+
+	// SetPresence changes the presence settings of the authenticated user.
+	SetPresence(ctx context.Context, update PresenceUpdate) error
 
-	// ActiveUsers returns all known active users in the system.
-	ActiveUsers(ctx context.Context) ([]ActiveUser, error)
+	// Presence returns the presence of the user as seen by the authenticated user.
+	Presence(ctx context.Context, userID pkgid.ID) (ActiveUser, error)
+
+	// Status returns the status the user has chosen, online if the user has never chosen one.
+	Status(ctx context.Context, userID pkgid.ID) (PresenceStatus, error)
 }
 
 type tracker struct {
-	store pkgmemstore.Store
-	clock pkgclock.Clock
+	store  pkgmemstore.Store
+	clock  pkgclock.Clock
+	config PresenceConfiguration
 }
 
-func NewActiveUsersTracker(store pkgmemstore.Store, clock pkgclock.Clock) (ActiveUsersTracker, error) {
+func NewActiveUsersTracker(store pkgmemstore.Store, clock pkgclock.Clock, config PresenceConfiguration) (ActiveUsersTracker, error) {
 	return &tracker{
-		store: store,
-		clock: clock,
+		store:  store,
+		clock:  clock,
+		config: config.withDefaults(),
 	}, nil
 }
 
-func (r *tracker) HeartBeat(ctx context.Context) error {
-	var (
-		principal = pkgauth.PrincipalFromContext(ctx)
-		usr       = ActiveUser{
-			ID:       principal.ID,
-			Username: principal.UserName,
-			LastSeen: r.clock.Now(),
+func (r *tracker) HeartBeat(ctx context.Context, active bool) error {
+	principal := pkgauth.PrincipalFromContext(ctx)
+
+	return r.update(ctx, principal, func(p *presence) {
+		now := r.clock.Now()
+
+		p.Username = principal.UserName
+		p.LastSeen = now
+		if active {
+			p.LastActive = now
 		}
-	)
+	})
+}
 
-	bytes, err := usr.marshall()
+func (r *tracker) SetPresence(ctx context.Context, update PresenceUpdate) error {
+	if err := update.Validate(); err != nil {
+		return pkgerrors.BadRequest(err)
+	}
+
+	principal := pkgauth.PrincipalFromContext(ctx)
+
+	return r.update(ctx, principal, func(p *presence) {
+		now := r.clock.Now()
+
+		// setting the presence is an interaction with the client
+		p.Username = principal.UserName
+		p.LastSeen, p.LastActive = now, now
+
+		if update.Status != nil {
+			p.Status = *update.Status
+		}
+		if update.StatusMessage != nil {
+			p.StatusMessage = *update.StatusMessage
+		}
+		if update.LastSeenVisibility != nil {
+			p.LastSeenVisibility = *update.LastSeenVisibility
+		}
+	})
+}
+
+// update modifies the presence of the user, concurrent updates are retried.
+func (r *tracker) update(ctx context.Context, principal pkgauth.Principal, modify func(p *presence)) error {
+	key := principal.ID.String()
+
+	for attempt := 0; attempt < maxPresenceUpdates; attempt++ {
+		current, err := r.store.GetCollectionItem(ctx, activeUsersCollection, key)
+		if err != nil && !errors.Is(err, pkgmemstore.ErrNotFound) {
+			return fmt.Errorf("fetching presence: %w", err)
+		}
+
+		p := presence{
+			ID:                 principal.ID,
+			Status:             PresenceOnline,
+			LastSeenVisibility: LastSeenEveryone,
+		}
+		if current != nil {
+			if err = p.unmarshall(current); err != nil {
+				return fmt.Errorf("unmarshaling presence: %w", err)
+			}
+		}
+
+		modify(&p)
+
+		bytes, err := p.marshall()
+		if err != nil {
+			return fmt.Errorf("marshaling presence: %w", err)
+		}
+
+		swapped, err := r.store.CompareAndSwapCollectionItem(ctx, activeUsersCollection, key, current, bytes, presenceRetention)
+		if err != nil {
+			return fmt.Errorf("storing presence: %w", err)
+		}
+		if swapped {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("presence of the user is being updated concurrently")
+}
+
+func (r *tracker) ActiveUsers(ctx context.Context, userIDs []pkgid.ID) ([]ActiveUser, error) {
+	viewerID := pkgauth.PrincipalFromContext(ctx).ID
+
+	users := make([]ActiveUser, 0, len(userIDs))
+	for _, userID := range userIDs {
+		data, err := r.store.GetCollectionItem(ctx, activeUsersCollection, userID.String())
+		if errors.Is(err, pkgmemstore.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetching presence: %w", err)
+		}
+
+		var p presence
+		if err = p.unmarshall(data); err != nil {
+			return nil, fmt.Errorf("unmarshaling active user: %w", err)
+		}
+
+		if user := r.view(p, viewerID); user.Status != PresenceOffline {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *tracker) Presence(ctx context.Context, userID pkgid.ID) (ActiveUser, error) {
+	data, err := r.store.GetCollectionItem(ctx, activeUsersCollection, userID.String())
+	if errors.Is(err, pkgmemstore.ErrNotFound) {
+		return ActiveUser{}, pkgerrors.NotFound(fmt.Errorf("presence of user %s is unknown", userID))
+	}
 	if err != nil {
-		return fmt.Errorf("marshaling active user: %w", err)
+		return ActiveUser{}, fmt.Errorf("fetching presence: %w", err)
 	}
 
-	if err = r.store.SetCollectionItemWithTTL(ctx, activeUsersCollection, usr.ID.String(), bytes, activeUserTTL); err != nil {
-		return fmt.Errorf("storing active user: %w", err)
+	var p presence
+	if err = p.unmarshall(data); err != nil {
+		return ActiveUser{}, fmt.Errorf("unmarshaling presence: %w", err)
 	}
 
-	return nil
+	return r.view(p, pkgauth.PrincipalFromContext(ctx).ID), nil
 }
 
-func (r *tracker) ActiveUsers(ctx context.Context) ([]ActiveUser, error) {
-	users, err := r.store.ListCollection(ctx, activeUsersCollection)
+func (r *tracker) Status(ctx context.Context, userID pkgid.ID) (PresenceStatus, error) {
+	data, err := r.store.GetCollectionItem(ctx, activeUsersCollection, userID.String())
+	if errors.Is(err, pkgmemstore.ErrNotFound) {
+		return PresenceOnline, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("fetching users: %w", err)
+		return "", fmt.Errorf("fetching presence: %w", err)
 	}
 
-	return pkgslices.MapE(users, func(b []byte) (ActiveUser, error) {
-		var usr ActiveUser
-		if err := usr.unmarshall(b); err != nil {
-			return ActiveUser{}, fmt.Errorf("unmarshaling active user: %w", err)
+	var p presence
+	if err = p.unmarshall(data); err != nil {
+		return "", fmt.Errorf("unmarshaling presence: %w", err)
+	}
+	return p.Status, nil
+}
+
+// view returns the presence as the viewer is allowed to see it.
+func (r *tracker) view(p presence, viewerID pkgid.ID) ActiveUser {
+	var (
+		self = p.ID == viewerID
+		user = ActiveUser{
+			ID:            p.ID,
+			Username:      p.Username,
+			Status:        r.effectiveStatus(p),
+			StatusMessage: p.StatusMessage,
 		}
-		return usr, nil
-	})
+	)
+
+	if self {
+		// users see their own settings as they are, invisible included
+		if r.clock.Now().Sub(p.LastSeen) <= activeUserTTL {
+			user.Status = p.Status
+		}
+		lastSeen := p.LastSeen
+		user.LastSeen = &lastSeen
+		return user
+	}
+
+	if user.Status == PresenceOffline {
+		user.StatusMessage = ""
+	}
+
+	// invisible users are not seen at all, so neither is their last-seen time
+	if p.LastSeenVisibility == LastSeenEveryone && p.Status != PresenceInvisible {
+		lastSeen := p.LastSeen
+		user.LastSeen = &lastSeen
+	}
+	return user
+}
+
+// effectiveStatus is the status others see the user in.
+func (r *tracker) effectiveStatus(p presence) PresenceStatus {
+	now := r.clock.Now()
+
+	switch {
+	case now.Sub(p.LastSeen) > activeUserTTL, p.Status == PresenceInvisible:
+		return PresenceOffline
+	case p.Status == PresenceOnline && now.Sub(p.LastActive) > r.config.AwayAfter:
+		return PresenceAway
+	default:
+		return p.Status
+	}
 }
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+func TestActiveUsers_IdleUsersAreAway(t *testing.T) {
+	var (
+		clock   = &fixedClock{now: time.Now()}
+		tracker = newTestTracker(t, clock)
+
+		alice = userContext("alice")
+		bob   = userContext("bob")
+	)
+
+	require.NoError(t, tracker.HeartBeat(alice, true))
+	require.NoError(t, tracker.HeartBeat(bob, true))
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	require.NoError(t, tracker.HeartBeat(alice, false))
+	require.NoError(t, tracker.HeartBeat(bob, true))
+	require.Equal(t, PresenceAway, presenceOf(t, tracker, bob, alice).Status)
+	require.Equal(t, PresenceOnline, presenceOf(t, tracker, alice, bob).Status)
+
+	// users without heartbeats are offline, but their last-seen time is kept
+	clock.now = clock.now.Add(time.Minute)
+	require.NoError(t, tracker.HeartBeat(bob, true))
+
+	users, err := tracker.ActiveUsers(bob, []pkgid.ID{idOf(alice), idOf(bob), pkgid.NewID()})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "bob", users[0].Username)
+
+	offline := presenceOf(t, tracker, bob, alice)
+	require.Equal(t, PresenceOffline, offline.Status)
+	require.NotNil(t, offline.LastSeen)
+}
+
+func TestActiveUsers_PrivacySettings(t *testing.T) {
+	var (
+		clock   = &fixedClock{now: time.Now()}
+		tracker = newTestTracker(t, clock)
+
+		alice = userContext("alice")
+		bob   = userContext("bob")
+
+		invisible = PresenceInvisible
+		nobody    = LastSeenNobody
+		message   = "on holidays"
+	)
+
+	require.NoError(t, tracker.HeartBeat(bob, true))
+	require.NoError(t, tracker.SetPresence(alice, PresenceUpdate{StatusMessage: &message, LastSeenVisibility: &nobody}))
+
+	seen := presenceOf(t, tracker, bob, alice)
+	require.Equal(t, PresenceOnline, seen.Status)
+	require.Equal(t, message, seen.StatusMessage)
+	require.Nil(t, seen.LastSeen)
+
+	// users see their own last-seen time regardless
+	require.NotNil(t, presenceOf(t, tracker, alice, alice).LastSeen)
+
+	require.NoError(t, tracker.SetPresence(alice, PresenceUpdate{Status: &invisible}))
+	require.Equal(t, PresenceOffline, presenceOf(t, tracker, bob, alice).Status)
+	require.Empty(t, presenceOf(t, tracker, bob, alice).StatusMessage)
+	require.Equal(t, PresenceInvisible, presenceOf(t, tracker, alice, alice).Status)
+
+	users, err := tracker.ActiveUsers(bob, []pkgid.ID{idOf(alice), idOf(bob), pkgid.NewID()})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "bob", users[0].Username)
+
+	unknown := PresenceOffline
+	var gErr pkgerrors.GenericErr
+	require.ErrorAs(t, tracker.SetPresence(alice, PresenceUpdate{Status: &unknown}), &gErr)
+	require.Equal(t, pkgerrors.TypeBadRequest, gErr.Type())
+}
+
+func newTestTracker(t *testing.T, clock *fixedClock) ActiveUsersTracker {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+		items = map[string][]byte{}
+	)
+
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), activeUsersCollection, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, key string) ([]byte, error) {
+			if v, ok := items[key]; ok {
+				return v, nil
+			}
+			return nil, pkgmemstore.ErrNotFound
+		}).
+		AnyTimes()
+
+	store.EXPECT().
+		CompareAndSwapCollectionItem(gomock.Any(), activeUsersCollection, gomock.Any(), gomock.Any(), gomock.Any(), presenceRetention).
+		DoAndReturn(func(_ context.Context, _ string, key string, expected, value []byte, _ time.Duration) (bool, error) {
+			if current, ok := items[key]; ok != (expected != nil) || !bytes.Equal(current, expected) {
+				return false, nil
+			}
+			items[key] = value
+			return true, nil
+		}).
+		AnyTimes()
+
+	tracker, err := NewActiveUsersTracker(store, clock, PresenceConfiguration{AwayAfter: time.Minute})
+	require.NoError(t, err)
+	return tracker
+}
+
+func userContext(username string) context.Context {
+	return pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: pkgid.NewID(), UserName: username})
+}
+
+func presenceOf(t *testing.T, tracker ActiveUsersTracker, viewer context.Context, user context.Context) ActiveUser {
+	t.Helper()
+
+	presence, err := tracker.Presence(viewer, idOf(user))
+	require.NoError(t, err)
+	return presence
+}
+
+func idOf(user context.Context) pkgid.ID {
+	return pkgauth.PrincipalFromContext(user).ID
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	"github.com/faustuzas/occa/src/pkg/contacts"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+type ContactManager interface {
+	// Request asks the user to become a contact of the authenticated user. If the user has
+	// already asked the same, that request is accepted instead.
+	Request(ctx context.Context, userID pkgid.ID) (contacts.Contact, error)
+
+	// Accept accepts the request the user has sent to the authenticated user.
+	Accept(ctx context.Context, userID pkgid.ID) (contacts.Contact, error)
+
+	// Remove removes the contact, declines its request or withdraws the own one.
+	Remove(ctx context.Context, userID pkgid.ID) error
+
+	// Contacts returns the contacts and pending requests of the authenticated user.
+	Contacts(ctx context.Context) ([]contacts.Contact, error)
+
+	// ContactIDs returns the accepted contacts of the authenticated user.
+	ContactIDs(ctx context.Context) ([]pkgid.ID, error)
+
+	// Block stops the user from messaging the authenticated user and removes it from the contacts.
+	Block(ctx context.Context, userID pkgid.ID) error
+	Unblock(ctx context.Context, userID pkgid.ID) error
+	Blocked(ctx context.Context) ([]contacts.Block, error)
+
+	// CheckCanMessage fails with a forbidden error if either the authenticated user or
+	// the recipient has blocked the other.
+	CheckCanMessage(ctx context.Context, recipientID pkgid.ID) error
+
+	// CheckIsContact fails with a forbidden error unless the user is an accepted contact
+	// of the authenticated user.
+	CheckIsContact(ctx context.Context, userID pkgid.ID) error
+
+	// CheckCanJoin fails with a forbidden error if the user and any of the group members
+	// have blocked one another, since members message all the others.
+	CheckCanJoin(ctx context.Context, userID pkgid.ID, memberIDs []pkgid.ID) error
+}
+
+type contactManager struct {
+	store contacts.Store
+}
+
+func NewContactManager(store contacts.Store) ContactManager {
+	return &contactManager{
+		store: store,
+	}
+}
+
+func (m *contactManager) Request(ctx context.Context, userID pkgid.ID) (contacts.Contact, error) {
+	requesterID := pkgauth.PrincipalFromContext(ctx).ID
+	if requesterID == userID {
+		return contacts.Contact{}, pkgerrors.BadRequest(fmt.Errorf("users cannot add themselves as contacts"))
+	}
+
+	if err := m.checkNotBlocked(ctx, requesterID, userID); err != nil {
+		return contacts.Contact{}, err
+	}
+
+	existing, err := m.store.Get(ctx, requesterID, userID)
+	if err == nil && existing.Status == contacts.StatusIncoming {
+		// both users want the same, so there is nothing left to wait for
+		return m.accept(ctx, userID, requesterID)
+	}
+	if err != nil && !errors.Is(err, contacts.ErrContactNotFound) {
+		return contacts.Contact{}, err
+	}
+
+	contact, err := m.store.Request(ctx, requesterID, userID)
+	if err != nil {
+		return contacts.Contact{}, contactError(err)
+	}
+	return contact, nil
+}
+
+func (m *contactManager) Accept(ctx context.Context, userID pkgid.ID) (contacts.Contact, error) {
+	return m.accept(ctx, userID, pkgauth.PrincipalFromContext(ctx).ID)
+}
+
+func (m *contactManager) accept(ctx context.Context, requesterID, addresseeID pkgid.ID) (contacts.Contact, error) {
+	contact, err := m.store.Accept(ctx, requesterID, addresseeID)
+	if err != nil {
+		return contacts.Contact{}, contactError(err)
+	}
+	return contact, nil
+}
+
+func (m *contactManager) Remove(ctx context.Context, userID pkgid.ID) error {
+	if err := m.store.Remove(ctx, pkgauth.PrincipalFromContext(ctx).ID, userID); err != nil {
+		return contactError(err)
+	}
+	return nil
+}
+
+func (m *contactManager) Contacts(ctx context.Context) ([]contacts.Contact, error) {
+	return m.store.Contacts(ctx, pkgauth.PrincipalFromContext(ctx).ID)
+}
+
+func (m *contactManager) ContactIDs(ctx context.Context) ([]pkgid.ID, error) {
+	return acceptedContacts(ctx, m.store, pkgauth.PrincipalFromContext(ctx).ID)
+}
+
+func (m *contactManager) Block(ctx context.Context, userID pkgid.ID) error {
+	blockerID := pkgauth.PrincipalFromContext(ctx).ID
+	if blockerID == userID {
+		return pkgerrors.BadRequest(fmt.Errorf("users cannot block themselves"))
+	}
+
+	if err := m.store.Block(ctx, blockerID, userID); err != nil {
+		return err
+	}
+
+	// the block is stored first, so the contact cannot be requested again in between
+	if err := m.store.Remove(ctx, blockerID, userID); err != nil && !errors.Is(err, contacts.ErrContactNotFound) {
+		return fmt.Errorf("removing contact of blocked user: %w", err)
+	}
+	return nil
+}
+
+func (m *contactManager) Unblock(ctx context.Context, userID pkgid.ID) error {
+	if err := m.store.Unblock(ctx, pkgauth.PrincipalFromContext(ctx).ID, userID); err != nil {
+		return contactError(err)
+	}
+	return nil
+}
+
+func (m *contactManager) Blocked(ctx context.Context) ([]contacts.Block, error) {
+	return m.store.Blocked(ctx, pkgauth.PrincipalFromContext(ctx).ID)
+}
+
+func (m *contactManager) CheckCanMessage(ctx context.Context, recipientID pkgid.ID) error {
+	return m.checkNotBlocked(ctx, pkgauth.PrincipalFromContext(ctx).ID, recipientID)
+}
+
+func (m *contactManager) CheckIsContact(ctx context.Context, userID pkgid.ID) error {
+	contact, err := m.store.Get(ctx, pkgauth.PrincipalFromContext(ctx).ID, userID)
+	if err != nil && !errors.Is(err, contacts.ErrContactNotFound) {
+		return fmt.Errorf("fetching contact: %w", err)
+	}
+	if err != nil || contact.Status != contacts.StatusAccepted {
+		return pkgerrors.Forbidden(fmt.Errorf("user %s is not a contact", userID))
+	}
+	return nil
+}
+
+func (m *contactManager) CheckCanJoin(ctx context.Context, userID pkgid.ID, memberIDs []pkgid.ID) error {
+	blocked, err := m.store.IsBlockedWithAny(ctx, userID, memberIDs)
+	if err != nil {
+		return fmt.Errorf("checking blocks: %w", err)
+	}
+	if blocked {
+		return pkgerrors.Forbidden(fmt.Errorf("user %s cannot be reached", userID))
+	}
+	return nil
+}
+
+// checkNotBlocked does not tell which of the users has blocked the other, so users
+// cannot find out they have been blocked any more than they can by being ignored.
+func (m *contactManager) checkNotBlocked(ctx context.Context, userID, otherID pkgid.ID) error {
+	blocked, err := m.store.IsBlocked(ctx, userID, otherID)
+	if err != nil {
+		return fmt.Errorf("checking blocks: %w", err)
+	}
+	if blocked {
+		return pkgerrors.Forbidden(fmt.Errorf("user %s cannot be reached", otherID))
+	}
+	return nil
+}
+
+// acceptedContacts returns the users the user has accepted contacts with.
+func acceptedContacts(ctx context.Context, store contacts.Store, userID pkgid.ID) ([]pkgid.ID, error) {
+	all, err := store.Contacts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching contacts: %w", err)
+	}
+
+	ids := make([]pkgid.ID, 0, len(all))
+	for _, contact := range all {
+		if contact.Status == contacts.StatusAccepted {
+			ids = append(ids, contact.UserID)
+		}
+	}
+	return ids, nil
+}
+
+func contactError(err error) error {
+	switch {
+	case errors.Is(err, contacts.ErrContactNotFound), errors.Is(err, contacts.ErrNotBlocked):
+		return pkgerrors.NotFound(err)
+	case errors.Is(err, contacts.ErrAlreadyContact):
+		return pkgerrors.BadRequest(err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	"github.com/faustuzas/occa/src/pkg/contacts"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+func TestContactManager_MutualRequestIsAccepted(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = contacts.NewMockStore(ctrl)
+
+		userID  = pkgid.NewID()
+		otherID = pkgid.NewID()
+		ctx     = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+
+		accepted = contacts.Contact{UserID: otherID, Status: contacts.StatusAccepted}
+	)
+
+	store.EXPECT().IsBlocked(gomock.Any(), userID, otherID).Return(false, nil)
+	store.EXPECT().Get(gomock.Any(), userID, otherID).Return(contacts.Contact{UserID: otherID, Status: contacts.StatusIncoming}, nil)
+	store.EXPECT().Accept(gomock.Any(), otherID, userID).Return(accepted, nil)
+
+	contact, err := NewContactManager(store).Request(ctx, otherID)
+	require.NoError(t, err)
+	require.Equal(t, accepted, contact)
+}
+
+func TestContactManager_RequestRules(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = contacts.NewMockStore(ctrl)
+
+		userID  = pkgid.NewID()
+		otherID = pkgid.NewID()
+		ctx     = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+
+		manager = NewContactManager(store)
+	)
+
+	_, err := manager.Request(ctx, userID)
+	requireErrorType(t, pkgerrors.TypeBadRequest, err)
+
+	store.EXPECT().IsBlocked(gomock.Any(), userID, otherID).Return(true, nil)
+	_, err = manager.Request(ctx, otherID)
+	requireErrorType(t, pkgerrors.TypeForbidden, err)
+
+	store.EXPECT().IsBlocked(gomock.Any(), userID, otherID).Return(false, nil)
+	store.EXPECT().Get(gomock.Any(), userID, otherID).Return(contacts.Contact{UserID: otherID, Status: contacts.StatusOutgoing}, nil)
+	store.EXPECT().Request(gomock.Any(), userID, otherID).Return(contacts.Contact{}, fmt.Errorf("%w: %s", contacts.ErrAlreadyContact, otherID))
+	_, err = manager.Request(ctx, otherID)
+	requireErrorType(t, pkgerrors.TypeBadRequest, err)
+}
+
+func TestContactManager_BlockRemovesContactAndStopsMessages(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = contacts.NewMockStore(ctrl)
+
+		userID  = pkgid.NewID()
+		otherID = pkgid.NewID()
+		ctx     = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+
+		manager = NewContactManager(store)
+	)
+
+	gomock.InOrder(
+		store.EXPECT().Block(gomock.Any(), userID, otherID).Return(nil),
+		store.EXPECT().Remove(gomock.Any(), userID, otherID).Return(nil),
+	)
+	require.NoError(t, manager.Block(ctx, otherID))
+
+	// users without a contact can be blocked as well
+	store.EXPECT().Block(gomock.Any(), userID, otherID).Return(nil)
+	store.EXPECT().Remove(gomock.Any(), userID, otherID).Return(fmt.Errorf("%w: %s", contacts.ErrContactNotFound, otherID))
+	require.NoError(t, manager.Block(ctx, otherID))
+
+	store.EXPECT().IsBlocked(gomock.Any(), userID, otherID).Return(true, nil)
+	requireErrorType(t, pkgerrors.TypeForbidden, manager.CheckCanMessage(ctx, otherID))
+
+	store.EXPECT().Unblock(gomock.Any(), userID, otherID).Return(fmt.Errorf("%w: %s", contacts.ErrNotBlocked, otherID))
+	requireErrorType(t, pkgerrors.TypeNotFound, manager.Unblock(ctx, otherID))
+}
+
+func TestContactManager_CheckIsContact(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = contacts.NewMockStore(ctrl)
+
+		userID  = pkgid.NewID()
+		otherID = pkgid.NewID()
+		ctx     = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+
+		manager = NewContactManager(store)
+	)
+
+	store.EXPECT().Get(gomock.Any(), userID, otherID).Return(contacts.Contact{UserID: otherID, Status: contacts.StatusAccepted}, nil)
+	require.NoError(t, manager.CheckIsContact(ctx, otherID))
+
+	store.EXPECT().Get(gomock.Any(), userID, otherID).Return(contacts.Contact{UserID: otherID, Status: contacts.StatusOutgoing}, nil)
+	requireErrorType(t, pkgerrors.TypeForbidden, manager.CheckIsContact(ctx, otherID))
+
+	store.EXPECT().Get(gomock.Any(), userID, otherID).Return(contacts.Contact{}, fmt.Errorf("%w: %s", contacts.ErrContactNotFound, otherID))
+	requireErrorType(t, pkgerrors.TypeForbidden, manager.CheckIsContact(ctx, otherID))
+}
+
+func TestContactManager_CheckCanJoin(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = contacts.NewMockStore(ctrl)
+
+		userID    = pkgid.NewID()
+		memberIDs = []pkgid.ID{pkgid.NewID(), pkgid.NewID()}
+		ctx       = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: memberIDs[0]})
+
+		manager = NewContactManager(store)
+	)
+
+	store.EXPECT().IsBlockedWithAny(gomock.Any(), userID, memberIDs).Return(false, nil)
+	require.NoError(t, manager.CheckCanJoin(ctx, userID, memberIDs))
+
+	// the user has blocked a member other than the one adding it
+	store.EXPECT().IsBlockedWithAny(gomock.Any(), userID, memberIDs).Return(true, nil)
+	requireErrorType(t, pkgerrors.TypeForbidden, manager.CheckCanJoin(ctx, userID, memberIDs))
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+const (
+	deadLettersCollection = "dead_letters"
+
+	defaultDeadLetterRetention = 7 * 24 * time.Hour
+)
+
+// ErrDeadLetterNotFound is returned when the dead letter does not exist, e.g. it has been
+// redriven, purged or has expired.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+type DeadLetterConfiguration struct {
+	// Retention is how long an undeliverable event is kept for on-call to recover it.
+	Retention time.Duration `yaml:"retention"`
+}
+
+func (c DeadLetterConfiguration) withDefaults() DeadLetterConfiguration {
+	if c.Retention <= 0 {
+		c.Retention = defaultDeadLetterRetention
+	}
+
+	return c
+}
+
+func (c DeadLetterConfiguration) Build(i pkginstrument.Instrumentation, store pkgmemstore.Store) DeadLetterStore {
+	return NewMemStoreDeadLetterStore(i, store, c)
+}
+
+// DeadLetter is an event which could not be delivered nor stored for later delivery.
+type DeadLetter struct {
+	ID          pkgid.ID
+	RecipientID pkgid.ID
+	Event       *rteventspb.Event
+
+	// Reason is the error of the last delivery attempt.
+	Reason   string
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeadLetterStore keeps undeliverable events, so they could be inspected and redriven once
+// the cause of the failure is fixed.
+type DeadLetterStore interface {
+	Add(ctx context.Context, letter DeadLetter) error
+
+	// List returns all the dead letters, from the oldest to the newest.
+	List(ctx context.Context) ([]DeadLetter, error)
+
+	Get(ctx context.Context, id pkgid.ID) (DeadLetter, error)
+
+	// Remove deletes the dead letter, only a single caller succeeds removing it.
+	Remove(ctx context.Context, id pkgid.ID) error
+
+	// Purge deletes all the dead letters and returns how many were deleted.
+	Purge(ctx context.Context) (int, error)
+}
+
+type deadLetterEntry struct {
+	ID          pkgid.ID  `json:"id"`
+	RecipientID pkgid.ID  `json:"recipientId"`
+	Event       []byte    `json:"event"`
+	Reason      string    `json:"reason"`
+	Attempts    int       `json:"attempts"`
+	FailedAt    time.Time `json:"failedAt"`
+}
+
+type memStoreDeadLetterStore struct {
+	store  pkgmemstore.Store
+	config DeadLetterConfiguration
+
+	i pkginstrument.Instrumentation
+}
+
+func NewMemStoreDeadLetterStore(i pkginstrument.Instrumentation, store pkgmemstore.Store, config DeadLetterConfiguration) DeadLetterStore {
+	return &memStoreDeadLetterStore{
+		store:  store,
+		config: config.withDefaults(),
+
+		i: i,
+	}
+}
+
+func (s *memStoreDeadLetterStore) Add(ctx context.Context, letter DeadLetter) error {
+	eventBytes, err := proto.Marshal(letter.Event)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %w", err)
+	}
+
+	data, err := json.Marshal(deadLetterEntry{
+		ID:          letter.ID,
+		RecipientID: letter.RecipientID,
+		Event:       eventBytes,
+		Reason:      letter.Reason,
+		Attempts:    letter.Attempts,
+		FailedAt:    letter.FailedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling dead letter: %w", err)
+	}
+
+	err = s.store.SetCollectionItemWithTTL(ctx, deadLettersCollection, letter.ID.String(), data, s.config.Retention)
+	if err != nil {
+		return fmt.Errorf("storing dead letter: %w", err)
+	}
+
+	return nil
+}
+
+func (s *memStoreDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	values, err := s.store.ListCollection(ctx, deadLettersCollection)
+	if err != nil {
+		return nil, fmt.Errorf("listing dead letters: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(values))
+	for _, v := range values {
+		letter, err := decodeDeadLetter(v)
+		if err != nil {
+			s.i.Logger.Error("skipping malformed dead letter", zap.Error(err))
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(a, b int) bool {
+		return letters[a].FailedAt.Before(letters[b].FailedAt)
+	})
+
+	return letters, nil
+}
+
+func (s *memStoreDeadLetterStore) Get(ctx context.Context, id pkgid.ID) (DeadLetter, error) {
+	letter, _, err := s.get(ctx, id)
+	return letter, err
+}
+
+func (s *memStoreDeadLetterStore) get(ctx context.Context, id pkgid.ID) (DeadLetter, []byte, error) {
+	value, err := s.store.GetCollectionItem(ctx, deadLettersCollection, id.String())
+	if errors.Is(err, pkgmemstore.ErrNotFound) {
+		return DeadLetter{}, nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	if err != nil {
+		return DeadLetter{}, nil, fmt.Errorf("fetching dead letter: %w", err)
+	}
+
+	letter, err := decodeDeadLetter(value)
+	if err != nil {
+		return DeadLetter{}, nil, err
+	}
+	return letter, value, nil
+}
+
+func (s *memStoreDeadLetterStore) Remove(ctx context.Context, id pkgid.ID) error {
+	_, value, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.store.CompareAndSwapCollectionItem(ctx, deadLettersCollection, id.String(), value, nil, 0)
+	if err != nil {
+		return fmt.Errorf("removing dead letter: %w", err)
+	}
+	if !removed {
+		// someone else has removed it in the meantime
+		return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+
+	return nil
+}
+
+func (s *memStoreDeadLetterStore) Purge(ctx context.Context) (int, error) {
+	keys, err := s.store.ListCollectionKeys(ctx, deadLettersCollection)
+	if err != nil {
+		return 0, fmt.Errorf("listing dead letters: %w", err)
+	}
+
+	purged := 0
+	for _, key := range keys {
+		id, err := pkgid.Parse(key)
+		if err != nil {
+			s.i.Logger.Error("skipping dead letter with malformed id", zap.String("key", key), zap.Error(err))
+			continue
+		}
+
+		err = s.Remove(ctx, id)
+		if errors.Is(err, ErrDeadLetterNotFound) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func decodeDeadLetter(value []byte) (DeadLetter, error) {
+	var e deadLetterEntry
+	if err := json.Unmarshal(value, &e); err != nil {
+		return DeadLetter{}, fmt.Errorf("unmarshalling dead letter: %w", err)
+	}
+
+	var event rteventspb.Event
+	if err := proto.Unmarshal(e.Event, &event); err != nil {
+		return DeadLetter{}, fmt.Errorf("unmarshalling event: %w", err)
+	}
+
+	return DeadLetter{
+		ID:          e.ID,
+		RecipientID: e.RecipientID,
+		Event:       &event,
+		Reason:      e.Reason,
+		Attempts:    e.Attempts,
+		FailedAt:    e.FailedAt,
+	}, nil
+}
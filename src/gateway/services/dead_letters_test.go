@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestDeadLetters_RoundTrip(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+
+		deadLetters = NewMemStoreDeadLetterStore(pkgtest.Instrumentation, store, DeadLetterConfiguration{Retention: time.Hour})
+
+		older = DeadLetter{
+			ID:          pkgid.NewID(),
+			RecipientID: pkgid.NewID(),
+			Event:       rteventspb.NewDirectMessageEvent(pkgid.NewID(), "older"),
+			Reason:      "connection refused",
+			Attempts:    1,
+			FailedAt:    time.Now().Add(-time.Minute).UTC(),
+		}
+		newer = DeadLetter{
+			ID:          pkgid.NewID(),
+			RecipientID: pkgid.NewID(),
+			Event:       rteventspb.NewDirectMessageEvent(pkgid.NewID(), "newer"),
+			Reason:      "circuit breaker of the server is open",
+			Attempts:    2,
+			FailedAt:    time.Now().UTC(),
+		}
+
+		stored = map[string][]byte{}
+	)
+
+	store.EXPECT().
+		SetCollectionItemWithTTL(gomock.Any(), deadLettersCollection, gomock.Any(), gomock.Any(), time.Hour).
+		DoAndReturn(func(_ context.Context, _ string, key string, value []byte, _ time.Duration) error {
+			stored[key] = value
+			return nil
+		}).
+		Times(2)
+
+	require.NoError(t, deadLetters.Add(context.Background(), newer))
+	require.NoError(t, deadLetters.Add(context.Background(), older))
+
+	store.EXPECT().
+		ListCollection(gomock.Any(), deadLettersCollection).
+		Return([][]byte{stored[newer.ID.String()], []byte("malformed"), stored[older.ID.String()]}, nil)
+
+	letters, err := deadLetters.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	requireDeadLetter(t, older, letters[0])
+	requireDeadLetter(t, newer, letters[1])
+}
+
+func TestDeadLetters_RemoveSucceedsOnce(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+
+		deadLetters = NewMemStoreDeadLetterStore(pkgtest.Instrumentation, store, DeadLetterConfiguration{})
+
+		id    = pkgid.NewID()
+		value []byte
+	)
+
+	store.EXPECT().
+		SetCollectionItemWithTTL(gomock.Any(), deadLettersCollection, id.String(), gomock.Any(), defaultDeadLetterRetention).
+		DoAndReturn(func(_ context.Context, _ string, _ string, v []byte, _ time.Duration) error {
+			value = v
+			return nil
+		})
+	require.NoError(t, deadLetters.Add(context.Background(), DeadLetter{ID: id, Event: &rteventspb.Event{}}))
+
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), deadLettersCollection, id.String()).
+		DoAndReturn(func(context.Context, string, string) ([]byte, error) {
+			return value, nil
+		}).
+		Times(2)
+
+	// the first caller removes it, the concurrent one loses the swap
+	gomock.InOrder(
+		store.EXPECT().
+			CompareAndSwapCollectionItem(gomock.Any(), deadLettersCollection, id.String(), gomock.Any(), nil, time.Duration(0)).
+			Return(true, nil),
+		store.EXPECT().
+			CompareAndSwapCollectionItem(gomock.Any(), deadLettersCollection, id.String(), gomock.Any(), nil, time.Duration(0)).
+			Return(false, nil),
+	)
+
+	require.NoError(t, deadLetters.Remove(context.Background(), id))
+	require.ErrorIs(t, deadLetters.Remove(context.Background(), id), ErrDeadLetterNotFound)
+
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), deadLettersCollection, id.String()).
+		Return(nil, pkgmemstore.ErrNotFound)
+
+	_, err := deadLetters.Get(context.Background(), id)
+	require.ErrorIs(t, err, ErrDeadLetterNotFound)
+}
+
+func requireDeadLetter(t *testing.T, expected, actual DeadLetter) {
+	t.Helper()
+
+	require.Equal(t, expected.ID, actual.ID)
+	require.Equal(t, expected.RecipientID, actual.RecipientID)
+	require.True(t, proto.Equal(expected.Event, actual.Event))
+	require.Equal(t, expected.Reason, actual.Reason)
+	require.Equal(t, expected.Attempts, actual.Attempts)
+	require.True(t, expected.FailedAt.Equal(actual.FailedAt))
+}
@@ -2,48 +2,580 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	multierr "github.com/hashicorp/go-multierror"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
 	esclient "github.com/faustuzas/occa/src/pkg/eventserver/client"
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/eventserver/pending"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
 	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
 	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 )
 
+//go:generate sh -c "mockgen -package=services -destination=event_relay_mock.go . RealTimeEventRelay"
+
 type RealTimeEventRelay interface {
+	// Forward sends the event to the recipient. Direct messages are archived first and
+	// get the ID of the archived message set. The event gets the next sequence number of
+	// the recipient before it is sent to any of its servers.
 	Forward(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error
+
+	// ForwardEphemeral delivers the event only if the recipient is reachable right now. The event
+	// is neither retried, archived, stored for offline recipients nor dead-lettered.
+	ForwardEphemeral(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error
+
+	// ForwardMany sends the event to every recipient, sequenced for each of them. Recipients connected
+	// to the same server are sent to in a single call, the rest are delivered one by one as by Forward.
+	ForwardMany(ctx context.Context, recipientIDs []pkgid.ID, event *rteventspb.Event) error
+
+	// Redrive attempts to deliver the dead letter again. It is removed once delivered or
+	// stored for later delivery, otherwise it is kept with the new failure reason.
+	Redrive(ctx context.Context, deadLetterID pkgid.ID) error
+}
+
+// errStaleEpoch is returned when the connection record was left by a previous process with the same server ID.
+var errStaleEpoch = errors.New("connection belongs to a previous epoch of the server")
+
+const (
+	defaultRelayMaxAttempts = 4
+	defaultRelayMinBackoff  = 20 * time.Millisecond
+	defaultRelayMaxBackoff  = 500 * time.Millisecond
+	defaultRelayDeadline    = 3 * time.Second
+)
+
+// RelayConfiguration controls retries of deliveries to recipients which have moved to
+// another server since their servers were resolved.
+type RelayConfiguration struct {
+	// MaxAttempts is how many times the servers of the recipient are resolved and the delivery attempted.
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	MinBackoff time.Duration `yaml:"minBackoff"`
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+
+	// Deadline bounds the whole delivery, including all the retries.
+	Deadline time.Duration `yaml:"deadline"`
 }
 
+func (c RelayConfiguration) withDefaults() RelayConfiguration {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultRelayMaxAttempts
+	}
+
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultRelayMinBackoff
+	}
+
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = max(defaultRelayMaxBackoff, c.MinBackoff)
+	}
+
+	if c.Deadline <= 0 {
+		c.Deadline = defaultRelayDeadline
+	}
+
+	return c
+}
+
+type deliveryOutcome int
+
+const (
+	outcomeDelivered deliveryOutcome = iota
+	// outcomeOffline means the recipient is not connected anywhere, so the event waits for it
+	outcomeOffline
+	// outcomeMoved means the recipient was not found on the resolved servers, so it is worth resolving again
+	outcomeMoved
+	outcomeFailed
+)
+
 type realTimeEventRelay struct {
 	i pkginstrument.Instrumentation
 
 	serverResolver rtconn.ServerResolver
+	serverInfo     membership.ServerInfoResolver
 	esPool         esclient.Pool
+	archiver       archiverclient.Client
+	pendingEvents  pending.Queue
+	replayLog      esreplay.Log
+	deadLetters    DeadLetterStore
+	config         RelayConfiguration
+
+	retries      prometheus.Counter
+	attempts     prometheus.Histogram
+	deadLettered prometheus.Counter
+
+	now func() time.Time
 }
 
-func NewRealTimeEventRelay(i pkginstrument.Instrumentation, serverResolver rtconn.ServerResolver, esPool esclient.Pool) RealTimeEventRelay {
-	return &realTimeEventRelay{
+func NewRealTimeEventRelay(
+	i pkginstrument.Instrumentation,
+	serverResolver rtconn.ServerResolver,
+	serverInfo membership.ServerInfoResolver,
+	esPool esclient.Pool,
+	archiver archiverclient.Client,
+	pendingEvents pending.Queue,
+	replayLog esreplay.Log,
+	deadLetters DeadLetterStore,
+	config RelayConfiguration,
+) (RealTimeEventRelay, error) {
+	config = config.withDefaults()
+
+	r := &realTimeEventRelay{
 		i:              i,
 		serverResolver: serverResolver,
+		serverInfo:     serverInfo,
 		esPool:         esPool,
+		archiver:       archiver,
+		pendingEvents:  pendingEvents,
+		replayLog:      replayLog,
+		deadLetters:    deadLetters,
+		config:         config,
+
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "event_relay_retries_total",
+			Help: "Number of times delivery was retried because the recipient had moved to another server.",
+		}),
+		attempts: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "event_relay_delivery_attempts",
+			Help:    "Number of attempts it took to deliver or store an event.",
+			Buckets: prometheus.LinearBuckets(1, 1, config.MaxAttempts),
+		}),
+		deadLettered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "event_relay_dead_letters_total",
+			Help: "Number of events which could not be delivered nor stored for later delivery.",
+		}),
+
+		now: time.Now,
+	}
+
+	if err := i.Registerer.Register(r.retries); err != nil {
+		return nil, fmt.Errorf("registering retries metric: %w", err)
+	}
+	if err := i.Registerer.Register(r.attempts); err != nil {
+		return nil, fmt.Errorf("registering attempts metric: %w", err)
 	}
+	if err := i.Registerer.Register(r.deadLettered); err != nil {
+		return nil, fmt.Errorf("registering dead letters metric: %w", err)
+	}
+
+	return r, nil
 }
 
 func (r *realTimeEventRelay) Forward(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
-	serverInfo, err := r.serverResolver.Resolve(ctx, recipientID)
+	// Messages are archived before the delivery is attempted, so they are not lost even if
+	// the recipient cannot be reached at the moment.
+	if err := r.archive(ctx, recipientID, event); err != nil {
+		return fmt.Errorf("archiving event: %w", err)
+	}
+
+	// the sequence is assigned once, so every server of the recipient delivers the event under the same number
+	event, err := r.replayLog.Record(ctx, recipientID, event)
+	if err != nil {
+		return fmt.Errorf("sequencing event: %w", err)
+	}
+
+	attempts, err := r.deliverWithRetries(ctx, recipientID, event)
+	if err == nil {
+		return nil
+	}
+
+	return r.deadLetter(ctx, DeadLetter{
+		ID:          pkgid.NewID(),
+		RecipientID: recipientID,
+		Event:       event,
+		Reason:      err.Error(),
+		Attempts:    attempts,
+	}, err)
+}
+
+func (r *realTimeEventRelay) ForwardEphemeral(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+	outcome, err := r.deliver(ctx, recipientID, event)
+	if outcome == outcomeFailed {
+		return fmt.Errorf("sending event: %w", err)
+	}
+	return nil
+}
+
+func (r *realTimeEventRelay) ForwardMany(ctx context.Context, recipientIDs []pkgid.ID, event *rteventspb.Event) error {
+	var (
+		batches = map[string][]batchedRecipient{}
+		offline []pkgid.ID
+
+		// epochs of the servers are checked once per fan-out
+		epochErrs = map[rtconn.ServerInformation]error{}
+
+		// delivered are recipients which have received the event on at least one device, failed
+		// those which have not been batched or whose batched delivery has failed on some server
+		delivered = map[pkgid.ID]bool{}
+		failed    = map[pkgid.ID]bool{}
+		batched   []pkgid.ID
+
+		// every recipient gets the event under its own sequence number
+		events = map[pkgid.ID]*rteventspb.Event{}
+		errs   error
+	)
+	for _, recipientID := range recipientIDs {
+		sequenced, err := r.replayLog.Record(ctx, recipientID, event)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("sequencing event for %s: %w", recipientID, err))
+			continue
+		}
+		events[recipientID] = sequenced
+
+		servers, err := r.serverResolver.Resolve(ctx, recipientID)
+		if errors.Is(err, rtconn.ErrUserNotConnected) {
+			offline = append(offline, recipientID)
+			continue
+		}
+		if err != nil {
+			failed[recipientID] = true
+			batched = append(batched, recipientID)
+			continue
+		}
+
+		// the recipient goes to the batch of every server its devices are connected to
+		for _, server := range servers {
+			epochErr, checked := epochErrs[server]
+			if !checked {
+				epochErr = r.checkEpoch(ctx, server)
+				epochErrs[server] = epochErr
+			}
+			if epochErr != nil {
+				failed[recipientID] = true
+				continue
+			}
+			batches[server.ServerID] = append(batches[server.ServerID], batchedRecipient{id: recipientID, event: sequenced, affine: server.Affine})
+		}
+		batched = append(batched, recipientID)
+	}
+
+	for serverID, recipients := range batches {
+		for idx, sendErr := range r.sendBatch(ctx, serverID, recipients) {
+			recipient := recipients[idx]
+			switch {
+			case sendErr == nil:
+				delivered[recipient.id] = true
+			case recipient.affine && errors.Is(sendErr, esclient.ErrRecipientNotConnected):
+				// affine servers are only a guess, the recipient not being there is not a failure
+			default:
+				failed[recipient.id] = true
+			}
+		}
+	}
+
+	// recipients which have not received the event anywhere are delivered one by one, unless
+	// the only thing known is that they are not on their affine servers, then they are offline
+	var individually []pkgid.ID
+	for _, recipientID := range batched {
+		switch {
+		case delivered[recipientID]:
+		case failed[recipientID]:
+			individually = append(individually, recipientID)
+		default:
+			offline = append(offline, recipientID)
+		}
+	}
+
+	for _, recipientID := range offline {
+		if pendingErr := r.storePending(ctx, recipientID, events[recipientID]); pendingErr != nil {
+			errs = r.appendDeadLetter(ctx, errs, recipientID, events[recipientID], 1, pendingErr)
+		}
+	}
+
+	for _, recipientID := range individually {
+		if attempts, deliveryErr := r.deliverWithRetries(ctx, recipientID, events[recipientID]); deliveryErr != nil {
+			errs = r.appendDeadLetter(ctx, errs, recipientID, events[recipientID], attempts, deliveryErr)
+		}
+	}
+
+	return errs
+}
+
+type batchedRecipient struct {
+	id    pkgid.ID
+	event *rteventspb.Event
+	// affine tells the server is only a guess of where the recipient is connected
+	affine bool
+}
+
+// appendDeadLetter dead-letters the event of a single recipient of the fan-out and appends
+// the error if that fails too.
+func (r *realTimeEventRelay) appendDeadLetter(
+	ctx context.Context, errs error, recipientID pkgid.ID, event *rteventspb.Event, attempts int, deliveryErr error,
+) error {
+	err := r.deadLetter(ctx, DeadLetter{
+		ID:          pkgid.NewID(),
+		RecipientID: recipientID,
+		Event:       event,
+		Reason:      deliveryErr.Error(),
+		Attempts:    attempts,
+	}, deliveryErr)
+	if err != nil {
+		return multierr.Append(errs, err)
+	}
+	return errs
+}
+
+// sendBatch sends the events to the recipients connected to the server in a single call. Returns
+// an error per recipient, in the order of the recipients.
+func (r *realTimeEventRelay) sendBatch(ctx context.Context, serverID string, recipients []batchedRecipient) []error {
+	failAll := func(err error) []error {
+		errs := make([]error, len(recipients))
+		for idx := range errs {
+			errs[idx] = err
+		}
+		return errs
+	}
+
+	client, err := r.esPool.ClientForServer(ctx, serverID)
+	if err != nil {
+		r.i.Logger.Warn("failed to resolve client for server", zap.String("serverId", serverID), zap.Error(err))
+		return failAll(err)
+	}
+
+	deliveries := make([]esclient.Delivery, 0, len(recipients))
+	for _, recipient := range recipients {
+		deliveries = append(deliveries, esclient.Delivery{RecipientID: recipient.id, Event: recipient.event})
+	}
+
+	errs, err := client.SendMany(ctx, deliveries)
+	if err != nil {
+		r.i.Logger.Warn("failed to relay events to server",
+			zap.String("serverId", serverID), zap.Int("recipients", len(recipients)), zap.Error(err))
+		return failAll(err)
+	}
+
+	for idx, deliveryErr := range errs {
+		if deliveryErr != nil {
+			r.i.Logger.Debug("batched delivery failed",
+				zap.Stringer("recipientId", recipients[idx].id), zap.String("serverId", serverID), zap.Error(deliveryErr))
+		}
+	}
+	return errs
+}
+
+func (r *realTimeEventRelay) Redrive(ctx context.Context, deadLetterID pkgid.ID) error {
+	letter, err := r.deadLetters.Get(ctx, deadLetterID)
+	if err != nil {
+		return err
+	}
+
+	// the event has been archived when it was forwarded the first time
+	attempts, deliveryErr := r.deliverWithRetries(ctx, letter.RecipientID, letter.Event)
+	if deliveryErr != nil {
+		letter.Reason = deliveryErr.Error()
+		letter.Attempts += attempts
+		if err = r.deadLetter(ctx, letter, deliveryErr); err != nil {
+			return err
+		}
+		return fmt.Errorf("redriving dead letter: %w", deliveryErr)
+	}
+
+	if err = r.deadLetters.Remove(ctx, deadLetterID); err != nil {
+		return fmt.Errorf("removing redriven dead letter: %w", err)
+	}
+	return nil
+}
+
+// deadLetter keeps the event which could not be delivered, so it is not lost. The error is
+// returned only if the event could not be kept either.
+func (r *realTimeEventRelay) deadLetter(ctx context.Context, letter DeadLetter, deliveryErr error) error {
+	letter.FailedAt = r.now()
+
+	// the event has to be kept even if the caller has gone away meanwhile
+	if err := r.deadLetters.Add(context.WithoutCancel(ctx), letter); err != nil {
+		return fmt.Errorf("sending event: %w, dead-lettering failed: %v", deliveryErr, err)
+	}
+	r.deadLettered.Inc()
+
+	r.i.Logger.Warn("event could not be delivered, stored as dead letter",
+		zap.Stringer("recipientId", letter.RecipientID),
+		zap.Stringer("deadLetterId", letter.ID),
+		zap.Int("attempts", letter.Attempts),
+		zap.Error(deliveryErr))
+	return nil
+}
+
+// deliverWithRetries delivers the event or stores it for later delivery, retrying while the recipient
+// keeps moving between servers. Returns the number of attempts made.
+func (r *realTimeEventRelay) deliverWithRetries(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) (int, error) {
+	deliverCtx, cancel := context.WithTimeout(ctx, r.config.Deadline)
+	defer cancel()
+
+	var (
+		backoff = r.config.MinBackoff
+		attempt = 1
+	)
+	for ; ; attempt++ {
+		outcome, err := r.deliver(deliverCtx, recipientID, event)
+		if outcome != outcomeMoved {
+			r.attempts.Observe(float64(attempt))
+
+			switch outcome {
+			case outcomeDelivered:
+				return attempt, nil
+			case outcomeOffline:
+				return attempt, r.storePending(ctx, recipientID, event)
+			default:
+				return attempt, fmt.Errorf("sending event: %w", err)
+			}
+		}
+
+		if attempt >= r.config.MaxAttempts {
+			break
+		}
+
+		r.retries.Inc()
+		r.i.Logger.Debug("recipient has moved, retrying delivery",
+			zap.Stringer("recipientId", recipientID), zap.Int("attempt", attempt), zap.Duration("backoff", backoff))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-deliverCtx.Done():
+			timer.Stop()
+		}
+		if deliverCtx.Err() != nil {
+			break
+		}
+
+		backoff = min(backoff*2, r.config.MaxBackoff)
+	}
+	r.attempts.Observe(float64(attempt))
+
+	// the recipient keeps moving or its connection records are stale, it gets the event once connected
+	r.i.Logger.Warn("recipient could not be found on its servers, storing event for later delivery",
+		zap.Stringer("recipientId", recipientID), zap.Int("attempts", attempt))
+	return attempt, r.storePending(ctx, recipientID, event)
+}
+
+// deliver resolves the servers of the recipient and sends the event to them.
+func (r *realTimeEventRelay) deliver(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) (deliveryOutcome, error) {
+	servers, err := r.serverResolver.Resolve(ctx, recipientID)
+	if errors.Is(err, rtconn.ErrUserNotConnected) {
+		r.i.Logger.Debug("recipient is offline", zap.Stringer("recipientId", recipientID))
+		return outcomeOffline, nil
+	}
 	if err != nil {
-		return fmt.Errorf("resolving user server: %w", err)
+		return outcomeFailed, fmt.Errorf("resolving user servers: %w", err)
+	}
+
+	// user's devices might be connected to different servers, so the event is sent to all of
+	// them and considered delivered if at least one has received it
+	var (
+		sendErr   error
+		delivered = false
+		// unreachable counts servers the user turned out not to be connected to
+		unreachable = 0
+		// moved counts servers the user has left since it was resolved
+		moved = 0
+	)
+	for _, serverInfo := range servers {
+		err = r.sendToServer(ctx, recipientID, serverInfo, event)
+		switch {
+		case err == nil:
+			delivered = true
+			continue
+		case serverInfo.Affine && errors.Is(err, esclient.ErrRecipientNotConnected):
+			// affine servers are only a guess, the user not being there means it is not connected at all
+			unreachable++
+			r.i.Logger.Debug("recipient is not connected to its affine server",
+				zap.Stringer("recipientId", recipientID), zap.String("serverId", serverInfo.ServerID))
+			continue
+		case errors.Is(err, esclient.ErrRecipientNotConnected):
+			moved++
+			r.i.Logger.Debug("recipient is not connected to the resolved server",
+				zap.Stringer("recipientId", recipientID), zap.String("serverId", serverInfo.ServerID))
+			continue
+		case errors.Is(err, errStaleEpoch) || serverInfo.Affine:
+			unreachable++
+		}
+
+		r.i.Logger.Warn("failed to relay event to server",
+			zap.Stringer("recipientId", recipientID), zap.String("serverId", serverInfo.ServerID), zap.Error(err))
+		sendErr = multierr.Append(sendErr, err)
+	}
+
+	switch {
+	case delivered:
+		return outcomeDelivered, nil
+	case moved > 0 && moved+unreachable == len(servers):
+		return outcomeMoved, nil
+	case unreachable == len(servers):
+		return outcomeOffline, nil
+	default:
+		return outcomeFailed, sendErr
+	}
+}
+
+func (r *realTimeEventRelay) storePending(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+	if err := r.pendingEvents.Enqueue(ctx, recipientID, event); err != nil {
+		return fmt.Errorf("storing event for offline user: %w", err)
+	}
+	return nil
+}
+
+func (r *realTimeEventRelay) sendToServer(ctx context.Context, recipientID pkgid.ID, server rtconn.ServerInformation, event *rteventspb.Event) error {
+	if err := r.checkEpoch(ctx, server); err != nil {
+		return err
 	}
 
-	client, err := r.esPool.ClientForServer(ctx, serverInfo.ServerID)
+	client, err := r.esPool.ClientForServer(ctx, server.ServerID)
 	if err != nil {
 		return fmt.Errorf("resolving client for server: %w", err)
 	}
 
-	if err = client.Send(ctx, event); err != nil {
-		return fmt.Errorf("sending event: %w", err)
+	return client.Send(ctx, recipientID, event)
+}
+
+// checkEpoch verifies the connection record belongs to the current process of the server.
+func (r *realTimeEventRelay) checkEpoch(ctx context.Context, server rtconn.ServerInformation) error {
+	// records without an epoch were written before the server joined the cluster
+	if server.Epoch == 0 {
+		return nil
+	}
+
+	info, err := r.serverInfo.Resolve(ctx, server.ServerID)
+	if err != nil {
+		return fmt.Errorf("resolving server info: %w", err)
+	}
+	if info.Epoch != server.Epoch {
+		return fmt.Errorf("%w: connected at %d, current %d", errStaleEpoch, server.Epoch, info.Epoch)
+	}
+	return nil
+}
+
+func (r *realTimeEventRelay) archive(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+	dm := event.GetDirectMessage()
+	if dm == nil {
+		return nil
+	}
+
+	senderID, err := pkgid.Parse(dm.SenderId)
+	if err != nil {
+		return fmt.Errorf("parsing sender id: %w", err)
+	}
+
+	archived, err := r.archiver.Archive(ctx, pkgarchive.Message{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Content:     dm.Message,
+		SentAt:      time.Now(),
+	})
+	if err != nil {
+		return err
 	}
 
+	// the recipient refers to the message by its archive ID when sending receipts back
+	dm.MessageId = archived.ID.String()
 	return nil
 }
@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/faustuzas/occa/src/gateway/services (interfaces: RealTimeEventRelay)
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	context "context"
+	reflect "reflect"
+
+	rteventspb "github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	id "github.com/faustuzas/occa/src/pkg/id"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRealTimeEventRelay is a mock of RealTimeEventRelay interface.
+type MockRealTimeEventRelay struct {
+	ctrl     *gomock.Controller
+	recorder *MockRealTimeEventRelayMockRecorder
+}
+
+// MockRealTimeEventRelayMockRecorder is the mock recorder for MockRealTimeEventRelay.
+type MockRealTimeEventRelayMockRecorder struct {
+	mock *MockRealTimeEventRelay
+}
+
+// NewMockRealTimeEventRelay creates a new mock instance.
+func NewMockRealTimeEventRelay(ctrl *gomock.Controller) *MockRealTimeEventRelay {
+	mock := &MockRealTimeEventRelay{ctrl: ctrl}
+	mock.recorder = &MockRealTimeEventRelayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRealTimeEventRelay) EXPECT() *MockRealTimeEventRelayMockRecorder {
+	return m.recorder
+}
+
+// Forward mocks base method.
+func (m *MockRealTimeEventRelay) Forward(arg0 context.Context, arg1 id.ID, arg2 *rteventspb.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Forward", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Forward indicates an expected call of Forward.
+func (mr *MockRealTimeEventRelayMockRecorder) Forward(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Forward", reflect.TypeOf((*MockRealTimeEventRelay)(nil).Forward), arg0, arg1, arg2)
+}
+
+// ForwardEphemeral mocks base method.
+func (m *MockRealTimeEventRelay) ForwardEphemeral(arg0 context.Context, arg1 id.ID, arg2 *rteventspb.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForwardEphemeral", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForwardEphemeral indicates an expected call of ForwardEphemeral.
+func (mr *MockRealTimeEventRelayMockRecorder) ForwardEphemeral(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForwardEphemeral", reflect.TypeOf((*MockRealTimeEventRelay)(nil).ForwardEphemeral), arg0, arg1, arg2)
+}
+
+// ForwardMany mocks base method.
+func (m *MockRealTimeEventRelay) ForwardMany(arg0 context.Context, arg1 []id.ID, arg2 *rteventspb.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForwardMany", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForwardMany indicates an expected call of ForwardMany.
+func (mr *MockRealTimeEventRelayMockRecorder) ForwardMany(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForwardMany", reflect.TypeOf((*MockRealTimeEventRelay)(nil).ForwardMany), arg0, arg1, arg2)
+}
+
+// Redrive mocks base method.
+func (m *MockRealTimeEventRelay) Redrive(arg0 context.Context, arg1 id.ID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redrive", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Redrive indicates an expected call of Redrive.
+func (mr *MockRealTimeEventRelayMockRecorder) Redrive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redrive", reflect.TypeOf((*MockRealTimeEventRelay)(nil).Redrive), arg0, arg1)
+}
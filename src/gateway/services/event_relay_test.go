@@ -0,0 +1,513 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	esclient "github.com/faustuzas/occa/src/pkg/eventserver/client"
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/eventserver/pending"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
+	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestRelay_StaleEpochIsStoredAsPending(t *testing.T) {
+	var (
+		pool    = &stubPool{}
+		pending = &collectingQueue{}
+		relay   = newTestRelay(t,
+			stubResolver{servers: []rtconn.ServerInformation{{ServerID: "es-1", Epoch: 1}}},
+			stubServerInfo{"es-1": {ID: "es-1", Epoch: 2}},
+			pool, pending, &memoryDeadLetters{})
+		recipientID = pkgid.NewID()
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), recipientID, &rteventspb.Event{}))
+	require.Len(t, pending.events[recipientID], 1)
+	require.Zero(t, pool.sent)
+}
+
+func TestRelay_DeliversToCurrentEpoch(t *testing.T) {
+	var (
+		pool    = &stubPool{}
+		pending = &collectingQueue{}
+		relay   = newTestRelay(t,
+			stubResolver{servers: []rtconn.ServerInformation{{ServerID: "es-1", Epoch: 2}}},
+			stubServerInfo{"es-1": {ID: "es-1", Epoch: 2}},
+			pool, pending, &memoryDeadLetters{})
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+	require.Empty(t, pending.events)
+	require.Equal(t, 1, pool.sent)
+}
+
+func TestRelay_DirectMessagesGetArchivedID(t *testing.T) {
+	var (
+		pending = &collectingQueue{}
+		relay   = newTestRelay(t, usersResolver{}, stubServerInfo{}, &stubPool{}, pending, &memoryDeadLetters{})
+		event   = rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hello")
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), pkgid.NewID(), event))
+
+	_, err := pkgid.Parse(event.GetDirectMessage().GetMessageId())
+	require.NoError(t, err)
+}
+
+func TestRelay_EphemeralEventsAreNotStored(t *testing.T) {
+	var (
+		pending     = &collectingQueue{}
+		deadLetters = &memoryDeadLetters{}
+		pool        = &stubPool{notConnected: map[string]bool{"es-1": true}}
+		resolver    = &movingResolver{resolutions: [][]rtconn.ServerInformation{{{ServerID: "es-1"}}}}
+		relay       = newTestRelay(t, resolver, stubServerInfo{}, pool, pending, deadLetters)
+	)
+
+	// offline recipient
+	require.NoError(t, newTestRelay(t, usersResolver{}, stubServerInfo{}, pool, pending, deadLetters).
+		ForwardEphemeral(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+
+	// moved recipient is not retried
+	require.NoError(t, relay.ForwardEphemeral(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+	require.Equal(t, 1, resolver.calls)
+
+	// failed delivery is reported, but not dead-lettered
+	pool.err = errors.New("connection refused")
+	require.Error(t, relay.ForwardEphemeral(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+
+	require.Empty(t, pending.events)
+	require.Empty(t, deadLetters.letters)
+}
+
+func TestRelay_DeliversToEveryDevice(t *testing.T) {
+	var (
+		pool    = &stubPool{notConnected: map[string]bool{"es-3": true}}
+		pending = &collectingQueue{}
+		relay   = newTestRelay(t,
+			stubResolver{servers: []rtconn.ServerInformation{{ServerID: "es-1"}, {ServerID: "es-2"}, {ServerID: "es-3"}}},
+			stubServerInfo{}, pool, pending, &memoryDeadLetters{})
+	)
+
+	recipientID := pkgid.NewID()
+	require.NoError(t, relay.Forward(context.Background(), recipientID, &rteventspb.Event{}))
+	require.Equal(t, 2, pool.sent)
+	require.Empty(t, pending.events)
+
+	// the event is sequenced once, so every device can resume from it
+	require.Equal(t, []uint64{1, 1}, pool.sequences[recipientID])
+}
+
+func TestRelay_RecipientMissingOnAffineServerIsOffline(t *testing.T) {
+	var (
+		pool        = &stubPool{notConnected: map[string]bool{"es-1": true}}
+		pending     = &collectingQueue{}
+		resolver    = &movingResolver{resolutions: [][]rtconn.ServerInformation{{{ServerID: "es-1", Affine: true}}}}
+		relay       = newTestRelay(t, resolver, stubServerInfo{}, pool, pending, &memoryDeadLetters{})
+		recipientID = pkgid.NewID()
+	)
+
+	// stored right away instead of being retried as if the recipient had moved
+	require.NoError(t, relay.Forward(context.Background(), recipientID, &rteventspb.Event{}))
+	require.Equal(t, 1, resolver.calls)
+	require.Len(t, pending.events[recipientID], 1)
+}
+
+func TestRelay_RetriesWhenRecipientMoves(t *testing.T) {
+	var (
+		pool    = &stubPool{notConnected: map[string]bool{"es-1": true}}
+		pending = &collectingQueue{}
+		// the first resolution is stale, the user has moved to es-2 meanwhile
+		resolver = &movingResolver{resolutions: [][]rtconn.ServerInformation{
+			{{ServerID: "es-1"}},
+			{{ServerID: "es-2"}},
+		}}
+		relay = newTestRelay(t, resolver, stubServerInfo{}, pool, pending, &memoryDeadLetters{})
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+	require.Equal(t, 2, resolver.calls)
+	require.Equal(t, 1, pool.sent)
+	require.Empty(t, pending.events)
+}
+
+func TestRelay_StoresPendingWhenRetriesRunOut(t *testing.T) {
+	var (
+		pool        = &stubPool{notConnected: map[string]bool{"es-1": true}}
+		pending     = &collectingQueue{}
+		resolver    = &movingResolver{resolutions: [][]rtconn.ServerInformation{{{ServerID: "es-1"}}}}
+		relay       = newTestRelay(t, resolver, stubServerInfo{}, pool, pending, &memoryDeadLetters{})
+		recipientID = pkgid.NewID()
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), recipientID, &rteventspb.Event{}))
+	require.Equal(t, 3, resolver.calls)
+	require.Len(t, pending.events[recipientID], 1)
+}
+
+func TestRelay_DeadLettersServerFailures(t *testing.T) {
+	var (
+		pool        = &stubPool{err: errors.New("connection refused")}
+		pending     = &collectingQueue{}
+		deadLetters = &memoryDeadLetters{}
+		resolver    = &movingResolver{resolutions: [][]rtconn.ServerInformation{{{ServerID: "es-1"}}}}
+		relay       = newTestRelay(t, resolver, stubServerInfo{}, pool, pending, deadLetters)
+		recipientID = pkgid.NewID()
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), recipientID, &rteventspb.Event{}))
+	// server failures are not retried
+	require.Equal(t, 1, resolver.calls)
+	require.Empty(t, pending.events)
+
+	letters, err := deadLetters.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, recipientID, letters[0].RecipientID)
+	require.Equal(t, 1, letters[0].Attempts)
+	require.Contains(t, letters[0].Reason, "connection refused")
+}
+
+func TestRelay_FailsWhenEventCannotBeDeadLettered(t *testing.T) {
+	var (
+		pool     = &stubPool{err: errors.New("connection refused")}
+		resolver = &movingResolver{resolutions: [][]rtconn.ServerInformation{{{ServerID: "es-1"}}}}
+		relay    = newTestRelay(t, resolver, stubServerInfo{}, pool, &collectingQueue{},
+			&memoryDeadLetters{err: errors.New("store is down")})
+	)
+
+	require.Error(t, relay.Forward(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+}
+
+func TestRelay_RedriveRemovesDeliveredDeadLetter(t *testing.T) {
+	var (
+		pool        = &stubPool{err: errors.New("connection refused")}
+		deadLetters = &memoryDeadLetters{}
+		resolver    = &movingResolver{resolutions: [][]rtconn.ServerInformation{{{ServerID: "es-1"}}}}
+		relay       = newTestRelay(t, resolver, stubServerInfo{}, pool, &collectingQueue{}, deadLetters)
+	)
+
+	require.NoError(t, relay.Forward(context.Background(), pkgid.NewID(), &rteventspb.Event{}))
+	letters, err := deadLetters.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+
+	// the server is still down, the dead letter is kept with the new attempts
+	require.Error(t, relay.Redrive(context.Background(), letters[0].ID))
+	letter, err := deadLetters.Get(context.Background(), letters[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, letter.Attempts)
+
+	pool.mu.Lock()
+	pool.err = nil
+	pool.mu.Unlock()
+
+	require.NoError(t, relay.Redrive(context.Background(), letters[0].ID))
+	require.Equal(t, 1, pool.sent)
+
+	_, err = deadLetters.Get(context.Background(), letters[0].ID)
+	require.ErrorIs(t, err, ErrDeadLetterNotFound)
+	require.ErrorIs(t, relay.Redrive(context.Background(), letters[0].ID), ErrDeadLetterNotFound)
+}
+
+func TestRelay_ForwardManyBatchesByServer(t *testing.T) {
+	var (
+		pool        = &stubPool{notConnected: map[string]bool{"es-3": true}}
+		pending     = &collectingQueue{}
+		deadLetters = &memoryDeadLetters{}
+
+		first, second, third = pkgid.NewID(), pkgid.NewID(), pkgid.NewID()
+		moved, offline       = pkgid.NewID(), pkgid.NewID()
+		multiDevice, affine  = pkgid.NewID(), pkgid.NewID()
+
+		resolver = usersResolver{
+			first:  {{ServerID: "es-1"}},
+			second: {{ServerID: "es-1"}},
+			third:  {{ServerID: "es-2"}},
+			// the recipient has left es-3, it keeps being resolved there
+			moved: {{ServerID: "es-3"}},
+			// devices on several servers get the event on every one of them
+			multiDevice: {{ServerID: "es-1"}, {ServerID: "es-2"}},
+			// the recipient is not on its affine server, so it is offline rather than moved
+			affine: {{ServerID: "es-3", Affine: true}},
+		}
+		relay = newTestRelay(t, resolver, stubServerInfo{}, pool, pending, deadLetters)
+	)
+
+	recipients := []pkgid.ID{first, second, third, moved, offline, multiDevice, affine}
+	require.NoError(t, relay.ForwardMany(context.Background(), recipients, &rteventspb.Event{}))
+
+	// a call per server, retries of the moved recipient are sent one by one
+	require.Equal(t, 3, pool.batches)
+	require.Equal(t, 5, pool.sent)
+	require.Len(t, pending.events[offline], 1)
+	require.Len(t, pending.events[moved], 1)
+	require.Len(t, pending.events[affine], 1)
+	require.Len(t, pending.events, 3)
+	require.Empty(t, deadLetters.letters)
+
+	// every recipient gets its own sequence, shared by all of its servers
+	require.Equal(t, []uint64{1}, pool.sequences[first])
+	require.Equal(t, []uint64{1, 1}, pool.sequences[multiDevice])
+	require.Equal(t, uint64(1), pending.events[offline][0].Sequence)
+}
+
+func newTestRelay(
+	t *testing.T,
+	resolver rtconn.ServerResolver,
+	serverInfo membership.ServerInfoResolver,
+	pool esclient.Pool,
+	queue pending.Queue,
+	deadLetters DeadLetterStore,
+) RealTimeEventRelay {
+	i := pkginstrument.Instrumentation{
+		Logger:     pkgtest.Instrumentation.Logger,
+		Registerer: prometheus.NewRegistry(),
+	}
+
+	relay, err := NewRealTimeEventRelay(i, resolver, serverInfo, pool, stubArchiver{}, queue, &sequencingLog{}, deadLetters, RelayConfiguration{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	})
+	require.NoError(t, err)
+	return relay
+}
+
+type movingResolver struct {
+	mu          sync.Mutex
+	calls       int
+	resolutions [][]rtconn.ServerInformation
+}
+
+func (r *movingResolver) Resolve(context.Context, pkgid.ID) ([]rtconn.ServerInformation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resolution := r.resolutions[min(r.calls, len(r.resolutions)-1)]
+	r.calls++
+	return resolution, nil
+}
+
+type stubResolver struct {
+	servers []rtconn.ServerInformation
+}
+
+func (r stubResolver) Resolve(context.Context, pkgid.ID) ([]rtconn.ServerInformation, error) {
+	if len(r.servers) == 0 {
+		return nil, rtconn.ErrUserNotConnected
+	}
+	return r.servers, nil
+}
+
+type usersResolver map[pkgid.ID][]rtconn.ServerInformation
+
+func (r usersResolver) Resolve(_ context.Context, userID pkgid.ID) ([]rtconn.ServerInformation, error) {
+	servers, ok := r[userID]
+	if !ok {
+		return nil, rtconn.ErrUserNotConnected
+	}
+	return servers, nil
+}
+
+type stubServerInfo map[string]membership.ServerInfo
+
+func (s stubServerInfo) Resolve(_ context.Context, serverID string) (membership.ServerInfo, error) {
+	info, ok := s[serverID]
+	if !ok {
+		return membership.ServerInfo{}, errors.New("server not found")
+	}
+	return info, nil
+}
+
+type stubPool struct {
+	mu      sync.Mutex
+	sent    int
+	batches int
+	err     error
+	// notConnected are servers the recipients are not connected to
+	notConnected map[string]bool
+	// sequences are the sequence numbers of the sent events by their recipients
+	sequences map[pkgid.ID][]uint64
+}
+
+func (p *stubPool) ClientForServer(_ context.Context, serverID string) (esclient.Client, error) {
+	return stubClient{pool: p, serverID: serverID}, nil
+}
+
+type stubClient struct {
+	pool     *stubPool
+	serverID string
+}
+
+func (c stubClient) Send(_ context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+
+	if c.pool.err != nil {
+		return c.pool.err
+	}
+	if c.pool.notConnected[c.serverID] {
+		return esclient.ErrRecipientNotConnected
+	}
+	c.pool.sent++
+
+	if c.pool.sequences == nil {
+		c.pool.sequences = map[pkgid.ID][]uint64{}
+	}
+	c.pool.sequences[recipientID] = append(c.pool.sequences[recipientID], event.Sequence)
+	return nil
+}
+
+func (c stubClient) SendMany(ctx context.Context, deliveries []esclient.Delivery) ([]error, error) {
+	c.pool.mu.Lock()
+	c.pool.batches++
+	c.pool.mu.Unlock()
+
+	errs := make([]error, len(deliveries))
+	for idx, d := range deliveries {
+		errs[idx] = c.Send(ctx, d.RecipientID, d.Event)
+	}
+	return errs, nil
+}
+
+func (p *stubPool) Evict(string) {}
+
+func (p *stubPool) Close(context.Context) error {
+	return nil
+}
+
+// sequencingLog assigns sequence numbers like the memstore log does, without keeping the events.
+type sequencingLog struct {
+	esreplay.Log
+
+	mu        sync.Mutex
+	sequences map[pkgid.ID]uint64
+}
+
+func (l *sequencingLog) Record(_ context.Context, userID pkgid.ID, event *rteventspb.Event) (*rteventspb.Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.sequences == nil {
+		l.sequences = map[pkgid.ID]uint64{}
+	}
+	l.sequences[userID]++
+
+	sequenced := proto.Clone(event).(*rteventspb.Event)
+	sequenced.Sequence = l.sequences[userID]
+	return sequenced, nil
+}
+
+type stubArchiver struct{}
+
+func (stubArchiver) Archive(_ context.Context, msg pkgarchive.Message) (pkgarchive.Message, error) {
+	msg.ID = pkgid.NewID()
+	return msg, nil
+}
+
+func (stubArchiver) History(context.Context, pkgarchive.HistoryQuery) (pkgarchive.HistoryPage, error) {
+	return pkgarchive.HistoryPage{}, nil
+}
+
+func (stubArchiver) Message(context.Context, pkgid.ID) (pkgarchive.Message, error) {
+	return pkgarchive.Message{}, pkgarchive.ErrMessageNotFound
+}
+
+type collectingQueue struct {
+	mu     sync.Mutex
+	events map[pkgid.ID][]*rteventspb.Event
+}
+
+func (q *collectingQueue) Enqueue(_ context.Context, userID pkgid.ID, event *rteventspb.Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.events == nil {
+		q.events = map[pkgid.ID][]*rteventspb.Event{}
+	}
+	q.events[userID] = append(q.events[userID], event)
+	return nil
+}
+
+func (q *collectingQueue) Drain(_ context.Context, userID pkgid.ID) ([]*rteventspb.Event, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events := q.events[userID]
+	delete(q.events, userID)
+	return events, nil
+}
+
+type memoryDeadLetters struct {
+	mu      sync.Mutex
+	letters map[pkgid.ID]DeadLetter
+	err     error
+}
+
+func (s *memoryDeadLetters) Add(_ context.Context, letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.letters == nil {
+		s.letters = map[pkgid.ID]DeadLetter{}
+	}
+	s.letters[letter.ID] = letter
+	return nil
+}
+
+func (s *memoryDeadLetters) List(context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := make([]DeadLetter, 0, len(s.letters))
+	for _, letter := range s.letters {
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+func (s *memoryDeadLetters) Get(_ context.Context, id pkgid.ID) (DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letter, ok := s.letters[id]
+	if !ok {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+	return letter, nil
+}
+
+func (s *memoryDeadLetters) Remove(_ context.Context, id pkgid.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(s.letters, id)
+	return nil
+}
+
+func (s *memoryDeadLetters) Purge(context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := len(s.letters)
+	s.letters = nil
+	return purged, nil
+}
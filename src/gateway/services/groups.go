@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	"github.com/faustuzas/occa/src/pkg/groups"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+const maxGroupNameLength = 255
+
+// GroupManager manages groups on behalf of the authenticated user, checking the user's
+// role in the group allows the action.
+type GroupManager interface {
+	// Create creates a group owned by the user.
+	Create(ctx context.Context, name string) (groups.Group, error)
+
+	// Groups returns the groups the user is a member of.
+	Groups(ctx context.Context) ([]groups.Group, error)
+
+	Group(ctx context.Context, groupID pkgid.ID) (groups.Group, []groups.Member, error)
+	Rename(ctx context.Context, groupID pkgid.ID, name string) error
+
+	// Archive makes the group read-only, no more messages or membership changes are accepted.
+	Archive(ctx context.Context, groupID pkgid.ID) error
+
+	AddMember(ctx context.Context, groupID pkgid.ID, userID pkgid.ID, role groups.Role) error
+	SetMemberRole(ctx context.Context, groupID pkgid.ID, userID pkgid.ID, role groups.Role) error
+
+	// RemoveMember removes the member from the group, users are free to leave the group themselves.
+	RemoveMember(ctx context.Context, groupID pkgid.ID, userID pkgid.ID) error
+
+	// SendMessage fans the message out to every other member of the group.
+	SendMessage(ctx context.Context, groupID pkgid.ID, message string) error
+}
+
+type groupManager struct {
+	store groups.Store
+	relay RealTimeEventRelay
+}
+
+func NewGroupManager(store groups.Store, relay RealTimeEventRelay) GroupManager {
+	return &groupManager{
+		store: store,
+		relay: relay,
+	}
+}
+
+func (m *groupManager) Create(ctx context.Context, name string) (groups.Group, error) {
+	name, err := validateGroupName(name)
+	if err != nil {
+		return groups.Group{}, err
+	}
+
+	group, err := m.store.Create(ctx, name, pkgauth.PrincipalFromContext(ctx).ID)
+	if err != nil {
+		return groups.Group{}, fmt.Errorf("creating group: %w", err)
+	}
+	return group, nil
+}
+
+func (m *groupManager) Groups(ctx context.Context) ([]groups.Group, error) {
+	userGroups, err := m.store.GroupsOf(ctx, pkgauth.PrincipalFromContext(ctx).ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching groups: %w", err)
+	}
+	return userGroups, nil
+}
+
+func (m *groupManager) Group(ctx context.Context, groupID pkgid.ID) (groups.Group, []groups.Member, error) {
+	group, _, err := m.authorize(ctx, groupID, groups.RoleMember)
+	if err != nil {
+		return groups.Group{}, nil, err
+	}
+
+	members, err := m.store.Members(ctx, groupID)
+	if err != nil {
+		return groups.Group{}, nil, fmt.Errorf("fetching members: %w", err)
+	}
+	return group, members, nil
+}
+
+func (m *groupManager) Rename(ctx context.Context, groupID pkgid.ID, name string) error {
+	name, err := validateGroupName(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err = m.authorizeActive(ctx, groupID, groups.RoleAdmin); err != nil {
+		return err
+	}
+
+	if err = m.store.Rename(ctx, groupID, name); err != nil {
+		return groupError(fmt.Errorf("renaming group: %w", err))
+	}
+	return nil
+}
+
+func (m *groupManager) Archive(ctx context.Context, groupID pkgid.ID) error {
+	if _, err := m.authorizeActive(ctx, groupID, groups.RoleOwner); err != nil {
+		return err
+	}
+
+	if err := m.store.Archive(ctx, groupID); err != nil {
+		return groupError(fmt.Errorf("archiving group: %w", err))
+	}
+	return nil
+}
+
+func (m *groupManager) AddMember(ctx context.Context, groupID pkgid.ID, userID pkgid.ID, role groups.Role) error {
+	if err := validateMemberRole(role); err != nil {
+		return err
+	}
+
+	// admins add members, only the owner appoints admins
+	required := groups.RoleAdmin
+	if role == groups.RoleAdmin {
+		required = groups.RoleOwner
+	}
+	if _, err := m.authorizeActive(ctx, groupID, required); err != nil {
+		return err
+	}
+
+	if err := m.store.AddMember(ctx, groupID, groups.Member{UserID: userID, Role: role}); err != nil {
+		return groupError(fmt.Errorf("adding member: %w", err))
+	}
+	return nil
+}
+
+func (m *groupManager) SetMemberRole(ctx context.Context, groupID pkgid.ID, userID pkgid.ID, role groups.Role) error {
+	if err := validateMemberRole(role); err != nil {
+		return err
+	}
+
+	if _, err := m.authorizeActive(ctx, groupID, groups.RoleOwner); err != nil {
+		return err
+	}
+
+	if userID == pkgauth.PrincipalFromContext(ctx).ID {
+		return pkgerrors.BadRequest(fmt.Errorf("the owner cannot change their own role"))
+	}
+
+	if err := m.store.SetMemberRole(ctx, groupID, userID, role); err != nil {
+		return groupError(fmt.Errorf("changing member role: %w", err))
+	}
+	return nil
+}
+
+func (m *groupManager) RemoveMember(ctx context.Context, groupID pkgid.ID, userID pkgid.ID) error {
+	actor, err := m.authorizeActive(ctx, groupID, groups.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	if userID == pkgauth.PrincipalFromContext(ctx).ID {
+		if actor.Role == groups.RoleOwner {
+			return pkgerrors.BadRequest(fmt.Errorf("the owner cannot leave the group, it should be archived instead"))
+		}
+	} else {
+		target, err := m.store.Member(ctx, groupID, userID)
+		if err != nil {
+			return groupError(err)
+		}
+
+		// members are removed by someone of a higher role only
+		if !actor.Role.AtLeast(groups.RoleAdmin) || target.Role.AtLeast(actor.Role) {
+			return pkgerrors.Forbidden(fmt.Errorf("%s cannot remove %s from the group", actor.Role, target.Role))
+		}
+	}
+
+	if err = m.store.RemoveMember(ctx, groupID, userID); err != nil {
+		return groupError(fmt.Errorf("removing member: %w", err))
+	}
+	return nil
+}
+
+func (m *groupManager) SendMessage(ctx context.Context, groupID pkgid.ID, message string) error {
+	if _, err := m.authorizeActive(ctx, groupID, groups.RoleMember); err != nil {
+		return err
+	}
+
+	members, err := m.store.Members(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("fetching members: %w", err)
+	}
+
+	senderID := pkgauth.PrincipalFromContext(ctx).ID
+
+	recipients := make([]pkgid.ID, 0, len(members))
+	for _, member := range members {
+		if member.UserID != senderID {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	event := rteventspb.NewGroupMessageEvent(groupID, senderID, message)
+	if err = m.relay.ForwardMany(ctx, recipients, event); err != nil {
+		return fmt.Errorf("forwarding group message: %w", err)
+	}
+	return nil
+}
+
+// authorizeActive authorizes the user as authorize does and makes sure the group is not archived.
+func (m *groupManager) authorizeActive(ctx context.Context, groupID pkgid.ID, required groups.Role) (groups.Member, error) {
+	group, member, err := m.authorize(ctx, groupID, required)
+	if err != nil {
+		return groups.Member{}, err
+	}
+
+	if group.Archived {
+		return groups.Member{}, pkgerrors.BadRequest(fmt.Errorf("group %s is archived", groupID))
+	}
+	return member, nil
+}
+
+// authorize makes sure the user is a member of the group with at least the required role.
+func (m *groupManager) authorize(ctx context.Context, groupID pkgid.ID, required groups.Role) (groups.Group, groups.Member, error) {
+	group, err := m.store.Get(ctx, groupID)
+	if err != nil {
+		return groups.Group{}, groups.Member{}, groupError(err)
+	}
+
+	member, err := m.store.Member(ctx, groupID, pkgauth.PrincipalFromContext(ctx).ID)
+	if errors.Is(err, groups.ErrNotMember) {
+		// the group is not revealed to users outside of it
+		return groups.Group{}, groups.Member{}, pkgerrors.NotFound(fmt.Errorf("%w: %s", groups.ErrGroupNotFound, groupID))
+	}
+	if err != nil {
+		return groups.Group{}, groups.Member{}, fmt.Errorf("fetching membership: %w", err)
+	}
+
+	if !member.Role.AtLeast(required) {
+		return groups.Group{}, groups.Member{}, pkgerrors.Forbidden(fmt.Errorf("%s role is required", required))
+	}
+	return group, member, nil
+}
+
+func validateGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", pkgerrors.BadRequest(fmt.Errorf("group name is required"))
+	}
+	if len(name) > maxGroupNameLength {
+		return "", pkgerrors.BadRequest(fmt.Errorf("group name is longer than %d bytes", maxGroupNameLength))
+	}
+	return name, nil
+}
+
+// validateMemberRole makes sure the role can be granted, a group has a single owner.
+func validateMemberRole(role groups.Role) error {
+	if err := role.Validate(); err != nil {
+		return pkgerrors.BadRequest(err)
+	}
+	if role == groups.RoleOwner {
+		return pkgerrors.BadRequest(fmt.Errorf("a group has a single owner"))
+	}
+	return nil
+}
+
+func groupError(err error) error {
+	switch {
+	case errors.Is(err, groups.ErrGroupNotFound), errors.Is(err, groups.ErrNotMember):
+		return pkgerrors.NotFound(err)
+	case errors.Is(err, groups.ErrAlreadyMember):
+		return pkgerrors.BadRequest(err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	"github.com/faustuzas/occa/src/pkg/groups"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+func TestGroupManager_SendMessageFansOutToOtherMembers(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = groups.NewMockStore(ctrl)
+		relay = NewMockRealTimeEventRelay(ctrl)
+
+		groupID  = pkgid.NewID()
+		senderID = pkgid.NewID()
+		others   = []pkgid.ID{pkgid.NewID(), pkgid.NewID()}
+		ctx      = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: senderID})
+	)
+
+	expectMember(store, groups.Group{ID: groupID}, senderID, groups.RoleMember)
+	store.EXPECT().Members(gomock.Any(), groupID).Return([]groups.Member{
+		{UserID: senderID, Role: groups.RoleMember},
+		{UserID: others[0], Role: groups.RoleOwner},
+		{UserID: others[1], Role: groups.RoleAdmin},
+	}, nil)
+
+	relay.EXPECT().ForwardMany(gomock.Any(), gomock.InAnyOrder(others), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []pkgid.ID, event *rteventspb.Event) error {
+			require.Equal(t, groupID.String(), event.GetGroupMessage().GetGroupId())
+			require.Equal(t, senderID.String(), event.GetGroupMessage().GetSenderId())
+			require.Equal(t, "hello", event.GetGroupMessage().GetMessage())
+			return nil
+		})
+
+	require.NoError(t, NewGroupManager(store, relay).SendMessage(ctx, groupID, "hello"))
+}
+
+func TestGroupManager_ArchivedGroupIsReadOnly(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = groups.NewMockStore(ctrl)
+
+		groupID = pkgid.NewID()
+		userID  = pkgid.NewID()
+		ctx     = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+	)
+
+	expectMember(store, groups.Group{ID: groupID, Archived: true}, userID, groups.RoleOwner).Times(2)
+
+	manager := NewGroupManager(store, NewMockRealTimeEventRelay(ctrl))
+	requireErrorType(t, pkgerrors.TypeBadRequest, manager.SendMessage(ctx, groupID, "hello"))
+	requireErrorType(t, pkgerrors.TypeBadRequest, manager.Rename(ctx, groupID, "renamed"))
+}
+
+func TestGroupManager_NonMembersDoNotSeeGroup(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = groups.NewMockStore(ctrl)
+
+		groupID = pkgid.NewID()
+		userID  = pkgid.NewID()
+		ctx     = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+	)
+
+	store.EXPECT().Get(gomock.Any(), groupID).Return(groups.Group{ID: groupID}, nil)
+	store.EXPECT().Member(gomock.Any(), groupID, userID).Return(groups.Member{}, groups.ErrNotMember)
+
+	_, _, err := NewGroupManager(store, NewMockRealTimeEventRelay(ctrl)).Group(ctx, groupID)
+	requireErrorType(t, pkgerrors.TypeNotFound, err)
+	require.ErrorIs(t, err, groups.ErrGroupNotFound)
+}
+
+func TestGroupManager_RolePermissions(t *testing.T) {
+	var (
+		groupID  = pkgid.NewID()
+		actorID  = pkgid.NewID()
+		targetID = pkgid.NewID()
+		ctx      = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: actorID})
+	)
+
+	tests := []struct {
+		name       string
+		actorRole  groups.Role
+		targetRole groups.Role
+		action     func(m GroupManager) error
+		expect     func(store *groups.MockStore)
+		errType    pkgerrors.ErrorType
+	}{
+		{
+			name:      "admin adds member",
+			actorRole: groups.RoleAdmin,
+			action: func(m GroupManager) error {
+				return m.AddMember(ctx, groupID, targetID, groups.RoleMember)
+			},
+			expect: func(store *groups.MockStore) {
+				store.EXPECT().AddMember(gomock.Any(), groupID, groups.Member{UserID: targetID, Role: groups.RoleMember})
+			},
+		},
+		{
+			name:      "member cannot add members",
+			actorRole: groups.RoleMember,
+			action: func(m GroupManager) error {
+				return m.AddMember(ctx, groupID, targetID, groups.RoleMember)
+			},
+			errType: pkgerrors.TypeForbidden,
+		},
+		{
+			name:      "admin cannot appoint admins",
+			actorRole: groups.RoleAdmin,
+			action: func(m GroupManager) error {
+				return m.AddMember(ctx, groupID, targetID, groups.RoleAdmin)
+			},
+			errType: pkgerrors.TypeForbidden,
+		},
+		{
+			name:      "admin cannot archive",
+			actorRole: groups.RoleAdmin,
+			action: func(m GroupManager) error {
+				return m.Archive(ctx, groupID)
+			},
+			errType: pkgerrors.TypeForbidden,
+		},
+		{
+			name:       "admin removes member",
+			actorRole:  groups.RoleAdmin,
+			targetRole: groups.RoleMember,
+			action: func(m GroupManager) error {
+				return m.RemoveMember(ctx, groupID, targetID)
+			},
+			expect: func(store *groups.MockStore) {
+				store.EXPECT().RemoveMember(gomock.Any(), groupID, targetID)
+			},
+		},
+		{
+			name:       "admin cannot remove admin",
+			actorRole:  groups.RoleAdmin,
+			targetRole: groups.RoleAdmin,
+			action: func(m GroupManager) error {
+				return m.RemoveMember(ctx, groupID, targetID)
+			},
+			errType: pkgerrors.TypeForbidden,
+		},
+		{
+			name:      "member leaves",
+			actorRole: groups.RoleMember,
+			action: func(m GroupManager) error {
+				return m.RemoveMember(ctx, groupID, actorID)
+			},
+			expect: func(store *groups.MockStore) {
+				store.EXPECT().RemoveMember(gomock.Any(), groupID, actorID)
+			},
+		},
+		{
+			name:      "owner cannot leave",
+			actorRole: groups.RoleOwner,
+			action: func(m GroupManager) error {
+				return m.RemoveMember(ctx, groupID, actorID)
+			},
+			errType: pkgerrors.TypeBadRequest,
+		},
+		{
+			name:      "owner promotes member",
+			actorRole: groups.RoleOwner,
+			action: func(m GroupManager) error {
+				return m.SetMemberRole(ctx, groupID, targetID, groups.RoleAdmin)
+			},
+			expect: func(store *groups.MockStore) {
+				store.EXPECT().SetMemberRole(gomock.Any(), groupID, targetID, groups.RoleAdmin)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ctrl  = gomock.NewController(t)
+				store = groups.NewMockStore(ctrl)
+			)
+
+			expectMember(store, groups.Group{ID: groupID}, actorID, tt.actorRole)
+			if tt.targetRole != "" {
+				store.EXPECT().Member(gomock.Any(), groupID, targetID).Return(groups.Member{UserID: targetID, Role: tt.targetRole}, nil)
+			}
+			if tt.expect != nil {
+				tt.expect(store)
+			}
+
+			err := tt.action(NewGroupManager(store, NewMockRealTimeEventRelay(ctrl)))
+			if tt.errType == 0 {
+				require.NoError(t, err)
+			} else {
+				requireErrorType(t, tt.errType, err)
+			}
+		})
+	}
+}
+
+func expectMember(store *groups.MockStore, group groups.Group, userID pkgid.ID, role groups.Role) *gomock.Call {
+	store.EXPECT().Get(gomock.Any(), group.ID).Return(group, nil).AnyTimes()
+	return store.EXPECT().Member(gomock.Any(), group.ID, userID).Return(groups.Member{UserID: userID, Role: role}, nil)
+}
+
+func requireErrorType(t *testing.T, expected pkgerrors.ErrorType, err error) {
+	t.Helper()
+
+	var gErr pkgerrors.GenericErr
+	require.True(t, errors.As(err, &gErr), "unexpected error: %v", err)
+	require.Equal(t, expected, gErr.Type())
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/faustuzas/occa/src/pkg/contacts"
+	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+)
+
+const presenceRewatchInterval = time.Second
+
+// OnlineUsersWatcher reports users connecting to and disconnecting from event servers.
+type OnlineUsersWatcher interface {
+	Watch(ctx context.Context) (<-chan rtconn.OnlineChange, error)
+}
+
+// PresenceAudience decides who is notified about presence changes of the user.
+type PresenceAudience interface {
+	AudienceOf(ctx context.Context, userID pkgid.ID) ([]pkgid.ID, error)
+}
+
+type contactsAudience struct {
+	store contacts.Store
+}
+
+// NewContactsAudience notifies the accepted contacts of the user.
+func NewContactsAudience(store contacts.Store) PresenceAudience {
+	return contactsAudience{store: store}
+}
+
+func (a contactsAudience) AudienceOf(ctx context.Context, userID pkgid.ID) ([]pkgid.ID, error) {
+	return acceptedContacts(ctx, a.store, userID)
+}
+
+// PresenceNotifier pushes PresenceChanged events to the audience of users who come online or
+// go offline, so clients do not have to poll active users.
+type PresenceNotifier struct {
+	mu      sync.Mutex
+	started bool
+	closeCh chan struct{}
+	doneCh  chan struct{}
+
+	online   OnlineUsersWatcher
+	tracker  ActiveUsersTracker
+	audience PresenceAudience
+	relay    RealTimeEventRelay
+
+	i pkginstrument.Instrumentation
+}
+
+func NewPresenceNotifier(
+	i pkginstrument.Instrumentation,
+	online OnlineUsersWatcher,
+	tracker ActiveUsersTracker,
+	audience PresenceAudience,
+	relay RealTimeEventRelay,
+) *PresenceNotifier {
+	return &PresenceNotifier{
+		closeCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+
+		online:   online,
+		tracker:  tracker,
+		audience: audience,
+		relay:    relay,
+
+		i: i,
+	}
+}
+
+// Start follows the online users in the background.
+func (n *PresenceNotifier) Start(_ context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.started = true
+	go n.run()
+
+	return nil
+}
+
+// run watches the online users until closed. Whenever the watch breaks it is started again,
+// the changes missed meanwhile are not pushed.
+func (n *PresenceNotifier) run() {
+	defer close(n.doneCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-n.closeCh
+		cancel()
+	}()
+
+	for {
+		changes, err := n.online.Watch(ctx)
+		if err != nil {
+			n.i.Logger.Warn("failed to watch online users", zap.Error(err))
+		} else {
+			for change := range changes {
+				n.notify(ctx, change)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(presenceRewatchInterval):
+		}
+	}
+}
+
+func (n *PresenceNotifier) notify(ctx context.Context, change rtconn.OnlineChange) {
+	status := PresenceOffline
+	if change.Online {
+		var err error
+		if status, err = n.tracker.Status(ctx, change.UserID); err != nil {
+			n.i.Logger.Warn("failed to fetch presence status", zap.Stringer("userId", change.UserID), zap.Error(err))
+			return
+		}
+
+		// invisible users are shown offline, which the audience already believes
+		if status == PresenceInvisible {
+			return
+		}
+	}
+
+	audience, err := n.audience.AudienceOf(ctx, change.UserID)
+	if err != nil {
+		n.i.Logger.Warn("failed to fetch presence audience", zap.Stringer("userId", change.UserID), zap.Error(err))
+		return
+	}
+
+	event := rteventspb.NewPresenceChangedEvent(change.UserID, string(status))
+	for _, recipientID := range audience {
+		if err = n.relay.ForwardEphemeral(ctx, recipientID, event); err != nil {
+			n.i.Logger.Warn("failed to push presence change",
+				zap.Stringer("userId", change.UserID), zap.Stringer("recipientId", recipientID), zap.Error(err))
+		}
+	}
+}
+
+func (n *PresenceNotifier) Close(ctx context.Context) error {
+	close(n.closeCh)
+
+	n.mu.Lock()
+	started := n.started
+	n.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-n.doneCh:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the presence watch to stop: %w", ctx.Err())
+	}
+}
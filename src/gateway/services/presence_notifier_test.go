@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	"github.com/faustuzas/occa/src/pkg/contacts"
+	"github.com/faustuzas/occa/src/pkg/eventserver/rtconn"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestPresenceNotifier_PushesChangesToAudience(t *testing.T) {
+	var (
+		ctrl    = gomock.NewController(t)
+		store   = contacts.NewMockStore(ctrl)
+		tracker = newTestTracker(t, &fixedClock{now: time.Now()})
+		relay   = NewMockRealTimeEventRelay(ctrl)
+		pushed  = make(chan pushedEvent, 10)
+		watcher = &channelWatcher{changes: make(chan rtconn.OnlineChange)}
+
+		alice   = userContext("alice")
+		aliceID = pkgauth.PrincipalFromContext(alice).ID
+		bobID   = pkgid.NewID()
+		carolID = pkgid.NewID()
+
+		invisible = PresenceInvisible
+	)
+
+	store.EXPECT().Contacts(gomock.Any(), aliceID).Return([]contacts.Contact{
+		{UserID: bobID, Status: contacts.StatusAccepted},
+		{UserID: pkgid.NewID(), Status: contacts.StatusIncoming},
+		{UserID: carolID, Status: contacts.StatusAccepted},
+	}, nil).AnyTimes()
+
+	relay.EXPECT().ForwardEphemeral(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+			pushed <- pushedEvent{
+				recipientID: recipientID,
+				userID:      pkgid.FromString(event.GetPresenceChanged().GetUserId()),
+				status:      event.GetPresenceChanged().GetStatus(),
+			}
+			return nil
+		}).
+		AnyTimes()
+
+	notifier := NewPresenceNotifier(pkgtest.Instrumentation, watcher, tracker, NewContactsAudience(store), relay)
+	require.NoError(t, notifier.Start(context.Background()))
+
+	// only accepted contacts are notified
+	watcher.changes <- rtconn.OnlineChange{UserID: aliceID, Online: true}
+	require.ElementsMatch(t, []pushedEvent{
+		{recipientID: bobID, userID: aliceID, status: "online"},
+		{recipientID: carolID, userID: aliceID, status: "online"},
+	}, receivePushed(t, pushed, 2))
+
+	// invisible users do not announce coming online, but going offline is harmless
+	require.NoError(t, tracker.SetPresence(alice, PresenceUpdate{Status: &invisible}))
+	watcher.changes <- rtconn.OnlineChange{UserID: aliceID, Online: true}
+	watcher.changes <- rtconn.OnlineChange{UserID: aliceID, Online: false}
+	require.ElementsMatch(t, []pushedEvent{
+		{recipientID: bobID, userID: aliceID, status: "offline"},
+		{recipientID: carolID, userID: aliceID, status: "offline"},
+	}, receivePushed(t, pushed, 2))
+
+	require.NoError(t, notifier.Close(context.Background()))
+	require.Empty(t, pushed)
+}
+
+type channelWatcher struct {
+	changes chan rtconn.OnlineChange
+}
+
+func (w *channelWatcher) Watch(ctx context.Context) (<-chan rtconn.OnlineChange, error) {
+	changes := make(chan rtconn.OnlineChange)
+	go func() {
+		defer close(changes)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change := <-w.changes:
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+type pushedEvent struct {
+	recipientID pkgid.ID
+	userID      pkgid.ID
+	status      string
+}
+
+func receivePushed(t *testing.T, pushed <-chan pushedEvent, count int) []pushedEvent {
+	t.Helper()
+
+	events := make([]pushedEvent, 0, count)
+	for len(events) < count {
+		select {
+		case event := <-pushed:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatalf("expected %d presence changes, got %d", count, len(events))
+		}
+	}
+	return events
+}
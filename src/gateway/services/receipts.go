@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgclock "github.com/faustuzas/occa/src/pkg/clock"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	"github.com/faustuzas/occa/src/pkg/receipts"
+)
+
+// ConversationReceipts is the receipt state of both directions of the conversation.
+type ConversationReceipts struct {
+	// Sent tells how far the other user has got through the messages of the authenticated user.
+	Sent receipts.State `json:"sent"`
+
+	// Received tells what the authenticated user has reported about the messages of the other user.
+	Received receipts.State `json:"received"`
+}
+
+type ReceiptTracker interface {
+	// Acknowledge records that the authenticated user has received or read the message
+	// and lets its sender know.
+	Acknowledge(ctx context.Context, kind receipts.Kind, senderID, messageID pkgid.ID) error
+
+	// Conversation returns receipts of the conversation between the authenticated user and the other user.
+	Conversation(ctx context.Context, withID pkgid.ID) (ConversationReceipts, error)
+}
+
+type receiptTracker struct {
+	store    receipts.Store
+	archiver archiverclient.Client
+	relay    RealTimeEventRelay
+	clock    pkgclock.Clock
+}
+
+func NewReceiptTracker(store receipts.Store, archiver archiverclient.Client, relay RealTimeEventRelay, clock pkgclock.Clock) ReceiptTracker {
+	return &receiptTracker{
+		store:    store,
+		archiver: archiver,
+		relay:    relay,
+		clock:    clock,
+	}
+}
+
+func (t *receiptTracker) Acknowledge(ctx context.Context, kind receipts.Kind, senderID, messageID pkgid.ID) error {
+	if err := kind.Validate(); err != nil {
+		return pkgerrors.BadRequest(err)
+	}
+
+	recipientID := pkgauth.PrincipalFromContext(ctx).ID
+	if recipientID == senderID {
+		return pkgerrors.BadRequest(fmt.Errorf("receipts are sent for messages of other users"))
+	}
+
+	msg, err := t.archiver.Message(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("fetching message: %w", err)
+	}
+
+	// a message of another conversation is reported the same as a missing one, so
+	// receipts cannot be used to probe for messages of other users
+	if msg.SenderID != senderID || msg.RecipientID != recipientID {
+		return pkgerrors.NotFound(fmt.Errorf("%w: %s", pkgarchive.ErrMessageNotFound, messageID))
+	}
+
+	// the receipt is persisted first, so the sender can query it even if it is offline now
+	err = t.store.Record(ctx, receipts.Receipt{
+		Kind:          kind,
+		MessageID:     messageID,
+		MessageSentAt: msg.SentAt,
+		SenderID:      senderID,
+		RecipientID:   recipientID,
+		At:            t.clock.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("recording receipt: %w", err)
+	}
+
+	event := rteventspb.NewDeliveredEvent(messageID, recipientID)
+	if kind == receipts.KindRead {
+		event = rteventspb.NewReadEvent(messageID, recipientID)
+	}
+
+	if err = t.relay.Forward(ctx, senderID, event); err != nil {
+		return fmt.Errorf("forwarding receipt: %w", err)
+	}
+	return nil
+}
+
+func (t *receiptTracker) Conversation(ctx context.Context, withID pkgid.ID) (ConversationReceipts, error) {
+	userID := pkgauth.PrincipalFromContext(ctx).ID
+
+	sent, err := t.store.State(ctx, userID, withID)
+	if err != nil {
+		return ConversationReceipts{}, fmt.Errorf("fetching sent receipts: %w", err)
+	}
+
+	received, err := t.store.State(ctx, withID, userID)
+	if err != nil {
+		return ConversationReceipts{}, fmt.Errorf("fetching received receipts: %w", err)
+	}
+
+	return ConversationReceipts{Sent: sent, Received: received}, nil
+}
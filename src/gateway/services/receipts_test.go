@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgclock "github.com/faustuzas/occa/src/pkg/clock"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	"github.com/faustuzas/occa/src/pkg/receipts"
+)
+
+func TestReceiptTracker_ReadIsRecordedAndForwardedToSender(t *testing.T) {
+	var (
+		ctrl     = gomock.NewController(t)
+		store    = receipts.NewMockStore(ctrl)
+		archiver = archiverclient.NewMockClient(ctrl)
+		relay    = NewMockRealTimeEventRelay(ctrl)
+		now      = time.Now()
+
+		senderID    = pkgid.NewID()
+		recipientID = pkgid.NewID()
+		messageID   = pkgid.NewID()
+		sentAt      = now.Add(-time.Minute)
+		ctx         = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: recipientID})
+	)
+
+	archiver.EXPECT().Message(gomock.Any(), messageID).Return(pkgarchive.Message{
+		ID:          messageID,
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		SentAt:      sentAt,
+	}, nil)
+	store.EXPECT().Record(gomock.Any(), receipts.Receipt{
+		Kind:          receipts.KindRead,
+		MessageID:     messageID,
+		MessageSentAt: sentAt,
+		SenderID:      senderID,
+		RecipientID:   recipientID,
+		At:            now,
+	})
+
+	relay.EXPECT().Forward(gomock.Any(), senderID, rteventspb.NewReadEvent(messageID, recipientID))
+
+	tracker := NewReceiptTracker(store, archiver, relay, fixedClock{now})
+	require.NoError(t, tracker.Acknowledge(ctx, receipts.KindRead, senderID, messageID))
+}
+
+func TestReceiptTracker_RejectsInvalidReceipts(t *testing.T) {
+	var (
+		ctrl     = gomock.NewController(t)
+		archiver = archiverclient.NewMockClient(ctrl)
+		userID   = pkgid.NewID()
+		ctx      = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: userID})
+		tracker  = NewReceiptTracker(receipts.NewMockStore(ctrl), archiver, NewMockRealTimeEventRelay(ctrl), pkgclock.RealClock{})
+
+		senderID  = pkgid.NewID()
+		messageID = pkgid.NewID()
+	)
+
+	requireErrorType(t, pkgerrors.TypeBadRequest, tracker.Acknowledge(ctx, "seen", pkgid.NewID(), pkgid.NewID()))
+	requireErrorType(t, pkgerrors.TypeBadRequest, tracker.Acknowledge(ctx, receipts.KindDelivered, userID, pkgid.NewID()))
+
+	archiver.EXPECT().Message(gomock.Any(), messageID).
+		Return(pkgarchive.Message{}, pkgerrors.NotFound(pkgarchive.ErrMessageNotFound))
+	requireErrorType(t, pkgerrors.TypeNotFound, tracker.Acknowledge(ctx, receipts.KindRead, senderID, messageID))
+
+	// the message exists, but was sent to someone else
+	archiver.EXPECT().Message(gomock.Any(), messageID).
+		Return(pkgarchive.Message{ID: messageID, SenderID: senderID, RecipientID: pkgid.NewID()}, nil)
+	requireErrorType(t, pkgerrors.TypeNotFound, tracker.Acknowledge(ctx, receipts.KindRead, senderID, messageID))
+
+	// or by someone else than the receipt claims
+	archiver.EXPECT().Message(gomock.Any(), messageID).
+		Return(pkgarchive.Message{ID: messageID, SenderID: pkgid.NewID(), RecipientID: userID}, nil)
+	requireErrorType(t, pkgerrors.TypeNotFound, tracker.Acknowledge(ctx, receipts.KindRead, senderID, messageID))
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgclock "github.com/faustuzas/occa/src/pkg/clock"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+const (
+	typingCollection = "typing"
+
+	defaultTypingThrottle = 2 * time.Second
+	defaultTypingExpiry   = 6 * time.Second
+)
+
+type TypingConfiguration struct {
+	// Throttle is how often start signals are relayed while the user keeps typing.
+	Throttle time.Duration `yaml:"throttle"`
+
+	// Expiry is how long the user is considered typing after the last relayed start signal.
+	Expiry time.Duration `yaml:"expiry"`
+}
+
+func (c TypingConfiguration) withDefaults() TypingConfiguration {
+	if c.Throttle <= 0 {
+		c.Throttle = defaultTypingThrottle
+	}
+
+	if c.Expiry <= c.Throttle {
+		c.Expiry = max(defaultTypingExpiry, 2*c.Throttle)
+	}
+
+	return c
+}
+
+type TypingNotifier interface {
+	// Typing relays that the authenticated user has started or stopped typing to the recipient.
+	Typing(ctx context.Context, recipientID pkgid.ID, typing bool) error
+}
+
+// typingNotifier keeps when a start signal was last relayed to the recipient until it expires,
+// so repeated start signals are throttled and stop signals are relayed only while the recipient
+// is shown the indicator.
+type typingNotifier struct {
+	store  pkgmemstore.Store
+	relay  RealTimeEventRelay
+	clock  pkgclock.Clock
+	config TypingConfiguration
+}
+
+func NewTypingNotifier(store pkgmemstore.Store, relay RealTimeEventRelay, clock pkgclock.Clock, config TypingConfiguration) TypingNotifier {
+	return &typingNotifier{
+		store:  store,
+		relay:  relay,
+		clock:  clock,
+		config: config.withDefaults(),
+	}
+}
+
+func (n *typingNotifier) Typing(ctx context.Context, recipientID pkgid.ID, typing bool) error {
+	senderID := pkgauth.PrincipalFromContext(ctx).ID
+	if senderID == recipientID {
+		return pkgerrors.BadRequest(fmt.Errorf("typing signals are sent to other users"))
+	}
+
+	key := senderID.String() + ":" + recipientID.String()
+
+	current, err := n.store.GetCollectionItem(ctx, typingCollection, key)
+	if err != nil && !errors.Is(err, pkgmemstore.ErrNotFound) {
+		return fmt.Errorf("fetching typing state: %w", err)
+	}
+
+	now := n.clock.Now()
+	if typing {
+		if current != nil && now.Sub(parseTypingState(current)) < n.config.Throttle {
+			return nil
+		}
+
+		swapped, err := n.store.CompareAndSwapCollectionItem(ctx, typingCollection, key, current,
+			[]byte(strconv.FormatInt(now.UnixNano(), 10)), n.config.Expiry)
+		if err != nil {
+			return fmt.Errorf("storing typing state: %w", err)
+		}
+		if !swapped {
+			// a concurrent signal has been relayed
+			return nil
+		}
+	} else {
+		if current == nil {
+			// the indicator has expired or was never shown
+			return nil
+		}
+
+		swapped, err := n.store.CompareAndSwapCollectionItem(ctx, typingCollection, key, current, nil, 0)
+		if err != nil {
+			return fmt.Errorf("clearing typing state: %w", err)
+		}
+		if !swapped {
+			return nil
+		}
+	}
+
+	event := rteventspb.NewTypingEvent(senderID, typing, n.config.Expiry)
+	if err = n.relay.ForwardEphemeral(ctx, recipientID, event); err != nil {
+		return fmt.Errorf("relaying typing signal: %w", err)
+	}
+	return nil
+}
+
+// parseTypingState returns when the start signal was relayed, malformed state is treated as expired.
+func parseTypingState(value []byte) time.Time {
+	nanos, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+func TestTypingNotifier_ThrottlesStartSignals(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+		relay = NewMockRealTimeEventRelay(ctrl)
+		clock = &fixedClock{now: time.Now()}
+
+		senderID    = pkgid.NewID()
+		recipientID = pkgid.NewID()
+		key         = senderID.String() + ":" + recipientID.String()
+		ctx         = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: senderID})
+
+		notifier = NewTypingNotifier(store, relay, clock, TypingConfiguration{Throttle: time.Second, Expiry: 3 * time.Second})
+		state    = []byte(strconv.FormatInt(clock.now.UnixNano(), 10))
+	)
+
+	// the first signal is relayed
+	store.EXPECT().GetCollectionItem(gomock.Any(), typingCollection, key).Return(nil, pkgmemstore.ErrNotFound)
+	store.EXPECT().CompareAndSwapCollectionItem(gomock.Any(), typingCollection, key, nil, state, 3*time.Second).Return(true, nil)
+	relay.EXPECT().ForwardEphemeral(gomock.Any(), recipientID, rteventspb.NewTypingEvent(senderID, true, 3*time.Second))
+	require.NoError(t, notifier.Typing(ctx, recipientID, true))
+
+	// the repeated one within the throttle is not
+	store.EXPECT().GetCollectionItem(gomock.Any(), typingCollection, key).Return(state, nil)
+	require.NoError(t, notifier.Typing(ctx, recipientID, true))
+
+	// stop clears the state and is relayed
+	store.EXPECT().GetCollectionItem(gomock.Any(), typingCollection, key).Return(state, nil)
+	store.EXPECT().CompareAndSwapCollectionItem(gomock.Any(), typingCollection, key, state, nil, time.Duration(0)).Return(true, nil)
+	relay.EXPECT().ForwardEphemeral(gomock.Any(), recipientID, rteventspb.NewTypingEvent(senderID, false, 3*time.Second))
+	require.NoError(t, notifier.Typing(ctx, recipientID, false))
+}
+
+func TestTypingNotifier_StopWithoutStartIsDropped(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+		relay = NewMockRealTimeEventRelay(ctrl)
+
+		senderID    = pkgid.NewID()
+		recipientID = pkgid.NewID()
+		ctx         = pkgauth.ContextWithPrincipal(context.Background(), pkgauth.Principal{ID: senderID})
+	)
+
+	store.EXPECT().GetCollectionItem(gomock.Any(), typingCollection, gomock.Any()).Return(nil, pkgmemstore.ErrNotFound)
+
+	notifier := NewTypingNotifier(store, relay, &fixedClock{now: time.Now()}, TypingConfiguration{})
+	// the relay mock fails the test if anything is relayed
+	require.NoError(t, notifier.Typing(ctx, recipientID, false))
+}
@@ -0,0 +1,77 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/faustuzas/occa/src/archiver"
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestArchiver_HistoryPagination(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		params = DefaultParams(t)
+
+		alice = pkgid.NewID()
+		bob   = pkgid.NewID()
+		carol = pkgid.NewID()
+	)
+	go func() {
+		require.NoError(t, archiver.Start(params))
+	}()
+
+	require.Eventually(t, func() bool {
+		_, body := pkgtest.HTTPGetBody(t, params.HTTPListenAddress.String(), "/health")
+		return pkghttp.DefaultOKResponse() == string(body)
+	}, time.Second, 100*time.Millisecond)
+
+	// only other services are allowed to read and write the archive
+	status, _ := pkgtest.HTTPGetBody(t, params.HTTPListenAddress.String(), fmt.Sprintf("/history?user=%s&with=%s", alice, bob))
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	client := archiverclient.NewHTTPClient(params.HTTPListenAddress.String(),
+		map[string]string{"Authorization": "Bearer " + ServiceToken})
+
+	sentAt := time.Now()
+	for i := 0; i < 3; i++ {
+		sender, recipient := alice, bob
+		if i%2 == 1 {
+			sender, recipient = bob, alice
+		}
+
+		_, err := client.Archive(ctx, pkgarchive.Message{
+			SenderID:    sender,
+			RecipientID: recipient,
+			Content:     fmt.Sprintf("message %d", i),
+			SentAt:      sentAt.Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+	}
+
+	// message from another conversation should not leak into the history
+	_, err := client.Archive(ctx, pkgarchive.Message{SenderID: carol, RecipientID: alice, Content: "hi", SentAt: sentAt})
+	require.NoError(t, err)
+
+	page, err := client.History(ctx, pkgarchive.HistoryQuery{UserID: alice, WithID: bob, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	require.Equal(t, "message 2", page.Messages[0].Content)
+	require.Equal(t, "message 1", page.Messages[1].Content)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = client.History(ctx, pkgarchive.HistoryQuery{UserID: bob, WithID: alice, Limit: 2, Before: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	require.Equal(t, "message 0", page.Messages[0].Content)
+	require.Empty(t, page.NextCursor)
+}
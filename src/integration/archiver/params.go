@@ -0,0 +1,53 @@
+package archiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/faustuzas/occa/src/archiver"
+	"github.com/faustuzas/occa/src/integration/containers"
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	pkgdb "github.com/faustuzas/occa/src/pkg/db"
+	pkgnet "github.com/faustuzas/occa/src/pkg/net"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+// ServiceToken authorizes calls of other services to the archiver in tests.
+const ServiceToken = "test-service-token"
+
+func DefaultParams(t *testing.T) archiver.Params {
+	db := containers.WithMysql(t)
+
+	listenAddr := pkgnet.ListenAddrFromAddress("0.0.0.0:0")
+	_, err := listenAddr.Listener()
+	require.NoError(t, err)
+
+	closeCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(closeCh)
+	})
+
+	archiveDatabase, err := db.WithTemporaryDatabase(t, "archive")
+	require.NoError(t, err)
+
+	return archiver.Params{
+		Configuration: archiver.Configuration{
+			HTTPListenAddress: listenAddr,
+			Store: pkgarchive.Configuration{
+				DB: pkgdb.Configuration{
+					DBType:         "mysql",
+					DataSourceName: db.DataSourceName(archiveDatabase),
+				},
+			},
+			ServiceAuth: pkgauth.ServiceAuthConfiguration{
+				Type:  pkgauth.ServiceAuthConfigurationToken,
+				Token: ServiceToken,
+			},
+		},
+		Logger:  pkgtest.Instrumentation.Logger.With(zap.String("component", "archiver"), zap.String("test", t.Name())),
+		CloseCh: closeCh,
+	}
+}
@@ -61,6 +61,10 @@ func DefaultParams(t *testing.T) eventserver.Params {
 					PublicKeyPath: pubKey,
 				},
 			},
+			ServiceAuth: pkgauth.ServiceAuthConfiguration{
+				Type:  pkgauth.ServiceAuthConfigurationToken,
+				Token: "test-service-token",
+			},
 		},
 		Logger:  pkgtest.Instrumentation.Logger.With(zap.String("component", "chat-server"), zap.String("test", t.Name())),
 		CloseCh: closeCh,
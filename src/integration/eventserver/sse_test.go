@@ -0,0 +1,108 @@
+package eventserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/faustuzas/occa/src/eventserver"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestEventServer_SSE(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		params = DefaultParams(t)
+
+		sender    = pkgid.NewID()
+		recipient = pkgid.NewID()
+	)
+	go func() {
+		require.NoError(t, eventserver.Start(params))
+	}()
+
+	require.Eventually(t, func() bool {
+		_, body := pkgtest.HTTPGetBody(t, params.HTTPListenAddress.String(), "/health")
+		return pkghttp.DefaultOKResponse() == string(body)
+	}, time.Second, 100*time.Millisecond)
+
+	openStream := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+params.HTTPListenAddress.String()+"/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", generateToken(t, recipient, "recipient")))
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	sendMessage := func(content string) {
+		event := sequenceEvent(t, ctx, params, recipient, rteventspb.NewDirectMessageEvent(sender, content))
+		require.Eventually(t, func() bool {
+			return deliverEvent(t, ctx, params, recipient, event) == nil
+		}, time.Second, 50*time.Millisecond)
+	}
+
+	readEvent := func(reader *bufio.Reader) (string, *rteventspb.Event) {
+		var (
+			id    string
+			event rteventspb.Event
+		)
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && id != "":
+				return id, &event
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, protojson.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			}
+		}
+	}
+
+	firstResp, firstStream := openStream("")
+	defer func() {
+		_ = firstResp.Body.Close()
+	}()
+
+	sendMessage("first")
+	sendMessage("second")
+
+	id, event := readEvent(firstStream)
+	require.Equal(t, "1", id)
+	require.Equal(t, sender.String(), event.GetDirectMessage().GetSenderId())
+	require.Equal(t, "first", event.GetDirectMessage().GetMessage())
+
+	id, event = readEvent(firstStream)
+	require.Equal(t, "2", id)
+	require.Equal(t, "second", event.GetDirectMessage().GetMessage())
+
+	// reconnecting client gets everything after the last event it has seen
+	secondResp, secondStream := openStream("1")
+	defer func() {
+		_ = secondResp.Body.Close()
+	}()
+
+	id, event = readEvent(secondStream)
+	require.Equal(t, "2", id)
+	require.Equal(t, "second", event.GetDirectMessage().GetMessage())
+}
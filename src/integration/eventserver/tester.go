@@ -11,8 +11,10 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	grpcmeta "google.golang.org/grpc/metadata"
 
+	"github.com/faustuzas/occa/src/eventserver"
 	"github.com/faustuzas/occa/src/eventserver/generated/proto/eventserverpb"
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	esreplay "github.com/faustuzas/occa/src/pkg/eventserver/replay"
 	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkgtest "github.com/faustuzas/occa/src/pkg/test"
@@ -98,6 +100,38 @@ func (r GRPCStream[T]) RecvCh() <-chan Msg[T] {
 	return r.outputCh
 }
 
+// deliverEvent sends the event through the service authenticated Deliver RPC, the way the gateway does.
+func deliverEvent(t *testing.T, ctx context.Context, params eventserver.Params, recipientID pkgid.ID, event *rteventspb.Event) error {
+	creds, err := params.ServiceAuth.BuildGRPCCredentials()
+	require.NoError(t, err)
+
+	conn, err := grpc.DialContext(ctx, params.GRPCListenAddress.String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithPerRPCCredentials(creds))
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = eventserverpb.NewEventServerClient(conn).Deliver(ctx, &eventserverpb.DeliverRequest{
+		RecipientId: recipientID.String(),
+		Event:       event,
+	})
+	return err
+}
+
+// sequenceEvent assigns the next sequence number of the recipient to the event, the way the gateway does.
+func sequenceEvent(t *testing.T, ctx context.Context, params eventserver.Params, recipientID pkgid.ID, event *rteventspb.Event) *rteventspb.Event {
+	store, err := params.MemStore.Build()
+	require.NoError(t, err)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	sequenced, err := esreplay.NewMemStoreLog(pkgtest.Instrumentation, store, esreplay.Configuration{}).Record(ctx, recipientID, event)
+	require.NoError(t, err)
+	return sequenced
+}
+
 func generateToken(t *testing.T, userID pkgid.ID, name string) string {
 	_, privatePath, err := pkgtest.GetRSAPairPaths()
 	require.NoError(t, err)
@@ -0,0 +1,91 @@
+package eventserver
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/eventserver"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestEventServer_WebSocket(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		params = DefaultParams(t)
+
+		sender    = pkgid.NewID()
+		recipient = pkgid.NewID()
+	)
+	go func() {
+		require.NoError(t, eventserver.Start(params))
+	}()
+
+	require.Eventually(t, func() bool {
+		_, body := pkgtest.HTTPGetBody(t, params.HTTPListenAddress.String(), "/health")
+		return pkghttp.DefaultOKResponse() == string(body)
+	}, time.Second, 100*time.Millisecond)
+
+	for _, format := range []string{"json", "protobuf"} {
+		t.Run(format, func(t *testing.T) {
+			wsURL := url.URL{
+				Scheme:   "ws",
+				Host:     params.HTTPListenAddress.String(),
+				Path:     "/ws",
+				RawQuery: url.Values{"access_token": {generateToken(t, recipient, "recipient")}, "format": {format}}.Encode(),
+			}
+
+			conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), nil)
+			require.NoError(t, err)
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			require.Eventually(t, func() bool {
+				return deliverEvent(t, ctx, params, recipient, rteventspb.NewDirectMessageEvent(sender, "hello "+format)) == nil
+			}, time.Second, 50*time.Millisecond)
+
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+			msgType, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+
+			var event rteventspb.Event
+			if format == "json" {
+				require.Equal(t, websocket.TextMessage, msgType)
+				require.NoError(t, protojson.Unmarshal(data, &event))
+			} else {
+				require.Equal(t, websocket.BinaryMessage, msgType)
+				require.NoError(t, proto.Unmarshal(data, &event))
+			}
+
+			require.Equal(t, sender.String(), event.GetDirectMessage().GetSenderId())
+			require.Equal(t, "hello "+format, event.GetDirectMessage().GetMessage())
+		})
+	}
+}
+
+func TestEventServer_WebSocketRequiresAuth(t *testing.T) {
+	params := DefaultParams(t)
+	go func() {
+		require.NoError(t, eventserver.Start(params))
+	}()
+
+	require.Eventually(t, func() bool {
+		_, body := pkgtest.HTTPGetBody(t, params.HTTPListenAddress.String(), "/health")
+		return pkghttp.DefaultOKResponse() == string(body)
+	}, time.Second, 100*time.Millisecond)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws://"+params.HTTPListenAddress.String()+"/ws", nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/faustuzas/occa/src/gateway"
+	"github.com/faustuzas/occa/src/pkg/contacts"
 )
 
 func TestGateway_ActiveUsers(t *testing.T) {
@@ -19,14 +20,25 @@ func TestGateway_ActiveUsers(t *testing.T) {
 	user1.Register("user_1", "password")
 	user1.Login("user_1", "password")
 
-	activeUsers := user1.ActiveUsers().ActiveUsers
-	require.Empty(t, activeUsers)
+	user2 := NewTester(t, context.Background(), params.HTTPListenAddress.String())
+	user2.Register("user_2", "password")
+	user2.Login("user_2", "password")
+
+	require.Empty(t, user1.ActiveUsers().ActiveUsers)
 
 	user1.Heartbeat()
+	user2.Heartbeat()
+
+	// only contacts are listed
+	require.Empty(t, user1.ActiveUsers().ActiveUsers)
 
-	activeUsers = user1.ActiveUsers().ActiveUsers
+	user1ID, user2ID := user1.Presence().ID, user2.Presence().ID
+	require.Equal(t, contacts.StatusOutgoing, user1.RequestContact(user2ID).Status)
+	require.Equal(t, contacts.StatusAccepted, user2.AcceptContact(user1ID).Status)
+
+	activeUsers := user1.ActiveUsers().ActiveUsers
 	require.Len(t, activeUsers, 1)
-	require.Equal(t, "user_1", activeUsers[0].Username)
-	require.NotEmpty(t, activeUsers[0].ID)
+	require.Equal(t, "user_2", activeUsers[0].Username)
+	require.Equal(t, user2ID, activeUsers[0].ID)
 	require.NotEmpty(t, activeUsers[0].LastSeen)
 }
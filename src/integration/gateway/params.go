@@ -8,13 +8,19 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/faustuzas/occa/src/archiver"
 	"github.com/faustuzas/occa/src/gateway"
+	archiverintegration "github.com/faustuzas/occa/src/integration/archiver"
 	"github.com/faustuzas/occa/src/integration/containers"
+	archiverclient "github.com/faustuzas/occa/src/pkg/archiver/client"
 	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	"github.com/faustuzas/occa/src/pkg/contacts"
 	pkgdb "github.com/faustuzas/occa/src/pkg/db"
 	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
+	"github.com/faustuzas/occa/src/pkg/groups"
 	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
 	pkgnet "github.com/faustuzas/occa/src/pkg/net"
+	"github.com/faustuzas/occa/src/pkg/receipts"
 	pkgtest "github.com/faustuzas/occa/src/pkg/test"
 )
 
@@ -37,9 +43,17 @@ func DefaultParams(t *testing.T) gateway.Params {
 	authDatabase, err := db.WithTemporaryDatabase(t, "auth_users")
 	require.NoError(t, err)
 
+	chatDatabase, err := db.WithTemporaryDatabase(t, "chat")
+	require.NoError(t, err)
+
 	pubKey, privKey, err := pkgtest.GetRSAPairPaths()
 	require.NoError(t, err)
 
+	archiverParams := archiverintegration.DefaultParams(t)
+	go func() {
+		require.NoError(t, archiver.Start(archiverParams))
+	}()
+
 	return gateway.Params{
 		Configuration: gateway.Configuration{
 			HTTPListenAddress: listenAddr,
@@ -57,6 +71,31 @@ func DefaultParams(t *testing.T) gateway.Params {
 				Endpoints: etcd.Endpoints(),
 			},
 
+			Archiver: archiverclient.Configuration{
+				Address: archiverParams.HTTPListenAddress.String(),
+			},
+
+			Groups: groups.Configuration{
+				DB: pkgdb.Configuration{
+					DBType:         "mysql",
+					DataSourceName: db.DataSourceName(chatDatabase),
+				},
+			},
+
+			Receipts: receipts.Configuration{
+				DB: pkgdb.Configuration{
+					DBType:         "mysql",
+					DataSourceName: db.DataSourceName(chatDatabase),
+				},
+			},
+
+			Contacts: contacts.Configuration{
+				DB: pkgdb.Configuration{
+					DBType:         "mysql",
+					DataSourceName: db.DataSourceName(chatDatabase),
+				},
+			},
+
 			Auth: pkgauth.ValidatorConfiguration{
 				Type: pkgauth.ValidatorConfigurationJWTRSA,
 				JWTValidator: pkgauth.JWTValidatorConfiguration{
@@ -64,6 +103,11 @@ func DefaultParams(t *testing.T) gateway.Params {
 				},
 			},
 
+			ServiceAuth: pkgauth.ServiceAuthConfiguration{
+				Type:  pkgauth.ServiceAuthConfigurationToken,
+				Token: "test-service-token",
+			},
+
 			Registerer: pkgauth.RegistererConfiguration{
 				TokenIssuer: pkgauth.TokenIssuerConfiguration{
 					PrivateKeyPath: privKey,
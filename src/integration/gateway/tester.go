@@ -10,6 +10,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	gatewayhttp "github.com/faustuzas/occa/src/gateway/http"
+	"github.com/faustuzas/occa/src/gateway/services"
+	"github.com/faustuzas/occa/src/pkg/contacts"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkgtest "github.com/faustuzas/occa/src/pkg/test"
 )
 
@@ -67,6 +70,36 @@ func (g *Tester) ActiveUsers() gatewayhttp.ActiveUsersResponse {
 	return pkgtest.FromJSONBytes[gatewayhttp.ActiveUsersResponse](g.t, body)
 }
 
+// Presence returns the presence of the tester itself.
+func (g *Tester) Presence() services.ActiveUser {
+	req := g.createAuthenticatedReq(http.MethodGet, "/presence", nil)
+
+	resp, body := pkgtest.HTTPExec(g.t, req)
+	require.Equal(g.t, http.StatusOK, resp.StatusCode)
+
+	return pkgtest.FromJSONBytes[services.ActiveUser](g.t, body)
+}
+
+func (g *Tester) RequestContact(userID pkgid.ID) contacts.Contact {
+	req := g.createAuthenticatedReq(http.MethodPost, "/contacts", pkgtest.ToJSONBody(g.t, gatewayhttp.ContactRequest{
+		UserID: userID,
+	}))
+
+	resp, body := pkgtest.HTTPExec(g.t, req)
+	require.Equal(g.t, http.StatusOK, resp.StatusCode)
+
+	return pkgtest.FromJSONBytes[contacts.Contact](g.t, body)
+}
+
+func (g *Tester) AcceptContact(userID pkgid.ID) contacts.Contact {
+	req := g.createAuthenticatedReq(http.MethodPost, "/contacts/"+userID.String()+"/accept", nil)
+
+	resp, body := pkgtest.HTTPExec(g.t, req)
+	require.Equal(g.t, http.StatusOK, resp.StatusCode)
+
+	return pkgtest.FromJSONBytes[contacts.Contact](g.t, body)
+}
+
 func (g *Tester) createReq(method string, path string, body io.Reader) *http.Request {
 	req, err := http.NewRequestWithContext(g.ctx, method, "http://"+g.address+path, body)
 	require.NoError(g.t, err)
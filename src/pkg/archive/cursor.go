@@ -0,0 +1,47 @@
+package archive
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+// cursor identifies a position in the conversation. Messages are ordered by the time they
+// were sent and ties are broken by the message ID.
+type cursor struct {
+	sentAt time.Time
+	id     pkgid.ID
+}
+
+func (c cursor) encode() string {
+	raw := strconv.FormatInt(c.sentAt.UnixNano(), 10) + ":" + c.id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(str string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return cursor{}, fmt.Errorf("decoding cursor: %w", err)
+	}
+
+	nanosStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return cursor{}, fmt.Errorf("malformed cursor")
+	}
+
+	nanos, err := strconv.ParseInt(nanosStr, 10, 64)
+	if err != nil {
+		return cursor{}, fmt.Errorf("parsing cursor time: %w", err)
+	}
+
+	id, err := pkgid.Parse(idStr)
+	if err != nil {
+		return cursor{}, fmt.Errorf("parsing cursor id: %w", err)
+	}
+
+	return cursor{sentAt: time.Unix(0, nanos), id: id}, nil
+}
@@ -0,0 +1,27 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := cursor{
+		sentAt: time.Unix(0, 1700000000123456789),
+		id:     pkgid.FromString("e8eeb523-2cc9-4f24-b878-0ad97aef5c90"),
+	}
+
+	decoded, err := decodeCursor(c.encode())
+	require.NoError(t, err)
+	require.True(t, c.sentAt.Equal(decoded.sentAt))
+	require.Equal(t, c.id, decoded.id)
+}
+
+func TestCursorMalformed(t *testing.T) {
+	_, err := decodeCursor("not a cursor")
+	require.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	pkgdb "github.com/faustuzas/occa/src/pkg/db"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgslices "github.com/faustuzas/occa/src/pkg/slices"
+)
+
+var _ Store = (*DBStore)(nil)
+
+type message struct {
+	pkgdb.BaseModel
+
+	// Conversation is the same for both directions of the exchange between two users,
+	// so the whole history can be fetched with a single index lookup.
+	Conversation string    `gorm:"size:73;not null;index:idx_conversation_sent_at,priority:1"`
+	SentAt       time.Time `gorm:"not null;index:idx_conversation_sent_at,priority:2"`
+
+	SenderID    string `gorm:"size:36;not null"`
+	RecipientID string `gorm:"size:36;not null"`
+	Content     string `gorm:"type:text;not null"`
+}
+
+func (message) TableName() string {
+	return "messages"
+}
+
+func (m message) toMessage() Message {
+	return Message{
+		ID:          pkgid.FromString(m.ID),
+		SenderID:    pkgid.FromString(m.SenderID),
+		RecipientID: pkgid.FromString(m.RecipientID),
+		Content:     m.Content,
+		SentAt:      m.SentAt,
+	}
+}
+
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{
+		db: db,
+	}
+}
+
+func (s *DBStore) Store(ctx context.Context, msg Message) (Message, error) {
+	m := message{
+		Conversation: conversationKey(msg.SenderID, msg.RecipientID),
+		SentAt:       msg.SentAt.UTC(),
+		SenderID:     msg.SenderID.String(),
+		RecipientID:  msg.RecipientID.String(),
+		Content:      msg.Content,
+	}
+
+	if err := s.db.WithContext(ctx).Create(&m).Error; err != nil {
+		return Message{}, fmt.Errorf("inserting message: %w", err)
+	}
+
+	return m.toMessage(), nil
+}
+
+func (s *DBStore) History(ctx context.Context, query HistoryQuery) (HistoryPage, error) {
+	pageSize := query.PageSize()
+
+	tx := s.db.WithContext(ctx).
+		Where("conversation = ?", conversationKey(query.UserID, query.WithID)).
+		Order("sent_at DESC").
+		Order("id DESC").
+		Limit(pageSize + 1)
+
+	if query.Before != "" {
+		c, err := decodeCursor(query.Before)
+		if err != nil {
+			return HistoryPage{}, pkgerrors.BadRequest(err)
+		}
+
+		sentAt := c.sentAt.UTC()
+		tx = tx.Where("sent_at < ? OR (sent_at = ? AND id < ?)", sentAt, sentAt, c.id.String())
+	}
+
+	var messages []message
+	if err := tx.Find(&messages).Error; err != nil {
+		return HistoryPage{}, fmt.Errorf("querying messages: %w", err)
+	}
+
+	var page HistoryPage
+	if len(messages) > pageSize {
+		messages = messages[:pageSize]
+
+		last := messages[len(messages)-1]
+		page.NextCursor = cursor{sentAt: last.SentAt, id: pkgid.FromString(last.ID)}.encode()
+	}
+	page.Messages = pkgslices.Map(messages, message.toMessage)
+
+	return page, nil
+}
+
+func (s *DBStore) Message(ctx context.Context, id pkgid.ID) (Message, error) {
+	var m message
+	err := s.db.WithContext(ctx).Take(&m, "id = ?", id.String()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Message{}, fmt.Errorf("%w: %s", ErrMessageNotFound, id)
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("querying message: %w", err)
+	}
+
+	return m.toMessage(), nil
+}
+
+func (s *DBStore) Start(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(message{})
+}
+
+func (s *DBStore) Close(ctx context.Context) error {
+	if db, _ := s.db.WithContext(ctx).DB(); db != nil {
+		return db.Close()
+	}
+	return nil
+}
+
+func conversationKey(a, b pkgid.ID) string {
+	first, second := a.String(), b.String()
+	if first > second {
+		first, second = second, first
+	}
+	return first + ":" + second
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgio "github.com/faustuzas/occa/src/pkg/io"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var ErrMessageNotFound = errors.New("message not found")
+
+type Message struct {
+	ID          pkgid.ID  `json:"id"`
+	SenderID    pkgid.ID  `json:"senderId"`
+	RecipientID pkgid.ID  `json:"recipientId"`
+	Content     string    `json:"content"`
+	SentAt      time.Time `json:"sentAt"`
+}
+
+// HistoryQuery selects a page of the conversation between UserID and WithID. Messages are
+// returned from the newest to the oldest, starting right before the Before cursor.
+type HistoryQuery struct {
+	UserID pkgid.ID
+	WithID pkgid.ID
+	Before string
+	Limit  int
+}
+
+// PageSize returns the effective amount of messages the query should fetch.
+func (q HistoryQuery) PageSize() int {
+	switch {
+	case q.Limit <= 0:
+		return defaultPageSize
+	case q.Limit > maxPageSize:
+		return maxPageSize
+	default:
+		return q.Limit
+	}
+}
+
+type HistoryPage struct {
+	Messages []Message `json:"messages"`
+
+	// NextCursor points to the oldest returned message and should be passed as
+	// HistoryQuery.Before to fetch the next page. Empty when there are no more messages.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Store durably keeps messages exchanged between users.
+type Store interface {
+	pkgio.Closer
+
+	Store(ctx context.Context, msg Message) (Message, error)
+	History(ctx context.Context, query HistoryQuery) (HistoryPage, error)
+
+	// Message returns the message by its ID or ErrMessageNotFound.
+	Message(ctx context.Context, id pkgid.ID) (Message, error)
+
+	Start(ctx context.Context) error
+}
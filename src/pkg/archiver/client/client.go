@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	pkgarchive "github.com/faustuzas/occa/src/pkg/archive"
+	pkgarchiver "github.com/faustuzas/occa/src/pkg/archiver"
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+//go:generate sh -c "mockgen -package=client -destination=client_mock.go . Client"
+
+// Client talks to the archiver service.
+type Client interface {
+	Archive(ctx context.Context, msg pkgarchive.Message) (pkgarchive.Message, error)
+	History(ctx context.Context, query pkgarchive.HistoryQuery) (pkgarchive.HistoryPage, error)
+
+	// Message fails with a not found error if the message has not been archived.
+	Message(ctx context.Context, id pkgid.ID) (pkgarchive.Message, error)
+}
+
+type Configuration struct {
+	Address string `yaml:"address"`
+}
+
+// Build creates the client, headers are attached to every request to authorize it.
+func (c Configuration) Build(headers map[string]string) (Client, error) {
+	if c.Address == "" {
+		return nil, fmt.Errorf("archiver address cannot be empty")
+	}
+
+	return NewHTTPClient(c.Address, headers), nil
+}
+
+type httpClient struct {
+	c       *pkghttp.Client
+	headers map[string]string
+}
+
+func NewHTTPClient(address string, headers map[string]string) Client {
+	return &httpClient{
+		c:       pkghttp.NewClient(address),
+		headers: headers,
+	}
+}
+
+func (h *httpClient) Archive(ctx context.Context, msg pkgarchive.Message) (pkgarchive.Message, error) {
+	body, err := json.Marshal(pkgarchiver.StoreMessageRequest{
+		SenderID:    msg.SenderID,
+		RecipientID: msg.RecipientID,
+		Content:     msg.Content,
+		SentAt:      msg.SentAt,
+	})
+	if err != nil {
+		return pkgarchive.Message{}, fmt.Errorf("marshalling request: %w", err)
+	}
+
+	resp, err := h.c.PostWithHeaders(ctx, "/messages", body, h.headers)
+	if err != nil {
+		return pkgarchive.Message{}, fmt.Errorf("sending HTTP request: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return pkgarchive.Message{}, fmt.Errorf("received non-200 status code: %v", resp.StatusCode)
+	}
+
+	var stored pkgarchive.Message
+	if err = json.Unmarshal(resp.Body, &stored); err != nil {
+		return pkgarchive.Message{}, fmt.Errorf("unmarshalling response: %w", err)
+	}
+
+	return stored, nil
+}
+
+func (h *httpClient) History(ctx context.Context, query pkgarchive.HistoryQuery) (pkgarchive.HistoryPage, error) {
+	values := url.Values{}
+	values.Set("user", query.UserID.String())
+	values.Set("with", query.WithID.String())
+	if query.Before != "" {
+		values.Set("before", query.Before)
+	}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	resp, err := h.c.GetWithHeaders(ctx, "/history?"+values.Encode(), h.headers)
+	if err != nil {
+		return pkgarchive.HistoryPage{}, fmt.Errorf("sending HTTP request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return pkgarchive.HistoryPage{}, pkgerrors.BadRequest(fmt.Errorf("archiver rejected the query: %s", resp.Body))
+	}
+
+	if resp.StatusCode != 200 {
+		return pkgarchive.HistoryPage{}, fmt.Errorf("received non-200 status code: %v", resp.StatusCode)
+	}
+
+	var page pkgarchive.HistoryPage
+	if err = json.Unmarshal(resp.Body, &page); err != nil {
+		return pkgarchive.HistoryPage{}, fmt.Errorf("unmarshalling response: %w", err)
+	}
+
+	return page, nil
+}
+
+func (h *httpClient) Message(ctx context.Context, id pkgid.ID) (pkgarchive.Message, error) {
+	resp, err := h.c.GetWithHeaders(ctx, "/messages/"+id.String(), h.headers)
+	if err != nil {
+		return pkgarchive.Message{}, fmt.Errorf("sending HTTP request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return pkgarchive.Message{}, pkgerrors.NotFound(fmt.Errorf("%w: %s", pkgarchive.ErrMessageNotFound, id))
+	}
+
+	if resp.StatusCode != 200 {
+		return pkgarchive.Message{}, fmt.Errorf("received non-200 status code: %v", resp.StatusCode)
+	}
+
+	var msg pkgarchive.Message
+	if err = json.Unmarshal(resp.Body, &msg); err != nil {
+		return pkgarchive.Message{}, fmt.Errorf("unmarshalling response: %w", err)
+	}
+
+	return msg, nil
+}
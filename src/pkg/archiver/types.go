@@ -0,0 +1,17 @@
+package archiver
+
+import (
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+type StoreMessageRequest struct {
+	SenderID    pkgid.ID `json:"senderId"`
+	RecipientID pkgid.ID `json:"recipientId"`
+	Content     string   `json:"content"`
+
+	// SentAt is the time the message was accepted by the system. Defaults to the time of
+	// archiving if not provided.
+	SentAt time.Time `json:"sentAt,omitempty"`
+}
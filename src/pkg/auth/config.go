@@ -40,6 +40,22 @@ func (c ValidatorConfiguration) BuildHTTPMiddleware(inst pkginstrument.Instrumen
 	}
 }
 
+func (c ValidatorConfiguration) BuildHTTPStreamMiddleware(inst pkginstrument.Instrumentation) (httpmiddleware.Middleware, error) {
+	switch c.Type {
+	case ValidatorConfigurationNoop:
+		return HTTPNoopMiddleware(), nil
+	case ValidatorConfigurationJWTRSA:
+		validator, err := c.JWTValidator.Build()
+		if err != nil {
+			return nil, fmt.Errorf("building JWT RSA validator: %w", err)
+		}
+
+		return HTTPStreamTokenAuthorizationMiddleware(inst, validator), nil
+	default:
+		return nil, fmt.Errorf("auth not configured")
+	}
+}
+
 func (c ValidatorConfiguration) BuildGRPCStreamInterceptor(inst pkginstrument.Instrumentation) (grpc.StreamServerInterceptor, error) {
 	switch c.Type {
 	case ValidatorConfigurationNoop:
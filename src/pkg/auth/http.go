@@ -12,10 +12,29 @@ import (
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 )
 
+const (
+	accessTokenQueryParam = "access_token"
+)
+
 func HTTPTokenAuthorizationMiddleware(i pkginstrument.Instrumentation, validator TokenValidator) httpmiddleware.Middleware {
+	return httpTokenAuthorizationMiddleware(i, validator, false)
+}
+
+// HTTPStreamTokenAuthorizationMiddleware authorizes long-lived streaming requests. Browsers are not able
+// to set headers when opening WebSocket or EventSource connections, so apart from the Authorization header
+// the token is also accepted as an access_token query parameter.
+func HTTPStreamTokenAuthorizationMiddleware(i pkginstrument.Instrumentation, validator TokenValidator) httpmiddleware.Middleware {
+	return httpTokenAuthorizationMiddleware(i, validator, true)
+}
+
+func httpTokenAuthorizationMiddleware(i pkginstrument.Instrumentation, validator TokenValidator, allowQueryToken bool) httpmiddleware.Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := r.Header.Get("Authorization")
+			if token == "" && allowQueryToken {
+				token = r.URL.Query().Get(accessTokenQueryParam)
+			}
+
 			if token == "" {
 				pkghttp.RespondWithJSONError(i.Logger, w, pkgerrors.ErrUnauthorized(fmt.Errorf("missing Authorization header")))
 				return
@@ -58,3 +58,41 @@ func TestHTTPMiddleware_Success(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 	require.Equal(t, principal, actualPrincipal)
 }
+
+func TestHTTPStreamMiddleware_QueryToken(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		validatorMock = NewMockTokenValidator(ctrl)
+
+		token     = "a-token"
+		principal = Principal{ID: pkgid.NewID(), UserName: "mr. test"}
+	)
+
+	validatorMock.EXPECT().Validate(gomock.Any(), token).Return(principal, nil)
+	authMiddleware := HTTPStreamTokenAuthorizationMiddleware(pkgtest.Instrumentation, validatorMock)
+
+	var actualPrincipal Principal
+	srv := httptest.NewServer(authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPrincipal = PrincipalFromContext(r.Context())
+	})))
+	defer srv.Close()
+
+	status, _ := pkgtest.HTTPGetBody(t, srv.URL, "/?access_token="+token)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, principal, actualPrincipal)
+}
+
+func TestHTTPMiddleware_QueryTokenIgnored(t *testing.T) {
+	var (
+		ctrl          = gomock.NewController(t)
+		validatorMock = NewMockTokenValidator(ctrl)
+	)
+
+	authMiddleware := HTTPTokenAuthorizationMiddleware(pkgtest.Instrumentation, validatorMock)
+
+	srv := httptest.NewServer(authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer srv.Close()
+
+	status, _ := pkgtest.HTTPGetBody(t, srv.URL, "/?access_token=a-token")
+	require.Equal(t, http.StatusUnauthorized, status)
+}
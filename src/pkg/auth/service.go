@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	pkgerrors "github.com/faustuzas/occa/src/pkg/errors"
+	pkggrpc "github.com/faustuzas/occa/src/pkg/grpc"
+	httpmiddleware "github.com/faustuzas/occa/src/pkg/http/middleware"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+)
+
+type ServiceAuthConfigurationType string
+
+const (
+	ServiceAuthConfigurationNoop  ServiceAuthConfigurationType = "noop"
+	ServiceAuthConfigurationToken ServiceAuthConfigurationType = "sharedToken"
+)
+
+// ServiceAuthConfiguration authenticates calls between internal services. The shared token
+// is a credential of the services only, end-user tokens are never accepted in its place.
+type ServiceAuthConfiguration struct {
+	Type ServiceAuthConfigurationType `yaml:"type"`
+
+	// Token is the shared secret, TokenPath points to a file holding it instead.
+	Token     string `yaml:"token"`
+	TokenPath string `yaml:"tokenPath"`
+}
+
+func (c ServiceAuthConfiguration) token() (string, error) {
+	if c.TokenPath == "" {
+		if c.Token == "" {
+			return "", fmt.Errorf("service token not configured")
+		}
+		return c.Token, nil
+	}
+
+	token, err := os.ReadFile(c.TokenPath)
+	if err != nil {
+		return "", fmt.Errorf("reading service token: %w", err)
+	}
+	if len(strings.TrimSpace(string(token))) == 0 {
+		return "", fmt.Errorf("service token file is empty")
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func (c ServiceAuthConfiguration) BuildGRPCUnaryInterceptor(inst pkginstrument.Instrumentation) (grpc.UnaryServerInterceptor, error) {
+	switch c.Type {
+	case ServiceAuthConfigurationNoop:
+		return GRPCUnaryNoopInterceptor(), nil
+	case ServiceAuthConfigurationToken:
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+
+		return GRPCUnaryServiceAuthorizationInterceptor(inst, NewServiceTokenValidator(token)), nil
+	default:
+		return nil, fmt.Errorf("service auth not configured")
+	}
+}
+
+// BuildHTTPMiddleware authorizes internal endpoints, e.g. administrative ones, with the service token.
+func (c ServiceAuthConfiguration) BuildHTTPMiddleware(inst pkginstrument.Instrumentation) (httpmiddleware.Middleware, error) {
+	switch c.Type {
+	case ServiceAuthConfigurationNoop:
+		return HTTPNoopMiddleware(), nil
+	case ServiceAuthConfigurationToken:
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+
+		return HTTPTokenAuthorizationMiddleware(inst, NewServiceTokenValidator(token)), nil
+	default:
+		return nil, fmt.Errorf("service auth not configured")
+	}
+}
+
+// BuildGRPCCredentials returns credentials attached to calls made to other services.
+func (c ServiceAuthConfiguration) BuildGRPCCredentials() (credentials.PerRPCCredentials, error) {
+	switch c.Type {
+	case ServiceAuthConfigurationNoop:
+		return serviceCredentials{}, nil
+	case ServiceAuthConfigurationToken:
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+
+		return serviceCredentials{token: token}, nil
+	default:
+		return nil, fmt.Errorf("service auth not configured")
+	}
+}
+
+// BuildHTTPHeaders returns headers attached to HTTP requests made to other services.
+func (c ServiceAuthConfiguration) BuildHTTPHeaders() (map[string]string, error) {
+	switch c.Type {
+	case ServiceAuthConfigurationNoop:
+		return nil, nil
+	case ServiceAuthConfigurationToken:
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]string{"Authorization": "Bearer " + token}, nil
+	default:
+		return nil, fmt.Errorf("service auth not configured")
+	}
+}
+
+// servicePrincipal is the principal of calls authenticated with the service token.
+var servicePrincipal = Principal{UserName: "occa-service"}
+
+var _ TokenValidator = serviceTokenValidator{}
+
+type serviceTokenValidator struct {
+	token []byte
+}
+
+func NewServiceTokenValidator(token string) TokenValidator {
+	return serviceTokenValidator{token: []byte(token)}
+}
+
+func (v serviceTokenValidator) Validate(_ context.Context, token string) (Principal, error) {
+	if subtle.ConstantTimeCompare(v.token, []byte(token)) != 1 {
+		return Principal{}, fmt.Errorf("invalid service token")
+	}
+	return servicePrincipal, nil
+}
+
+func GRPCUnaryServiceAuthorizationInterceptor(_ pkginstrument.Instrumentation, validator TokenValidator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		principal, err := authorizeRequest(ctx, validator)
+		if err != nil {
+			return nil, pkggrpc.Error(pkgerrors.ErrUnauthorized(err))
+		}
+
+		return handler(ContextWithPrincipal(ctx, principal), req)
+	}
+}
+
+func GRPCUnaryNoopInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(ctx, req)
+	}
+}
+
+type serviceCredentials struct {
+	token string
+}
+
+func (c serviceCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	if c.token == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+// RequireTransportSecurity is false as services talk over the internal network.
+func (c serviceCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,261 @@
+package contacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	pkgdb "github.com/faustuzas/occa/src/pkg/db"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgslices "github.com/faustuzas/occa/src/pkg/slices"
+)
+
+var _ Store = (*DBStore)(nil)
+
+type contact struct {
+	pkgdb.BaseModel
+
+	// PairKey is the same no matter which of the users has sent the request,
+	// so there cannot be requests in both directions
+	PairKey     string `gorm:"size:73;not null;uniqueIndex"`
+	RequesterID string `gorm:"size:36;not null;index"`
+	AddresseeID string `gorm:"size:36;not null;index"`
+	AcceptedAt  *time.Time
+}
+
+func (contact) TableName() string {
+	return "contacts"
+}
+
+// toContact returns the contact as seen by the user.
+func (c contact) toContact(userID pkgid.ID) Contact {
+	result := Contact{
+		UserID: pkgid.FromString(c.AddresseeID),
+		Status: StatusOutgoing,
+		Since:  c.CreatedAt,
+	}
+	if c.AddresseeID == userID.String() {
+		result.UserID = pkgid.FromString(c.RequesterID)
+		result.Status = StatusIncoming
+	}
+
+	if c.AcceptedAt != nil {
+		result.Status = StatusAccepted
+		result.Since = *c.AcceptedAt
+	}
+	return result
+}
+
+type block struct {
+	pkgdb.BaseModel
+
+	BlockerID string `gorm:"size:36;not null;uniqueIndex:idx_blocker_blocked,priority:1"`
+	BlockedID string `gorm:"size:36;not null;uniqueIndex:idx_blocker_blocked,priority:2"`
+}
+
+func (block) TableName() string {
+	return "blocks"
+}
+
+func (b block) toBlock() Block {
+	return Block{
+		UserID: pkgid.FromString(b.BlockedID),
+		Since:  b.CreatedAt,
+	}
+}
+
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{
+		db: db,
+	}
+}
+
+func (s *DBStore) Request(ctx context.Context, requesterID, addresseeID pkgid.ID) (Contact, error) {
+	if _, err := s.Get(ctx, requesterID, addresseeID); err == nil {
+		return Contact{}, fmt.Errorf("%w: %s", ErrAlreadyContact, addresseeID)
+	} else if !errors.Is(err, ErrContactNotFound) {
+		return Contact{}, err
+	}
+
+	c := contact{
+		PairKey:     pairKey(requesterID, addresseeID),
+		RequesterID: requesterID.String(),
+		AddresseeID: addresseeID.String(),
+	}
+	if err := s.db.WithContext(ctx).Create(&c).Error; err != nil {
+		// the other user might have sent a request at the same time
+		if _, getErr := s.Get(ctx, requesterID, addresseeID); getErr == nil {
+			return Contact{}, fmt.Errorf("%w: %s", ErrAlreadyContact, addresseeID)
+		}
+		return Contact{}, fmt.Errorf("inserting contact request: %w", err)
+	}
+
+	return c.toContact(requesterID), nil
+}
+
+func (s *DBStore) Accept(ctx context.Context, requesterID, addresseeID pkgid.ID) (Contact, error) {
+	result := s.db.WithContext(ctx).Model(&contact{}).
+		Where("requester_id = ? AND addressee_id = ? AND accepted_at IS NULL", requesterID.String(), addresseeID.String()).
+		Update("accepted_at", time.Now().UTC())
+	if result.Error != nil {
+		return Contact{}, fmt.Errorf("accepting contact request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return Contact{}, fmt.Errorf("%w: no pending request from %s", ErrContactNotFound, requesterID)
+	}
+
+	return s.Get(ctx, addresseeID, requesterID)
+}
+
+func (s *DBStore) Remove(ctx context.Context, userID, otherID pkgid.ID) error {
+	result := s.db.WithContext(ctx).
+		Where("pair_key = ?", pairKey(userID, otherID)).
+		Delete(&contact{})
+	if result.Error != nil {
+		return fmt.Errorf("deleting contact: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrContactNotFound, otherID)
+	}
+	return nil
+}
+
+func (s *DBStore) Get(ctx context.Context, userID, otherID pkgid.ID) (Contact, error) {
+	var c contact
+	err := s.db.WithContext(ctx).Take(&c, "pair_key = ?", pairKey(userID, otherID)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Contact{}, fmt.Errorf("%w: %s", ErrContactNotFound, otherID)
+	}
+	if err != nil {
+		return Contact{}, fmt.Errorf("querying contact: %w", err)
+	}
+
+	return c.toContact(userID), nil
+}
+
+func (s *DBStore) Contacts(ctx context.Context, userID pkgid.ID) ([]Contact, error) {
+	var contacts []contact
+	err := s.db.WithContext(ctx).
+		Where("requester_id = ? OR addressee_id = ?", userID.String(), userID.String()).
+		Order("created_at").
+		Find(&contacts).Error
+	if err != nil {
+		return nil, fmt.Errorf("querying contacts: %w", err)
+	}
+
+	return pkgslices.Map(contacts, func(c contact) Contact {
+		return c.toContact(userID)
+	}), nil
+}
+
+func (s *DBStore) Block(ctx context.Context, blockerID, blockedID pkgid.ID) error {
+	blocked, err := s.isBlockedBy(ctx, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return nil
+	}
+
+	err = s.db.WithContext(ctx).Create(&block{
+		BlockerID: blockerID.String(),
+		BlockedID: blockedID.String(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("inserting block: %w", err)
+	}
+	return nil
+}
+
+func (s *DBStore) Unblock(ctx context.Context, blockerID, blockedID pkgid.ID) error {
+	result := s.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID.String(), blockedID.String()).
+		Delete(&block{})
+	if result.Error != nil {
+		return fmt.Errorf("deleting block: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrNotBlocked, blockedID)
+	}
+	return nil
+}
+
+func (s *DBStore) Blocked(ctx context.Context, blockerID pkgid.ID) ([]Block, error) {
+	var blocks []block
+	err := s.db.WithContext(ctx).
+		Where("blocker_id = ?", blockerID.String()).
+		Order("created_at").
+		Find(&blocks).Error
+	if err != nil {
+		return nil, fmt.Errorf("querying blocks: %w", err)
+	}
+
+	return pkgslices.Map(blocks, block.toBlock), nil
+}
+
+func (s *DBStore) IsBlocked(ctx context.Context, userID, otherID pkgid.ID) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+			userID.String(), otherID.String(), otherID.String(), userID.String()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("querying blocks: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *DBStore) IsBlockedWithAny(ctx context.Context, userID pkgid.ID, otherIDs []pkgid.ID) (bool, error) {
+	if len(otherIDs) == 0 {
+		return false, nil
+	}
+
+	others := pkgslices.Map(otherIDs, pkgid.ID.String)
+
+	var count int64
+	err := s.db.WithContext(ctx).Model(&block{}).
+		Where("(blocker_id = ? AND blocked_id IN ?) OR (blocker_id IN ? AND blocked_id = ?)",
+			userID.String(), others, others, userID.String()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("querying blocks: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *DBStore) isBlockedBy(ctx context.Context, blockerID, blockedID pkgid.ID) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&block{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID.String(), blockedID.String()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("querying blocks: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *DBStore) Start(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(contact{}, block{})
+}
+
+func (s *DBStore) Close(ctx context.Context) error {
+	if db, _ := s.db.WithContext(ctx).DB(); db != nil {
+		return db.Close()
+	}
+	return nil
+}
+
+func pairKey(userID, otherID pkgid.ID) string {
+	first, second := userID.String(), otherID.String()
+	if first > second {
+		first, second = second, first
+	}
+	return first + ":" + second
+}
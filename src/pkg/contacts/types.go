@@ -0,0 +1,77 @@
+package contacts
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgio "github.com/faustuzas/occa/src/pkg/io"
+)
+
+//go:generate sh -c "mockgen -package=contacts -destination=contacts_mock.go . Store"
+
+var (
+	ErrContactNotFound = errors.New("contact not found")
+	ErrAlreadyContact  = errors.New("users are already contacts or have a pending request")
+	ErrNotBlocked      = errors.New("user is not blocked")
+)
+
+// Status is the relation between the users as seen by one of them.
+type Status string
+
+const (
+	StatusAccepted Status = "accepted"
+	// StatusIncoming requests wait for the user to accept them.
+	StatusIncoming Status = "incoming"
+	// StatusOutgoing requests wait for the other user to accept them.
+	StatusOutgoing Status = "outgoing"
+)
+
+type Contact struct {
+	UserID pkgid.ID `json:"userId"`
+	Status Status   `json:"status"`
+	// Since is when the request was accepted or, for pending ones, sent.
+	Since time.Time `json:"since"`
+}
+
+type Block struct {
+	UserID pkgid.ID  `json:"userId"`
+	Since  time.Time `json:"since"`
+}
+
+// Store keeps contacts of users and who they have blocked. There is at most one request or
+// contact between two users. It does not check permissions of the callers.
+type Store interface {
+	pkgio.Closer
+
+	// Request records the request of the requester to become a contact of the addressee.
+	Request(ctx context.Context, requesterID, addresseeID pkgid.ID) (Contact, error)
+
+	// Accept accepts the pending request the requester has sent to the addressee.
+	Accept(ctx context.Context, requesterID, addresseeID pkgid.ID) (Contact, error)
+
+	// Remove removes the contact or the pending request between the users, whichever of them sent it.
+	Remove(ctx context.Context, userID, otherID pkgid.ID) error
+
+	// Get returns the relation of the user with the other user.
+	Get(ctx context.Context, userID, otherID pkgid.ID) (Contact, error)
+
+	// Contacts returns the contacts of the user together with pending requests.
+	Contacts(ctx context.Context, userID pkgid.ID) ([]Contact, error)
+
+	// Block blocks the user, blocking an already blocked user does nothing.
+	Block(ctx context.Context, blockerID, blockedID pkgid.ID) error
+	Unblock(ctx context.Context, blockerID, blockedID pkgid.ID) error
+
+	// Blocked returns the users the blocker has blocked.
+	Blocked(ctx context.Context, blockerID pkgid.ID) ([]Block, error)
+
+	// IsBlocked tells whether either of the users has blocked the other.
+	IsBlocked(ctx context.Context, userID, otherID pkgid.ID) (bool, error)
+
+	// IsBlockedWithAny tells whether the user and any of the others have blocked one another.
+	IsBlockedWithAny(ctx context.Context, userID pkgid.ID, otherIDs []pkgid.ID) (bool, error)
+
+	Start(ctx context.Context) error
+}
@@ -8,6 +8,8 @@ const (
 	TypeUnauthorized ErrorType = iota + 1
 	TypeBadRequest
 	TypeInternalServer
+	TypeNotFound
+	TypeForbidden
 )
 
 func (t ErrorType) String() string {
@@ -18,6 +20,10 @@ func (t ErrorType) String() string {
 		return "bad_request"
 	case TypeInternalServer:
 		return "internal"
+	case TypeNotFound:
+		return "not_found"
+	case TypeForbidden:
+		return "forbidden"
 	}
 	panic(fmt.Sprintf("unrecognized error: %d", t))
 }
@@ -52,3 +58,17 @@ func BadRequest(cause error) GenericErr {
 		cause: cause,
 	}
 }
+
+func NotFound(cause error) GenericErr {
+	return GenericErr{
+		type_: TypeNotFound,
+		cause: cause,
+	}
+}
+
+func Forbidden(cause error) GenericErr {
+	return GenericErr{
+		type_: TypeForbidden,
+		cause: cause,
+	}
+}
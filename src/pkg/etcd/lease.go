@@ -2,6 +2,7 @@ package etcd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -12,14 +13,32 @@ import (
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 )
 
+// ErrFenced is returned when a key cannot be put because its guard does not hold anymore,
+// i.e. the key has been taken over by someone else.
+var ErrFenced = errors.New("key is fenced off")
+
+const (
+	minReacquireBackoff = 200 * time.Millisecond
+	maxReacquireBackoff = 5 * time.Second
+)
+
+// LeasedClient puts keys attached to a lease which is kept alive for as long as the client
+// runs. If the lease is lost, e.g. because etcd was unreachable for longer than its TTL, a new
+// lease is granted and all the keys are put again. The loss is considered final only if that
+// does not succeed within the grace period.
 type LeasedClient struct {
 	client *clientv3.Client
 	lease  clientv3.Lease
 
-	mu       sync.Mutex
-	leaseTTL time.Duration
-	leaseID  clientv3.LeaseID
-	leaseOK  bool
+	mu          sync.Mutex
+	leaseTTL    time.Duration
+	gracePeriod time.Duration
+	leaseID     clientv3.LeaseID
+	leaseOK     bool
+	// keys are all the keys put by the client with their latest values
+	keys map[string]string
+	// guards are conditions which have to hold for a key to be put again
+	guards map[string][]clientv3.Cmp
 
 	leaseLostCh chan struct{}
 	closeCh     chan struct{}
@@ -27,12 +46,15 @@ type LeasedClient struct {
 	i pkginstrument.Instrumentation
 }
 
-func NewLeasedClient(i pkginstrument.Instrumentation, client *clientv3.Client, leaseTTL time.Duration) *LeasedClient {
+func NewLeasedClient(i pkginstrument.Instrumentation, client *clientv3.Client, leaseTTL, gracePeriod time.Duration) *LeasedClient {
 	return &LeasedClient{
 		client: client,
 		lease:  clientv3.NewLease(client),
 
 		leaseTTL:    leaseTTL,
+		gracePeriod: gracePeriod,
+		keys:        map[string]string{},
+		guards:      map[string][]clientv3.Cmp{},
 		leaseLostCh: make(chan struct{}),
 		closeCh:     make(chan struct{}),
 
@@ -41,28 +63,47 @@ func NewLeasedClient(i pkginstrument.Instrumentation, client *clientv3.Client, l
 }
 
 // Start initializes a lease and tries to keep it alive indefinitely. To get a notification
-// when the lease is lost, wait on channel returned by LeaseLostC.
+// when the lease is lost for good, wait on channel returned by LeaseLostC.
 func (c *LeasedClient) Start(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	keepAliveCh, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		c.keepAlive(keepAliveCh)
+	}()
+
+	return nil
+}
 
+// acquire grants a new lease, attaches all the owned keys to it and starts keeping it alive.
+func (c *LeasedClient) acquire(ctx context.Context) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
 	resp, err := c.lease.Grant(ctx, int64(c.leaseTTL.Seconds()))
 	if err != nil {
-		return fmt.Errorf("granting lease: %w", err)
+		return nil, fmt.Errorf("granting lease: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range c.keys {
+		if err = c.guardedPut(ctx, resp.ID, key, value); err != nil {
+			c.revokeQuietly(resp.ID)
+			return nil, fmt.Errorf("putting key %s: %w", key, err)
+		}
 	}
-	c.leaseID = resp.ID
 
-	keepAliveCh, err := c.lease.KeepAlive(ctx, c.leaseID)
+	// the keep-alive has to outlive the context of the acquisition
+	keepAliveCh, err := c.lease.KeepAlive(context.Background(), resp.ID)
 	if err != nil {
-		return fmt.Errorf("keeping lease alive: %w", err)
+		c.revokeQuietly(resp.ID)
+		return nil, fmt.Errorf("keeping lease alive: %w", err)
 	}
 
+	c.leaseID = resp.ID
 	c.leaseOK = true
-	go func() {
-		c.keepAlive(keepAliveCh)
-	}()
-
-	return nil
+	return keepAliveCh, nil
 }
 
 func (c *LeasedClient) LeaseLostC() <-chan struct{} {
@@ -73,35 +114,102 @@ func (c *LeasedClient) keepAlive(ch <-chan *clientv3.LeaseKeepAliveResponse) {
 	for {
 		select {
 		case <-c.closeCh:
+			c.release()
+			return
 		case _, ok := <-ch:
 			if ok {
 				continue
 			}
 		}
 
-		if err := c.invalidateLease(context.Background()); err != nil {
-			c.i.Logger.Error("failed revoking lease", zap.Error(err))
+		c.i.Logger.Warn("lost etcd lease, trying to re-acquire it", zap.Duration("gracePeriod", c.gracePeriod))
+
+		newCh, ok := c.reacquire()
+		if !ok {
+			return
 		}
-		return
+		ch = newCh
 	}
 }
 
-func (c *LeasedClient) invalidateLease(ctx context.Context) error {
+// reacquire tries to get a new lease until the grace period runs out. Returns false if
+// the lease is lost for good or the client got closed meanwhile.
+func (c *LeasedClient) reacquire() (<-chan *clientv3.LeaseKeepAliveResponse, bool) {
 	c.mu.Lock()
-	if !c.leaseOK {
-		c.mu.Unlock()
-		return fmt.Errorf("invalidating lease which is not valid")
+	c.leaseOK = false
+	previous := c.leaseID
+	c.mu.Unlock()
+
+	var (
+		deadline = time.Now().Add(c.gracePeriod)
+		backoff  = minReacquireBackoff
+	)
+	for {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		ch, err := c.acquire(ctx)
+		cancel()
+		if err == nil {
+			// keys are attached to the new lease, so the old one, if it is still around, owns nothing
+			c.revokeQuietly(previous)
+			c.i.Logger.Info("re-acquired etcd lease")
+			return ch, true
+		}
+
+		if errors.Is(err, ErrFenced) {
+			c.i.Logger.Error("keys got taken over while re-acquiring etcd lease", zap.Error(err))
+			close(c.leaseLostCh)
+			return nil, false
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			c.i.Logger.Error("failed to re-acquire etcd lease within the grace period", zap.Error(err))
+			close(c.leaseLostCh)
+			return nil, false
+		}
+
+		c.i.Logger.Warn("failed to re-acquire etcd lease, retrying", zap.Duration("backoff", backoff), zap.Error(err))
+
+		timer := time.NewTimer(min(backoff, remaining))
+		select {
+		case <-timer.C:
+		case <-c.closeCh:
+			timer.Stop()
+			close(c.leaseLostCh)
+			return nil, false
+		}
+
+		if backoff *= 2; backoff > maxReacquireBackoff {
+			backoff = maxReacquireBackoff
+		}
 	}
+}
+
+// release revokes the lease, so the keys disappear right away instead of after the TTL.
+func (c *LeasedClient) release() {
+	defer close(c.leaseLostCh)
 
+	c.mu.Lock()
+	ok, leaseID := c.leaseOK, c.leaseID
 	c.leaseOK = false
 	c.mu.Unlock()
 
-	if _, err := c.lease.Revoke(ctx, c.leaseID); err != nil {
-		return fmt.Errorf("revoking lease: %w", err)
+	if !ok {
+		return
 	}
-	close(c.leaseLostCh)
 
-	return nil
+	if _, err := c.lease.Revoke(context.Background(), leaseID); err != nil {
+		c.i.Logger.Error("failed revoking lease", zap.Error(err))
+	}
+}
+
+func (c *LeasedClient) revokeQuietly(leaseID clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.leaseTTL)
+	defer cancel()
+
+	if _, err := c.lease.Revoke(ctx, leaseID); err != nil {
+		c.i.Logger.Debug("failed revoking lease", zap.Error(err))
+	}
 }
 
 func (c *LeasedClient) Close(ctx context.Context) error {
@@ -115,9 +223,69 @@ func (c *LeasedClient) Close(ctx context.Context) error {
 	}
 }
 
+// Put puts the key attached to the lease. The key is remembered, so it is put again
+// with the latest value whenever the lease has to be re-acquired. If the key has a guard
+// which does not hold, ErrFenced is returned.
 func (c *LeasedClient) Put(ctx context.Context, key string, value []byte) error {
-	if _, err := c.client.Put(ctx, key, string(value), clientv3.WithLease(c.leaseID)); err != nil {
+	// holding the lock keeps the key from being attached to a lease which is being replaced
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keys[key] = string(value)
+	if !c.leaseOK {
+		return fmt.Errorf("lease is being re-acquired, the key will be put with the new one")
+	}
+
+	return c.guardedPut(ctx, c.leaseID, key, string(value))
+}
+
+// PutIf puts the key attached to the lease together with the additional operations
+// in a single transaction if all the comparisons succeed. Returns whether they did.
+// The key is remembered only if it has been put.
+func (c *LeasedClient) PutIf(ctx context.Context, key string, value []byte, cmps []clientv3.Cmp, ops ...clientv3.Op) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.leaseOK {
+		return false, fmt.Errorf("lease is not acquired")
+	}
+
+	ops = append([]clientv3.Op{clientv3.OpPut(key, string(value), clientv3.WithLease(c.leaseID))}, ops...)
+	resp, err := c.client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return false, err
+	}
+	if !resp.Succeeded {
+		return false, nil
+	}
+
+	c.keys[key] = string(value)
+	return true, nil
+}
+
+// Guard sets the conditions which have to hold for every later put of the key,
+// including the ones done when the lease is re-acquired.
+func (c *LeasedClient) Guard(key string, cmps ...clientv3.Cmp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.guards[key] = cmps
+}
+
+// guardedPut has to be called holding the lock.
+func (c *LeasedClient) guardedPut(ctx context.Context, leaseID clientv3.LeaseID, key, value string) error {
+	guards, ok := c.guards[key]
+	if !ok {
+		_, err := c.client.Put(ctx, key, value, clientv3.WithLease(leaseID))
 		return err
 	}
+
+	resp, err := c.client.Txn(ctx).If(guards...).Then(clientv3.OpPut(key, value, clientv3.WithLease(leaseID))).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrFenced
+	}
 	return nil
 }
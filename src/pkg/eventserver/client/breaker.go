@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrCircuitOpen is returned without calling the server while it is considered down.
+	ErrCircuitOpen = errors.New("circuit breaker of the server is open")
+
+	// ErrConcurrencyLimit is returned when too many calls to the server are in flight.
+	ErrConcurrencyLimit = errors.New("too many concurrent requests to the server")
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	// breakerHalfOpen lets a single probe through to find out whether the server has recovered
+	breakerHalfOpen
+)
+
+// breaker opens after consecutive failures of the server and fails calls fast until
+// the open duration passes, then a single probe decides whether it closes again.
+type breaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(cfg CircuitBreakerConfiguration, now func() time.Time) *breaker {
+	return &breaker{
+		failureThreshold: cfg.FailureThreshold,
+		openDuration:     cfg.OpenDuration,
+		now:              now,
+	}
+}
+
+// allow reports whether a call can be made, every allowed call has to be followed by done.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.state = breakerHalfOpen
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.probing = false
+	}
+	if isCancellation(err) {
+		// the call tells nothing about the server, a probe is made again by the next call
+		return
+	}
+
+	failed := isServerFailure(err)
+	if b.state == breakerHalfOpen {
+		if failed {
+			b.state, b.openedAt = breakerOpen, b.now()
+		} else {
+			b.state, b.failures = breakerClosed, 0
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	if b.failures++; b.failures >= b.failureThreshold && b.state == breakerClosed {
+		b.state, b.openedAt = breakerOpen, b.now()
+	}
+}
+
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state != breakerClosed
+}
+
+// isServerFailure tells whether the error means the server is unhealthy, errors about
+// the request itself, e.g. an offline recipient, do not count.
+func isServerFailure(err error) bool {
+	if err == nil || isCancellation(err) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return errors.Is(err, context.DeadlineExceeded)
+	}
+}
+
+// isCancellation tells whether the call has been cancelled by the caller, so nothing is known about the server.
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	var (
+		now = time.Now()
+		b   = newBreaker(CircuitBreakerConfiguration{FailureThreshold: 3, OpenDuration: time.Second}, func() time.Time {
+			return now
+		})
+		unavailable = status.Error(codes.Unavailable, "connection refused")
+	)
+
+	// failures about the request itself do not count
+	for i := 0; i < 5; i++ {
+		require.True(t, b.allow())
+		b.done(status.Error(codes.Internal, "recipient is not connected"))
+	}
+
+	for i := 0; i < 3; i++ {
+		require.True(t, b.allow())
+		b.done(unavailable)
+	}
+	require.False(t, b.allow())
+
+	// a single probe is let through after the open duration
+	now = now.Add(time.Second)
+	require.True(t, b.allow())
+	require.False(t, b.allow())
+
+	// a failed probe opens the circuit again
+	b.done(unavailable)
+	require.False(t, b.allow())
+
+	now = now.Add(time.Second)
+	require.True(t, b.allow())
+	b.done(nil)
+	require.True(t, b.allow())
+	require.True(t, b.allow())
+}
+
+func TestBreaker_CancelledProbeIsRetried(t *testing.T) {
+	var (
+		now = time.Now()
+		b   = newBreaker(CircuitBreakerConfiguration{FailureThreshold: 1, OpenDuration: time.Second}, func() time.Time {
+			return now
+		})
+	)
+
+	require.True(t, b.allow())
+	b.done(context.DeadlineExceeded)
+	require.True(t, b.open())
+
+	now = now.Add(time.Second)
+	require.True(t, b.allow())
+	b.done(errors.Join(errors.New("delivering event"), context.Canceled))
+
+	require.True(t, b.open())
+	require.True(t, b.allow())
+}
@@ -1,35 +1,128 @@
 package client
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
-	"github.com/gogo/protobuf/jsonpb"
+	multierr "github.com/hashicorp/go-multierror"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
+	"github.com/faustuzas/occa/src/eventserver/generated/proto/eventserverpb"
 	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
 	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
-	pkghttp "github.com/faustuzas/occa/src/pkg/http"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 	pkgio "github.com/faustuzas/occa/src/pkg/io"
 )
 
+// ErrRecipientNotConnected is returned when the recipient is not connected to the server,
+// e.g. because it has moved to another one since its servers were resolved.
+var ErrRecipientNotConnected = errors.New("recipient is not connected to the server")
+
+const (
+	defaultMaxConcurrentRequests = 100
+	defaultFailureThreshold      = 5
+	defaultOpenDuration          = 10 * time.Second
+)
+
+// Configuration controls how calls to a single event server are limited.
+type Configuration struct {
+	// MaxConcurrentRequests is the number of calls in flight to a single server, calls
+	// above it fail right away.
+	MaxConcurrentRequests int `yaml:"maxConcurrentRequests"`
+
+	CircuitBreaker CircuitBreakerConfiguration `yaml:"circuitBreaker"`
+}
+
+type CircuitBreakerConfiguration struct {
+	// FailureThreshold is the number of consecutive failures after which calls to the server fail fast.
+	FailureThreshold int `yaml:"failureThreshold"`
+
+	// OpenDuration is how long calls fail fast before the server is probed again.
+	OpenDuration time.Duration `yaml:"openDuration"`
+}
+
+func (c Configuration) withDefaults() Configuration {
+	if c.MaxConcurrentRequests <= 0 {
+		c.MaxConcurrentRequests = defaultMaxConcurrentRequests
+	}
+
+	if c.CircuitBreaker.FailureThreshold <= 0 {
+		c.CircuitBreaker.FailureThreshold = defaultFailureThreshold
+	}
+
+	if c.CircuitBreaker.OpenDuration <= 0 {
+		c.CircuitBreaker.OpenDuration = defaultOpenDuration
+	}
+
+	return c
+}
+
 type Pool interface {
 	pkgio.Closer
 
 	ClientForServer(ctx context.Context, serverID string) (Client, error)
+
+	// Evict closes the connection to the server, it should be called once the server leaves the cluster.
+	Evict(serverID string)
 }
 
+// pool keeps a single long-lived connection per event server, gRPC multiplexes
+// concurrent calls over it.
 type pool struct {
 	serverInfoResolver membership.ServerInfoResolver
-	i                  pkginstrument.Instrumentation
+	credentials        credentials.PerRPCCredentials
+	config             Configuration
+
+	mu      sync.Mutex
+	clients map[string]*grpcClient
+
+	rejections *prometheus.CounterVec
+
+	now func() time.Time
+	i   pkginstrument.Instrumentation
 }
 
-func NewPool(i pkginstrument.Instrumentation, serverInfoResolver membership.ServerInfoResolver) Pool {
-	return &pool{
+func NewPool(i pkginstrument.Instrumentation, serverInfoResolver membership.ServerInfoResolver, creds credentials.PerRPCCredentials, config Configuration) (Pool, error) {
+	p := &pool{
 		serverInfoResolver: serverInfoResolver,
-		i:                  i,
+		credentials:        creds,
+		config:             config.withDefaults(),
+		clients:            map[string]*grpcClient{},
+
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "event_server_client_rejections_total",
+			Help: "Number of calls to event servers failed without being made.",
+		}, []string{"reason"}),
+
+		now: time.Now,
+		i:   i,
+	}
+
+	if err := i.Registerer.Register(p.rejections); err != nil {
+		return nil, fmt.Errorf("registering rejections metric: %w", err)
+	}
+
+	openCircuits := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "event_server_client_open_circuits",
+		Help: "Number of event servers which calls fail fast to.",
+	}, func() float64 {
+		return float64(p.openCircuits())
+	})
+	if err := i.Registerer.Register(openCircuits); err != nil {
+		return nil, fmt.Errorf("registering open circuits metric: %w", err)
 	}
+
+	return p, nil
 }
 
 func (p *pool) ClientForServer(ctx context.Context, serverID string) (Client, error) {
@@ -38,43 +131,186 @@ func (p *pool) ClientForServer(ctx context.Context, serverID string) (Client, er
 		return nil, fmt.Errorf("resolving server info: %w", err)
 	}
 
-	return newHTTPClient(info.HTTPAddress), nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c, ok := p.clients[serverID]; ok {
+		if c.address == info.GRPCAddress {
+			return c, nil
+		}
+
+		// the server has restarted on a different address
+		p.closeClient(serverID, c)
+	}
+
+	c, err := p.newGRPCClient(info.GRPCAddress)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to server: %w", err)
+	}
+	p.clients[serverID] = c
+
+	return c, nil
+}
+
+func (p *pool) Evict(serverID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c, ok := p.clients[serverID]; ok {
+		p.closeClient(serverID, c)
+		p.i.Logger.Debug("evicted event server client", zap.String("serverId", serverID))
+	}
+}
+
+// closeClient has to be called holding the lock. Calls in flight are failed.
+func (p *pool) closeClient(serverID string, c *grpcClient) {
+	if err := c.conn.Close(); err != nil {
+		p.i.Logger.Debug("failed closing event server connection", zap.String("serverId", serverID), zap.Error(err))
+	}
+	delete(p.clients, serverID)
+}
+
+func (p *pool) openCircuits() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	open := 0
+	for _, c := range p.clients {
+		if c.breaker.open() {
+			open++
+		}
+	}
+	return open
 }
 
 func (p *pool) Close(_ context.Context) error {
-	return nil // noop for now
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var multiErr error
+	for serverID, c := range p.clients {
+		if err := c.conn.Close(); err != nil {
+			multiErr = multierr.Append(multiErr, err)
+		}
+		delete(p.clients, serverID)
+	}
+	return multiErr
+}
+
+// Delivery is a single event sent as part of a batch.
+type Delivery struct {
+	RecipientID pkgid.ID
+	Event       *rteventspb.Event
 }
 
 type Client interface {
-	Send(ctx context.Context, event *rteventspb.Event) error
+	Send(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error
+
+	// SendMany sends the events in a single call. Returns an error per delivery, in the
+	// order of the deliveries, or an error if the call itself has failed.
+	SendMany(ctx context.Context, deliveries []Delivery) ([]error, error)
 }
 
-type httpClient struct {
-	c            *pkghttp.Client
-	pbMarshaller jsonpb.Marshaler
+type grpcClient struct {
+	address string
+	conn    *grpc.ClientConn
+	client  eventserverpb.EventServerClient
+
+	breaker    *breaker
+	inFlight   chan struct{}
+	rejections *prometheus.CounterVec
 }
 
-func newHTTPClient(address string) *httpClient {
-	return &httpClient{
-		c:            pkghttp.NewClient(address),
-		pbMarshaller: jsonpb.Marshaler{},
+func (p *pool) newGRPCClient(address string) (*grpcClient, error) {
+	// dialing is lazy, the connection is established with the first call
+	conn, err := grpc.Dial(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(p.credentials))
+	if err != nil {
+		return nil, err
 	}
+
+	return &grpcClient{
+		address: address,
+		conn:    conn,
+		client:  eventserverpb.NewEventServerClient(conn),
+
+		breaker:    newBreaker(p.config.CircuitBreaker, p.now),
+		inFlight:   make(chan struct{}, p.config.MaxConcurrentRequests),
+		rejections: p.rejections,
+	}, nil
 }
 
-func (h *httpClient) Send(ctx context.Context, event *rteventspb.Event) error {
-	var buff bytes.Buffer
-	if err := h.pbMarshaller.Marshal(&buff, event); err != nil {
-		return fmt.Errorf("marshalling event: %w", err)
+// call makes the call unless the server is failing or too busy.
+func (c *grpcClient) call(fn func() error) error {
+	select {
+	case c.inFlight <- struct{}{}:
+	default:
+		c.rejections.WithLabelValues("concurrency_limit").Inc()
+		return ErrConcurrencyLimit
+	}
+	defer func() {
+		<-c.inFlight
+	}()
+
+	if !c.breaker.allow() {
+		c.rejections.WithLabelValues("circuit_open").Inc()
+		return ErrCircuitOpen
 	}
 
-	resp, err := h.c.Post(ctx, "/send-event", buff.Bytes())
+	err := fn()
+	c.breaker.done(err)
+	return err
+}
+
+func (c *grpcClient) Send(ctx context.Context, recipientID pkgid.ID, event *rteventspb.Event) error {
+	err := c.call(func() error {
+		_, err := c.client.Deliver(ctx, &eventserverpb.DeliverRequest{
+			RecipientId: recipientID.String(),
+			Event:       event,
+		})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%w: %v", ErrRecipientNotConnected, err)
+	}
 	if err != nil {
-		return fmt.Errorf("sending HTTP request: %w", err)
+		return fmt.Errorf("delivering event: %w", err)
 	}
+	return nil
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("received non-200 status code: %v", resp.StatusCode)
+func (c *grpcClient) SendMany(ctx context.Context, deliveries []Delivery) ([]error, error) {
+	req := &eventserverpb.DeliverManyRequest{
+		Deliveries: make([]*eventserverpb.DeliverRequest, 0, len(deliveries)),
+	}
+	for _, d := range deliveries {
+		req.Deliveries = append(req.Deliveries, &eventserverpb.DeliverRequest{
+			RecipientId: d.RecipientID.String(),
+			Event:       d.Event,
+		})
 	}
 
-	return nil
+	var resp *eventserverpb.DeliverManyResponse
+	err := c.call(func() (err error) {
+		resp, err = c.client.DeliverMany(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("delivering events: %w", err)
+	}
+	if len(resp.Results) != len(deliveries) {
+		return nil, fmt.Errorf("expected %d results, received %d", len(deliveries), len(resp.Results))
+	}
+
+	errs := make([]error, len(resp.Results))
+	for idx, result := range resp.Results {
+		switch {
+		case result.RecipientNotConnected:
+			errs[idx] = fmt.Errorf("%w: %s", ErrRecipientNotConnected, result.Error)
+		case result.Error != "":
+			errs[idx] = errors.New(result.Error)
+		}
+	}
+	return errs, nil
 }
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	esgrpc "github.com/faustuzas/occa/src/eventserver/grpc"
+	"github.com/faustuzas/occa/src/eventserver/services"
+	pkgauth "github.com/faustuzas/occa/src/pkg/auth"
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestPool_DeliversThroughGRPC(t *testing.T) {
+	var (
+		eventServer = &stubEventServer{failFor: pkgid.NewID()}
+		serviceAuth = pkgauth.ServiceAuthConfiguration{Type: pkgauth.ServiceAuthConfigurationToken, Token: "secret"}
+		pool        = newTestPool(t, serviceAuth, startTestServer(t, eventServer, serviceAuth))
+		recipientID = pkgid.NewID()
+	)
+
+	client, err := pool.ClientForServer(context.Background(), "es-1")
+	require.NoError(t, err)
+
+	event := rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hello")
+	require.NoError(t, client.Send(context.Background(), recipientID, event))
+	require.Equal(t, []pkgid.ID{recipientID}, eventServer.recipients())
+
+	errs, err := client.SendMany(context.Background(), []Delivery{
+		{RecipientID: eventServer.failFor, Event: event},
+		{RecipientID: recipientID, Event: event},
+	})
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	require.ErrorIs(t, errs[0], ErrRecipientNotConnected)
+	require.NoError(t, errs[1])
+
+	err = client.Send(context.Background(), eventServer.failFor, event)
+	require.ErrorIs(t, err, ErrRecipientNotConnected)
+
+	// the connection is reused
+	again, err := pool.ClientForServer(context.Background(), "es-1")
+	require.NoError(t, err)
+	require.Same(t, client, again)
+}
+
+func TestPool_EvictClosesConnection(t *testing.T) {
+	var (
+		serviceAuth = pkgauth.ServiceAuthConfiguration{Type: pkgauth.ServiceAuthConfigurationNoop}
+		pool        = newTestPool(t, serviceAuth, startTestServer(t, &stubEventServer{}, serviceAuth))
+	)
+
+	client, err := pool.ClientForServer(context.Background(), "es-1")
+	require.NoError(t, err)
+
+	pool.Evict("es-1")
+	require.Error(t, client.Send(context.Background(), pkgid.NewID(), rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hello")))
+
+	replaced, err := pool.ClientForServer(context.Background(), "es-1")
+	require.NoError(t, err)
+	require.NotSame(t, client, replaced)
+	require.NoError(t, replaced.Send(context.Background(), pkgid.NewID(), rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hello")))
+}
+
+func TestPool_RequiresServiceCredential(t *testing.T) {
+	address := startTestServer(t, &stubEventServer{},
+		pkgauth.ServiceAuthConfiguration{Type: pkgauth.ServiceAuthConfigurationToken, Token: "secret"})
+
+	pool := newTestPool(t, pkgauth.ServiceAuthConfiguration{Type: pkgauth.ServiceAuthConfigurationToken, Token: "wrong"}, address)
+
+	client, err := pool.ClientForServer(context.Background(), "es-1")
+	require.NoError(t, err)
+
+	err = client.Send(context.Background(), pkgid.NewID(), rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hello"))
+	require.Equal(t, codes.Unauthenticated, status.Code(errors.Unwrap(err)))
+}
+
+func startTestServer(t *testing.T, eventServer services.EventServer, serviceAuth pkgauth.ServiceAuthConfiguration) string {
+	i := pkginstrument.Instrumentation{
+		Logger:     pkgtest.Instrumentation.Logger,
+		Registerer: prometheus.NewRegistry(),
+	}
+
+	serviceAuthInterceptor, err := serviceAuth.BuildGRPCUnaryInterceptor(i)
+	require.NoError(t, err)
+
+	streamAuthInterceptor, err := pkgauth.ValidatorConfiguration{Type: pkgauth.ValidatorConfigurationNoop}.
+		BuildGRPCStreamInterceptor(i)
+	require.NoError(t, err)
+
+	server, err := esgrpc.Configure(esgrpc.Services{
+		EventServer:            eventServer,
+		StreamAuthInterceptor:  streamAuthInterceptor,
+		ServiceAuthInterceptor: serviceAuthInterceptor,
+		Instrumentation:        i,
+	})
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func newTestPool(t *testing.T, serviceAuth pkgauth.ServiceAuthConfiguration, address string) Pool {
+	creds, err := serviceAuth.BuildGRPCCredentials()
+	require.NoError(t, err)
+
+	i := pkginstrument.Instrumentation{
+		Logger:     pkgtest.Instrumentation.Logger,
+		Registerer: prometheus.NewRegistry(),
+	}
+
+	pool, err := NewPool(i, stubResolver{"es-1": {ID: "es-1", GRPCAddress: address}}, creds, Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, pool.Close(context.Background()))
+	})
+	return pool
+}
+
+type stubResolver map[string]membership.ServerInfo
+
+func (s stubResolver) Resolve(_ context.Context, serverID string) (membership.ServerInfo, error) {
+	info, ok := s[serverID]
+	if !ok {
+		return membership.ServerInfo{}, errors.New("server not found")
+	}
+	return info, nil
+}
+
+type stubEventServer struct {
+	services.EventServer
+
+	failFor pkgid.ID
+
+	mu   sync.Mutex
+	sent []pkgid.ID
+}
+
+func (s *stubEventServer) SendEvent(_ context.Context, recipientID pkgid.ID, _ *rteventspb.Event) error {
+	if recipientID == s.failFor {
+		return fmt.Errorf("%w: %s", services.ErrRecipientNotConnected, recipientID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, recipientID)
+	return nil
+}
+
+func (s *stubEventServer) recipients() []pkgid.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]pkgid.ID(nil), s.sent...)
+}
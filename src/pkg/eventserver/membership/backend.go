@@ -0,0 +1,141 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgio "github.com/faustuzas/occa/src/pkg/io"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+var (
+	// ErrServerIDTaken is returned when joining the cluster with the ID of a live server.
+	ErrServerIDTaken = errors.New("server ID is used by a live server")
+
+	// ErrFenced is returned when the server ID has been taken over by another process.
+	ErrFenced = errors.New("server ID has been taken over")
+)
+
+type BackendType string
+
+const (
+	BackendEtcd   BackendType = "etcd"
+	BackendRedis  BackendType = "redis"
+	BackendStatic BackendType = "static"
+)
+
+// Configuration selects where the cluster membership is stored.
+type Configuration struct {
+	Backend BackendType `yaml:"backend"`
+
+	// Static lists the servers of the static backend.
+	Static []StaticServer `yaml:"static"`
+}
+
+type StaticServer struct {
+	ID          string `yaml:"id"`
+	GRPCAddress string `yaml:"grpcAddress"`
+	HTTPAddress string `yaml:"httpAddress"`
+	Capacity    int    `yaml:"capacity"`
+}
+
+func (c Configuration) Validate() error {
+	switch c.Backend {
+	case "", BackendEtcd, BackendRedis:
+		return nil
+	case BackendStatic:
+		if len(c.Static) == 0 {
+			return fmt.Errorf("static backend needs at least one server")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown membership backend %q", c.Backend)
+	}
+}
+
+// Build creates the configured backend. The etcd configuration is used only by the etcd
+// backend and the memstore only by the redis one.
+func (c Configuration) Build(i pkginstrument.Instrumentation, etcd pkgetcd.Configuration, store pkgmemstore.Store) (Backend, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch c.Backend {
+	case BackendRedis:
+		return NewRedisBackend(i, store), nil
+	case BackendStatic:
+		return NewStaticBackend(c.Static), nil
+	default:
+		client, err := etcd.Build()
+		if err != nil {
+			return nil, fmt.Errorf("building etcd client: %w", err)
+		}
+		return NewEtcdBackend(i, client), nil
+	}
+}
+
+// Backend stores the membership of the event server cluster.
+type Backend interface {
+	pkgio.Closer
+
+	// List returns all registered servers together with the revision of the listing.
+	List(ctx context.Context) ([]ServerInfo, int64, error)
+
+	// Watch reports the changes after the revision to the handler until the context is
+	// cancelled, when nil is returned, or the watch breaks.
+	Watch(ctx context.Context, revision int64, handler WatchHandler) error
+
+	// NewRegistration creates a registration of a single server.
+	NewRegistration() Registration
+}
+
+// WatchHandler receives the changes of the membership.
+type WatchHandler interface {
+	ServerUpdated(info ServerInfo)
+	ServerRemoved(serverID string)
+
+	// ServersListed replaces all known servers, backends which cannot report every
+	// change reliably list the servers periodically.
+	ServersListed(servers []ServerInfo)
+
+	// Synced confirms that all the changes up to now have been reported.
+	Synced()
+}
+
+// Registration keeps a server registered in the cluster.
+type Registration interface {
+	pkgio.Closer
+
+	Start(ctx context.Context) error
+
+	// Join registers the server taking the next epoch of its ID, which is returned.
+	// Fails with ErrServerIDTaken if a live server uses the ID.
+	Join(ctx context.Context, info ServerInfo) (int64, error)
+
+	// Publish updates the information of the joined server. Fails with ErrFenced if
+	// the ID has been taken over by another process.
+	Publish(ctx context.Context, info ServerInfo) error
+
+	// LostC is closed when the registration cannot be kept anymore.
+	LostC() <-chan struct{}
+}
+
+// watchBrokenError is returned by backends when the watch breaks.
+type watchBrokenError struct {
+	reason string
+	err    error
+}
+
+func (e watchBrokenError) Error() string {
+	if e.err == nil {
+		return "watch " + e.reason
+	}
+	return fmt.Sprintf("watch %s: %v", e.reason, e.err)
+}
+
+func (e watchBrokenError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,229 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+)
+
+const (
+	eventServersNamespace = "/event_servers/"
+	// epochsNamespace holds the latest epoch of every server ID, it is not leased so
+	// epochs keep growing across restarts
+	epochsNamespace = "/event_server_epochs/"
+
+	joinAttempts = 3
+
+	leaseTTL = 15 * time.Second
+	// leaseGracePeriod is how long the server keeps trying to get a new lease after
+	// losing the previous one, before giving up its membership in the cluster.
+	leaseGracePeriod = time.Minute
+
+	// progressRequestInterval is how often etcd is asked to confirm the watch is up-to-date
+	// when no changes happen, so the staleness of the registry stays known.
+	progressRequestInterval = 5 * time.Second
+)
+
+type etcdBackend struct {
+	client *clientv3.Client
+	i      pkginstrument.Instrumentation
+}
+
+func NewEtcdBackend(i pkginstrument.Instrumentation, client *clientv3.Client) Backend {
+	return &etcdBackend{
+		client: client,
+		i:      i,
+	}
+}
+
+func (b *etcdBackend) List(ctx context.Context) ([]ServerInfo, int64, error) {
+	getResp, err := b.client.Get(ctx, eventServersNamespace, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing servers: %w", err)
+	}
+
+	servers := make([]ServerInfo, 0, len(getResp.Kvs))
+	for _, kv := range getResp.Kvs {
+		var info ServerInfo
+		if err = info.Unmarshall(kv.Value); err != nil {
+			return nil, 0, fmt.Errorf("unmarshaling server info: %w", err)
+		}
+
+		servers = append(servers, info)
+	}
+
+	return servers, getResp.Header.Revision, nil
+}
+
+func (b *etcdBackend) Watch(ctx context.Context, revision int64, handler WatchHandler) error {
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	watchCh := b.client.Watch(watchCtx, eventServersNamespace,
+		clientv3.WithRev(revision+1), clientv3.WithPrefix(), clientv3.WithProgressNotify())
+
+	progressTicker := time.NewTicker(progressRequestInterval)
+	defer progressTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-progressTicker.C:
+			// the answer arrives through the watch channel
+			if err := b.client.RequestProgress(watchCtx); err != nil {
+				b.i.Logger.Debug("failed to request watch progress", zap.Error(err))
+			}
+		case watchResp, ok := <-watchCh:
+			switch {
+			case !ok:
+				if ctx.Err() != nil {
+					return nil
+				}
+				return watchBrokenError{reason: "closed"}
+			case watchResp.CompactRevision != 0:
+				// the changes since the revision are gone, only a fresh listing can tell the state
+				return watchBrokenError{reason: "compacted",
+					err: fmt.Errorf("compacted at revision %d", watchResp.CompactRevision)}
+			case watchResp.Canceled:
+				return watchBrokenError{reason: "cancelled", err: watchResp.Err()}
+			case watchResp.Err() != nil:
+				return watchBrokenError{reason: "error", err: watchResp.Err()}
+			}
+
+			for _, e := range watchResp.Events {
+				switch e.Type {
+				case clientv3.EventTypePut:
+					var info ServerInfo
+					if err := info.Unmarshall(e.Kv.Value); err != nil {
+						b.i.Logger.Error("failed to unmarshal server info from watcher", zap.Error(err))
+						continue
+					}
+					handler.ServerUpdated(info)
+				case clientv3.EventTypeDelete:
+					handler.ServerRemoved(string(e.Kv.Key[len(eventServersNamespace):]))
+				}
+			}
+			handler.Synced()
+		}
+	}
+}
+
+func (b *etcdBackend) NewRegistration() Registration {
+	return &etcdRegistration{
+		client: b.client,
+		lease:  pkgetcd.NewLeasedClient(b.i, b.client, leaseTTL, leaseGracePeriod),
+		i:      b.i,
+	}
+}
+
+func (b *etcdBackend) Close(_ context.Context) error {
+	return b.client.Close()
+}
+
+// etcdRegistration keeps the server record attached to a lease, so it disappears
+// together with the server.
+type etcdRegistration struct {
+	client *clientv3.Client
+	lease  *pkgetcd.LeasedClient
+	i      pkginstrument.Instrumentation
+}
+
+func (r *etcdRegistration) Start(ctx context.Context) error {
+	if err := r.lease.Start(ctx); err != nil {
+		return fmt.Errorf("acquiring lease: %w", err)
+	}
+	return nil
+}
+
+// Join increments the epoch and registers the server in a single transaction, which
+// fails if the ID is used by a live server, so a misconfigured process cannot fence
+// off the legitimate one.
+func (r *etcdRegistration) Join(ctx context.Context, info ServerInfo) (int64, error) {
+	var (
+		serverKey = eventServersNamespace + info.ID
+		epochKey  = epochsNamespace + info.ID
+	)
+
+	for attempt := 0; attempt < joinAttempts; attempt++ {
+		getResp, err := r.client.Get(ctx, epochKey)
+		if err != nil {
+			return 0, fmt.Errorf("getting current epoch: %w", err)
+		}
+
+		var current, modRevision int64
+		if len(getResp.Kvs) > 0 {
+			if current, err = strconv.ParseInt(string(getResp.Kvs[0].Value), 10, 64); err != nil {
+				return 0, fmt.Errorf("parsing current epoch: %w", err)
+			}
+			modRevision = getResp.Kvs[0].ModRevision
+		}
+
+		info.Epoch = current + 1
+		epoch := strconv.FormatInt(info.Epoch, 10)
+
+		infoBytes, err := info.Marshall()
+		if err != nil {
+			return 0, fmt.Errorf("marshaling server info: %w", err)
+		}
+
+		joined, err := r.lease.PutIf(ctx, serverKey, infoBytes,
+			[]clientv3.Cmp{
+				clientv3.Compare(clientv3.CreateRevision(serverKey), "=", 0),
+				clientv3.Compare(clientv3.ModRevision(epochKey), "=", modRevision),
+			},
+			clientv3.OpPut(epochKey, epoch))
+		if err != nil {
+			return 0, fmt.Errorf("registering server: %w", err)
+		}
+
+		if joined {
+			// from now on the record is written only while the epoch is ours
+			r.lease.Guard(serverKey, clientv3.Compare(clientv3.Value(epochKey), "=", epoch))
+			return info.Epoch, nil
+		}
+
+		existing, err := r.client.Get(ctx, serverKey)
+		if err != nil {
+			return 0, fmt.Errorf("checking server record: %w", err)
+		}
+		if len(existing.Kvs) > 0 {
+			return 0, fmt.Errorf("%w: %s", ErrServerIDTaken, info.ID)
+		}
+		// the epoch has been bumped concurrently, try again with the new one
+	}
+
+	return 0, fmt.Errorf("epoch of the server kept changing")
+}
+
+func (r *etcdRegistration) Publish(ctx context.Context, info ServerInfo) error {
+	infoBytes, err := info.Marshall()
+	if err != nil {
+		return fmt.Errorf("marshaling server info: %w", err)
+	}
+
+	err = r.lease.Put(ctx, eventServersNamespace+info.ID, infoBytes)
+	if errors.Is(err, pkgetcd.ErrFenced) {
+		return ErrFenced
+	}
+	if err != nil {
+		return fmt.Errorf("putting key: %w", err)
+	}
+	return nil
+}
+
+func (r *etcdRegistration) LostC() <-chan struct{} {
+	return r.lease.LeaseLostC()
+}
+
+func (r *etcdRegistration) Close(ctx context.Context) error {
+	return r.lease.Close(ctx)
+}
@@ -3,38 +3,57 @@ package membership
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 
-	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 )
 
 const (
-	refreshInfoDuration   = 15 * time.Second
-	eventServersNamespace = "/event_servers/"
+	refreshInfoDuration = 15 * time.Second
 )
 
 type Manager interface {
+	// JoinCluster registers the server and returns a channel which is closed once the
+	// membership is lost for good.
 	JoinCluster(ctx context.Context, serverInfoFn func(context.Context) (ServerInfo, error)) (<-chan struct{}, error)
+
+	// Epoch identifies this incarnation of the server ID. It is zero until the server joins the cluster.
+	Epoch() int64
+
+	// MarkDraining announces that the server is shutting down and should not receive new connections.
+	MarkDraining(ctx context.Context) error
+
 	LeaveCluster(ctx context.Context) error
 }
 
 type manager struct {
-	lease   *pkgetcd.LeasedClient
-	closeCh chan struct{}
+	registration Registration
+	closeCh      chan struct{}
+
+	epoch      atomic.Int64
+	lostCh     chan struct{}
+	lostChOnce sync.Once
+
+	serverInfoFn func(context.Context) (ServerInfo, error)
+	draining     atomic.Bool
+	// refreshMu orders concurrent refreshes, so a stale state cannot overwrite the draining one
+	refreshMu sync.Mutex
 
 	i pkginstrument.Instrumentation
 }
 
-func NewManager(i pkginstrument.Instrumentation, client *clientv3.Client) (Manager, error) {
+func NewManager(i pkginstrument.Instrumentation, backend Backend) (Manager, error) {
 	return &manager{
-		lease:   pkgetcd.NewLeasedClient(i, client, 15*time.Second),
-		closeCh: make(chan struct{}),
-		i:       i,
+		registration: backend.NewRegistration(),
+		closeCh:      make(chan struct{}),
+		lostCh:       make(chan struct{}),
+		i:            i,
 	}, nil
 }
 
@@ -43,7 +62,23 @@ type ServerInfo struct {
 	GRPCAddress string `json:"grpcAddress"`
 	HTTPAddress string `json:"httpAddress"`
 
-	// TODO: some kind of load parameter
+	// Epoch grows every time the ID is taken by a new process, so records left by a
+	// previous process with the same ID can be told apart.
+	Epoch int64 `json:"epoch,omitempty"`
+
+	// Draining servers are still serving existing connections, but must not be given new ones.
+	Draining bool `json:"draining,omitempty"`
+
+	// Load is the state of the server at the last refresh of the information.
+	Load ServerLoad `json:"load"`
+}
+
+type ServerLoad struct {
+	Connections  int `json:"connections"`
+	QueuedEvents int `json:"queuedEvents"`
+
+	// Capacity is the number of connections the server is meant to handle, zero if unknown.
+	Capacity int `json:"capacity,omitempty"`
 }
 
 func (i *ServerInfo) Marshall() ([]byte, error) {
@@ -57,17 +92,55 @@ func (i *ServerInfo) Unmarshall(data []byte) error {
 // JoinCluster joins the event server cluster and regularly updates the information stored there.
 // Note: serverInfoFn should be thread-safe.
 func (m *manager) JoinCluster(ctx context.Context, serverInfoFn func(context.Context) (ServerInfo, error)) (<-chan struct{}, error) {
-	if err := m.lease.Start(ctx); err != nil {
-		return nil, fmt.Errorf("acquiring lease: %w", err)
+	if err := m.registration.Start(ctx); err != nil {
+		return nil, fmt.Errorf("starting registration: %w", err)
 	}
 
-	if err := m.refreshInfo(ctx, serverInfoFn); err != nil {
+	m.serverInfoFn = serverInfoFn
+	if err := m.join(ctx, serverInfoFn); err != nil {
 		return nil, fmt.Errorf("joining the cluster: %w", err)
 	}
 
+	go func() {
+		select {
+		case <-m.registration.LostC():
+			m.markLost()
+		case <-m.lostCh:
+		}
+	}()
 	go m.refreshInfoLoop(serverInfoFn)
 
-	return m.lease.LeaseLostC(), nil
+	return m.lostCh, nil
+}
+
+// join takes the server ID with the next epoch.
+func (m *manager) join(ctx context.Context, serverInfoFn func(context.Context) (ServerInfo, error)) error {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	info, err := m.buildInfo(ctx, serverInfoFn)
+	if err != nil {
+		return err
+	}
+
+	epoch, err := m.registration.Join(ctx, info)
+	if err != nil {
+		return err
+	}
+	m.epoch.Store(epoch)
+
+	m.i.Logger.Info("joined the cluster", zap.String("serverId", info.ID), zap.Int64("epoch", epoch))
+	return nil
+}
+
+func (m *manager) Epoch() int64 {
+	return m.epoch.Load()
+}
+
+func (m *manager) markLost() {
+	m.lostChOnce.Do(func() {
+		close(m.lostCh)
+	})
 }
 
 func (m *manager) refreshInfoLoop(serverInfoFn func(context.Context) (ServerInfo, error)) {
@@ -77,8 +150,8 @@ func (m *manager) refreshInfoLoop(serverInfoFn func(context.Context) (ServerInfo
 	for {
 		select {
 		case <-ticker.C:
-		case <-m.lease.LeaseLostC():
-			m.i.Logger.Warn("closing membership update loop because of lost lease")
+		case <-m.lostCh:
+			m.i.Logger.Warn("closing membership update loop because of lost membership")
 			return
 		case <-m.closeCh:
 			m.i.Logger.Info("closing membership update loop because of close request")
@@ -94,30 +167,56 @@ func (m *manager) refreshInfoLoop(serverInfoFn func(context.Context) (ServerInfo
 }
 
 func (m *manager) refreshInfo(ctx context.Context, serverInfoFn func(context.Context) (ServerInfo, error)) error {
-	info, err := serverInfoFn(ctx)
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	info, err := m.buildInfo(ctx, serverInfoFn)
 	if err != nil {
-		return fmt.Errorf("getting server info: %w", err)
+		return err
 	}
+	info.Epoch = m.epoch.Load()
 
-	if info.ID == "" {
-		return fmt.Errorf("empty server id provided")
+	err = m.registration.Publish(ctx, info)
+	if errors.Is(err, ErrFenced) {
+		// another process has taken the ID over after our record expired
+		m.i.Logger.Error("server ID has been taken over by another process", zap.String("serverId", info.ID))
+		m.markLost()
+	}
+	if err != nil {
+		return fmt.Errorf("publishing server info: %w", err)
 	}
 
-	infoBytes, err := info.Marshall()
+	return nil
+}
+
+func (m *manager) buildInfo(ctx context.Context, serverInfoFn func(context.Context) (ServerInfo, error)) (ServerInfo, error) {
+	info, err := serverInfoFn(ctx)
 	if err != nil {
-		return fmt.Errorf("marshaling server info: %w", err)
+		return ServerInfo{}, fmt.Errorf("getting server info: %w", err)
+	}
+
+	if info.ID == "" {
+		return ServerInfo{}, fmt.Errorf("empty server id provided")
 	}
+	info.Draining = m.draining.Load()
 
-	// TODO: need to have some kind of epoch counter for safe overrides
-	if err = m.lease.Put(ctx, eventServersNamespace+info.ID, infoBytes); err != nil {
-		return fmt.Errorf("putting key: %w", err)
+	return info, nil
+}
+
+func (m *manager) MarkDraining(ctx context.Context) error {
+	if m.serverInfoFn == nil {
+		return fmt.Errorf("server has not joined the cluster")
 	}
 
+	m.draining.Store(true)
+	if err := m.refreshInfo(ctx, m.serverInfoFn); err != nil {
+		return fmt.Errorf("publishing draining state: %w", err)
+	}
 	return nil
 }
 
 func (m *manager) LeaveCluster(ctx context.Context) error {
 	close(m.closeCh)
 
-	return m.lease.Close(ctx)
+	return m.registration.Close(ctx)
 }
@@ -0,0 +1,340 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+const (
+	serversCollection = "event_servers"
+	// epochsCollection holds the latest epoch of every server ID, it does not expire so
+	// epochs keep growing across restarts
+	epochsCollection = "event_server_epochs"
+
+	// recordTTL is for how long the server record outlives the last refresh of it.
+	recordTTL = 15 * time.Second
+
+	// relistInterval is how often the servers are listed on top of following the keyspace
+	// notifications, which are lost while the subscription is reconnecting.
+	relistInterval = 5 * time.Second
+)
+
+type redisBackend struct {
+	store pkgmemstore.Store
+	i     pkginstrument.Instrumentation
+}
+
+// NewRedisBackend keeps the membership in the memstore. Servers are records expiring
+// unless they are refreshed, changes are followed through keyspace notifications.
+func NewRedisBackend(i pkginstrument.Instrumentation, store pkgmemstore.Store) Backend {
+	return &redisBackend{
+		store: store,
+		i:     i,
+	}
+}
+
+// List returns the registered servers. The memstore has no revisions, so it is always zero.
+func (b *redisBackend) List(ctx context.Context) ([]ServerInfo, int64, error) {
+	values, err := b.store.ListCollection(ctx, serversCollection)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing servers: %w", err)
+	}
+
+	servers := make([]ServerInfo, 0, len(values))
+	for _, value := range values {
+		var info ServerInfo
+		if err = info.Unmarshall(value); err != nil {
+			return nil, 0, fmt.Errorf("unmarshaling server info: %w", err)
+		}
+
+		if joined(info) {
+			servers = append(servers, info)
+		}
+	}
+
+	return servers, 0, nil
+}
+
+func (b *redisBackend) Watch(ctx context.Context, _ int64, handler WatchHandler) error {
+	changes, err := b.store.WatchCollection(ctx, serversCollection)
+	if err != nil {
+		return watchBrokenError{reason: "error", err: err}
+	}
+
+	relistTicker := time.NewTicker(relistInterval)
+	defer relistTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-relistTicker.C:
+			servers, _, err := b.List(ctx)
+			if err != nil {
+				b.i.Logger.Warn("failed to list servers", zap.Error(err))
+				continue
+			}
+			handler.ServersListed(servers)
+			handler.Synced()
+		case change, ok := <-changes:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return watchBrokenError{reason: "closed"}
+			}
+
+			if change.Removed {
+				handler.ServerRemoved(change.Key)
+				continue
+			}
+
+			value, err := b.store.GetCollectionItem(ctx, serversCollection, change.Key)
+			if errors.Is(err, pkgmemstore.ErrNotFound) {
+				// removed meanwhile, the notification about it follows
+				continue
+			}
+			if err != nil {
+				b.i.Logger.Warn("failed to get changed server info", zap.String("serverId", change.Key), zap.Error(err))
+				continue
+			}
+
+			var info ServerInfo
+			if err = info.Unmarshall(value); err != nil {
+				b.i.Logger.Error("failed to unmarshal server info from watcher", zap.Error(err))
+				continue
+			}
+			if joined(info) {
+				handler.ServerUpdated(info)
+			}
+		}
+	}
+}
+
+func (b *redisBackend) NewRegistration() Registration {
+	return &redisRegistration{
+		store:   b.store,
+		closeCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+		lostCh:  make(chan struct{}),
+		i:       b.i,
+	}
+}
+
+// Close does nothing, the memstore is owned by the caller.
+func (b *redisBackend) Close(_ context.Context) error {
+	return nil
+}
+
+// joined tells whether the record belongs to a server which has finished joining,
+// records without an epoch only reserve the ID.
+func joined(info ServerInfo) bool {
+	return info.Epoch != 0
+}
+
+// redisRegistration refreshes the server record before it expires. If the record
+// expires anyway, e.g. because the memstore was unreachable, it is put back unless
+// another process has taken the ID over meanwhile.
+type redisRegistration struct {
+	store pkgmemstore.Store
+
+	// mu orders writes of the record, so the last written value is known
+	mu      sync.Mutex
+	id      string
+	epoch   int64
+	written []byte
+
+	started    bool
+	closeCh    chan struct{}
+	doneCh     chan struct{}
+	lostCh     chan struct{}
+	lostChOnce sync.Once
+
+	i pkginstrument.Instrumentation
+}
+
+func (r *redisRegistration) Start(_ context.Context) error {
+	r.started = true
+	go r.keepAlive()
+	return nil
+}
+
+// Join reserves the ID before taking the next epoch, so a misconfigured process cannot
+// fence off the live server using the same ID.
+func (r *redisRegistration) Join(ctx context.Context, info ServerInfo) (int64, error) {
+	info.Epoch = 0
+	reservation, err := info.Marshall()
+	if err != nil {
+		return 0, fmt.Errorf("marshaling server info: %w", err)
+	}
+
+	reserved, err := r.store.SetCollectionItemIfAbsent(ctx, serversCollection, info.ID, reservation, recordTTL)
+	if err != nil {
+		return 0, fmt.Errorf("reserving server ID: %w", err)
+	}
+	if !reserved {
+		return 0, fmt.Errorf("%w: %s", ErrServerIDTaken, info.ID)
+	}
+
+	info.Epoch, err = r.store.IncrementCollectionCounter(ctx, epochsCollection, info.ID, 0)
+	if err != nil {
+		return 0, fmt.Errorf("incrementing epoch: %w", err)
+	}
+
+	infoBytes, err := info.Marshall()
+	if err != nil {
+		return 0, fmt.Errorf("marshaling server info: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	swapped, err := r.store.CompareAndSwapCollectionItem(ctx, serversCollection, info.ID, reservation, infoBytes, recordTTL)
+	if err != nil {
+		return 0, fmt.Errorf("registering server: %w", err)
+	}
+	if !swapped {
+		return 0, fmt.Errorf("reservation of the server ID has expired")
+	}
+
+	r.id, r.epoch, r.written = info.ID, info.Epoch, infoBytes
+	return info.Epoch, nil
+}
+
+func (r *redisRegistration) Publish(ctx context.Context, info ServerInfo) error {
+	infoBytes, err := info.Marshall()
+	if err != nil {
+		return fmt.Errorf("marshaling server info: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.write(ctx, infoBytes)
+}
+
+// write replaces the record written last. Has to be called holding the lock.
+func (r *redisRegistration) write(ctx context.Context, value []byte) error {
+	if r.written == nil {
+		return fmt.Errorf("server has not joined the cluster")
+	}
+
+	swapped, err := r.store.CompareAndSwapCollectionItem(ctx, serversCollection, r.id, r.written, value, recordTTL)
+	if err != nil {
+		return fmt.Errorf("refreshing server record: %w", err)
+	}
+	if swapped {
+		r.written = value
+		return nil
+	}
+
+	// the record is not ours anymore, it is put back only if it has expired and nobody has joined since
+	epochBytes, err := r.store.GetCollectionItem(ctx, epochsCollection, r.id)
+	if err != nil {
+		return fmt.Errorf("getting current epoch: %w", err)
+	}
+	epoch, err := strconv.ParseInt(string(epochBytes), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parsing current epoch: %w", err)
+	}
+	if epoch != r.epoch {
+		return ErrFenced
+	}
+
+	swapped, err = r.store.CompareAndSwapCollectionItem(ctx, serversCollection, r.id, nil, value, recordTTL)
+	if err != nil {
+		return fmt.Errorf("putting back server record: %w", err)
+	}
+	if !swapped {
+		return ErrFenced
+	}
+
+	r.written = value
+	r.i.Logger.Warn("server record has expired, put it back", zap.String("serverId", r.id))
+	return nil
+}
+
+func (r *redisRegistration) keepAlive() {
+	defer close(r.doneCh)
+
+	ticker := time.NewTicker(recordTTL / 3)
+	defer ticker.Stop()
+
+	lastRefresh := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.lostCh:
+			return
+		case <-r.closeCh:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), recordTTL/3)
+		r.mu.Lock()
+		var err error
+		if r.written != nil {
+			err = r.write(ctx, r.written)
+		}
+		r.mu.Unlock()
+		cancel()
+
+		switch {
+		case err == nil:
+			lastRefresh = time.Now()
+		case errors.Is(err, ErrFenced):
+			r.i.Logger.Error("server ID has been taken over by another process", zap.String("serverId", r.id))
+			r.markLost()
+			return
+		case time.Since(lastRefresh) > leaseGracePeriod:
+			r.i.Logger.Error("failed to refresh server record within the grace period", zap.Error(err))
+			r.markLost()
+			return
+		default:
+			r.i.Logger.Warn("failed to refresh server record", zap.Error(err))
+		}
+	}
+}
+
+func (r *redisRegistration) markLost() {
+	r.lostChOnce.Do(func() {
+		close(r.lostCh)
+	})
+}
+
+func (r *redisRegistration) LostC() <-chan struct{} {
+	return r.lostCh
+}
+
+// Close stops refreshing the record and removes it, so the ID can be taken right away.
+func (r *redisRegistration) Close(ctx context.Context) error {
+	close(r.closeCh)
+	if r.started {
+		select {
+		case <-r.doneCh:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the refresh loop to stop: %w", ctx.Err())
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.written == nil {
+		return nil
+	}
+
+	if _, err := r.store.CompareAndSwapCollectionItem(ctx, serversCollection, r.id, r.written, nil, 0); err != nil {
+		return fmt.Errorf("removing server record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package membership
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestRedisRegistration_JoinFailsIfIDTaken(t *testing.T) {
+	var (
+		ctrl         = gomock.NewController(t)
+		store        = pkgmemstore.NewMockStore(ctrl)
+		registration = NewRedisBackend(pkgtest.Instrumentation, store).NewRegistration()
+	)
+
+	store.EXPECT().
+		SetCollectionItemIfAbsent(gomock.Any(), serversCollection, "es-1", gomock.Any(), recordTTL).
+		Return(false, nil)
+
+	_, err := registration.Join(context.Background(), ServerInfo{ID: "es-1"})
+	require.ErrorIs(t, err, ErrServerIDTaken)
+}
+
+func TestRedisRegistration_PublishIsFencedAfterTakeOver(t *testing.T) {
+	var (
+		ctrl         = gomock.NewController(t)
+		store        = pkgmemstore.NewMockStore(ctrl)
+		registration = NewRedisBackend(pkgtest.Instrumentation, store).NewRegistration()
+	)
+
+	store.EXPECT().
+		SetCollectionItemIfAbsent(gomock.Any(), serversCollection, "es-1", gomock.Any(), recordTTL).
+		Return(true, nil)
+	store.EXPECT().
+		IncrementCollectionCounter(gomock.Any(), epochsCollection, "es-1", gomock.Any()).
+		Return(int64(3), nil)
+	store.EXPECT().
+		CompareAndSwapCollectionItem(gomock.Any(), serversCollection, "es-1", gomock.Any(), gomock.Any(), recordTTL).
+		Return(true, nil)
+
+	epoch, err := registration.Join(context.Background(), ServerInfo{ID: "es-1"})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), epoch)
+
+	// the record has expired and another process has joined with the next epoch
+	store.EXPECT().
+		CompareAndSwapCollectionItem(gomock.Any(), serversCollection, "es-1", gomock.Any(), gomock.Any(), recordTTL).
+		Return(false, nil)
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), epochsCollection, "es-1").
+		Return([]byte("4"), nil)
+
+	err = registration.Publish(context.Background(), ServerInfo{ID: "es-1", Epoch: 3, Draining: true})
+	require.ErrorIs(t, err, ErrFenced)
+}
+
+func TestRedisRegistration_PublishPutsBackExpiredRecord(t *testing.T) {
+	var (
+		ctrl         = gomock.NewController(t)
+		store        = pkgmemstore.NewMockStore(ctrl)
+		registration = NewRedisBackend(pkgtest.Instrumentation, store).NewRegistration()
+	)
+
+	store.EXPECT().
+		SetCollectionItemIfAbsent(gomock.Any(), serversCollection, "es-1", gomock.Any(), recordTTL).
+		Return(true, nil)
+	store.EXPECT().
+		IncrementCollectionCounter(gomock.Any(), epochsCollection, "es-1", gomock.Any()).
+		Return(int64(1), nil)
+	store.EXPECT().
+		CompareAndSwapCollectionItem(gomock.Any(), serversCollection, "es-1", gomock.Any(), gomock.Any(), recordTTL).
+		Return(true, nil)
+
+	_, err := registration.Join(context.Background(), ServerInfo{ID: "es-1"})
+	require.NoError(t, err)
+
+	info := ServerInfo{ID: "es-1", Epoch: 1, Draining: true}
+	infoBytes, err := info.Marshall()
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		store.EXPECT().
+			CompareAndSwapCollectionItem(gomock.Any(), serversCollection, "es-1", gomock.Not(gomock.Nil()), infoBytes, recordTTL).
+			Return(false, nil),
+		store.EXPECT().
+			GetCollectionItem(gomock.Any(), epochsCollection, "es-1").
+			Return([]byte("1"), nil),
+		store.EXPECT().
+			CompareAndSwapCollectionItem(gomock.Any(), serversCollection, "es-1", gomock.Nil(), infoBytes, recordTTL).
+			Return(true, nil),
+	)
+
+	require.NoError(t, registration.Publish(context.Background(), info))
+}
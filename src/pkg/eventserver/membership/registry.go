@@ -2,16 +2,27 @@ package membership
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
+	"time"
 
-	clientv3 "go.etcd.io/etcd/client/v3"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 )
 
+const (
+	// maxHealthyStaleness is for how long the registry can go without a confirmation from the backend.
+	maxHealthyStaleness = 30 * time.Second
+
+	minResyncBackoff = 100 * time.Millisecond
+	maxResyncBackoff = 30 * time.Second
+)
+
 type SelectedServer struct {
 	ID          string
 	GRPCAddress string
@@ -23,7 +34,12 @@ type ServerInfoResolver interface {
 }
 
 type ServerSelector interface {
-	SelectServerForConnection(ctx context.Context) (ServerInfo, error)
+	SelectServerForConnection(ctx context.Context, userID pkgid.ID) (ServerInfo, error)
+}
+
+// HealthChecker reports whether the component can be relied on.
+type HealthChecker interface {
+	CheckHealth() error
 }
 
 // ServerRegistry is a registry of all currently available event servers.
@@ -31,47 +47,74 @@ type ServerSelector interface {
 type ServerRegistry struct {
 	mu sync.RWMutex
 
-	etcdClient *clientv3.Client
-	servers    map[string]ServerInfo
+	backend  Backend
+	servers  map[string]ServerInfo
+	strategy SelectionStrategy
+
+	// removalListeners are notified about servers which have left the registry
+	removalListeners []func(serverID string)
 
+	// syncedAt is the last time the backend confirmed the servers are up-to-date
+	syncedAt time.Time
+
+	started bool
 	closeCh chan struct{}
+	doneCh  chan struct{}
+
+	resyncs *prometheus.CounterVec
 
-	i pkginstrument.Instrumentation
+	now func() time.Time
+	i   pkginstrument.Instrumentation
 }
 
-func NewServerRegistry(i pkginstrument.Instrumentation, etcdClient *clientv3.Client) *ServerRegistry {
-	return &ServerRegistry{
-		etcdClient: etcdClient,
-		servers:    map[string]ServerInfo{},
+func NewServerRegistry(i pkginstrument.Instrumentation, backend Backend, strategy SelectionStrategy) (*ServerRegistry, error) {
+	r := &ServerRegistry{
+		backend:  backend,
+		servers:  map[string]ServerInfo{},
+		strategy: strategy,
 
 		closeCh: make(chan struct{}),
-		i:       i,
+		doneCh:  make(chan struct{}),
+
+		resyncs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "event_server_registry_resyncs_total",
+			Help: "Number of times the event server registry re-listed servers after its watch broke.",
+		}, []string{"reason"}),
+
+		now: time.Now,
+		i:   i,
 	}
-}
 
-func (r *ServerRegistry) Start(ctx context.Context) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	if err := i.Registerer.Register(r.resyncs); err != nil {
+		return nil, fmt.Errorf("registering resyncs metric: %w", err)
+	}
 
-	getResp, err := r.etcdClient.Get(ctx, eventServersNamespace, clientv3.WithPrefix())
-	if err != nil {
-		return fmt.Errorf("getting initial servers: %w", err)
+	staleness := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "event_server_registry_staleness_seconds",
+		Help: "Time since the backend last confirmed the event server registry is up-to-date.",
+	}, func() float64 {
+		return r.Staleness().Seconds()
+	})
+	if err := i.Registerer.Register(staleness); err != nil {
+		return nil, fmt.Errorf("registering staleness metric: %w", err)
 	}
 
-	for _, kv := range getResp.Kvs {
-		var info ServerInfo
-		if err = info.Unmarshall(kv.Value); err != nil {
-			return fmt.Errorf("unmarshaling server info: %w", err)
-		}
+	return r, nil
+}
 
-		r.servers[info.ID] = info
+// Start lists the current servers and keeps following the changes in the background.
+func (r *ServerRegistry) Start(ctx context.Context) error {
+	revision, err := r.sync(ctx)
+	if err != nil {
+		return fmt.Errorf("getting initial servers: %w", err)
 	}
 
+	r.mu.Lock()
 	r.i.Logger.Info("discovered event servers", zap.Any("servers", r.servers))
+	r.started = true
+	r.mu.Unlock()
 
-	watchStartRevision := getResp.Header.Revision + 1
-	watchChan := r.etcdClient.Watch(ctx, eventServersNamespace, clientv3.WithRev(watchStartRevision), clientv3.WithPrefix())
-	go r.watchForUpdates(watchChan)
+	go r.run(revision)
 
 	return nil
 }
@@ -88,87 +131,254 @@ func (r *ServerRegistry) Resolve(_ context.Context, serverID string) (ServerInfo
 	return info, nil
 }
 
-func (r *ServerRegistry) SelectServerForConnection(_ context.Context) (ServerInfo, error) {
+func (r *ServerRegistry) SelectServerForConnection(_ context.Context, userID pkgid.ID) (ServerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := r.availableServers()
+	if len(candidates) == 0 {
+		return ServerInfo{}, fmt.Errorf("no available servers in the registry")
+	}
+
+	selected := r.strategy.Select(userID, candidates)
+
+	// the published load is refreshed only periodically, so until then the connection
+	// is accounted locally to not direct every new client to the same server
+	info := r.servers[selected.ID]
+	info.Load.Connections++
+	r.servers[selected.ID] = info
+
+	return selected, nil
+}
+
+// ServerForUser returns the server the user is placed on by consistent hashing.
+func (r *ServerRegistry) ServerForUser(_ context.Context, userID pkgid.ID) (ServerInfo, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if len(r.servers) == 0 {
-		return ServerInfo{}, fmt.Errorf("no servers in the registry")
+	candidates := r.availableServers()
+	if len(candidates) == 0 {
+		return ServerInfo{}, fmt.Errorf("no available servers in the registry")
 	}
 
-	// TODO: find a better way to select a server based on load, and more efficient of course
-	var (
-		num = rand.Intn(len(r.servers))
-		i   int
-	)
+	return ConsistentHash{}.Select(userID, candidates), nil
+}
+
+// availableServers returns servers accepting new connections. Has to be called holding the lock.
+func (r *ServerRegistry) availableServers() []ServerInfo {
+	candidates := make([]ServerInfo, 0, len(r.servers))
 	for _, s := range r.servers {
-		if i == num {
-			return s, nil
+		if !s.Draining {
+			candidates = append(candidates, s)
 		}
-		i++
 	}
-	panic("not reachable")
+	return candidates
 }
 
-// TODO: current implementation is not resistant of transient etcd failures, add retries
-func (r *ServerRegistry) watchForUpdates(ch clientv3.WatchChan) {
+// run watches for changes after the revision. Whenever the watch breaks, the servers
+// are listed again, so the changes missed meanwhile are not lost, and the watch is
+// restarted from the revision of the listing.
+func (r *ServerRegistry) run(revision int64) {
+	defer close(r.doneCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.closeCh
+		cancel()
+	}()
+
 	for {
-		select {
-		case <-r.closeCh:
+		err := r.backend.Watch(ctx, revision, registryWatchHandler{r: r})
+		if err == nil {
 			r.i.Logger.Info("closing server registry")
 			return
-		case watchEvent := <-ch:
-			if watchEvent.Canceled {
-				r.i.Logger.Info("watch got cancelled")
-				return
-			}
-
-			if err := watchEvent.Err(); err != nil {
-				r.i.Logger.Error("watch received an error", zap.Error(err))
-				return
-			}
-
-			for _, e := range watchEvent.Events {
-				switch e.Type {
-				case clientv3.EventTypePut:
-					var info ServerInfo
-					if err := info.Unmarshall(e.Kv.Value); err != nil {
-						r.i.Logger.Error("failed to unmarshal server info from watcher", zap.Error(err))
-						continue
-					}
-
-					r.mu.Lock()
-					_, ok := r.servers[info.ID]
-					r.servers[info.ID] = info
-					r.mu.Unlock()
-
-					if ok {
-						r.i.Logger.Debug("event server info updated", zap.String("serverId", info.ID))
-					} else {
-						r.i.Logger.Info("new event server added", zap.String("serverId", info.ID))
-					}
-				case clientv3.EventTypeDelete:
-					serverID := string(e.Kv.Key[len(eventServersNamespace):])
-
-					r.mu.Lock()
-					if _, ok := r.servers[serverID]; !ok {
-						r.mu.Unlock()
-						r.i.Logger.Warn("trying to remove server which was not present", zap.String("serverId", serverID))
-						continue
-					}
-
-					delete(r.servers, serverID)
-					r.mu.Unlock()
-
-					r.i.Logger.Info("removed server", zap.String("serverId", serverID))
-				}
-			}
+		}
+
+		reason := "error"
+		var brokenErr watchBrokenError
+		if errors.As(err, &brokenErr) {
+			reason = brokenErr.reason
+		}
+
+		r.i.Logger.Warn("watch broke, re-syncing servers", zap.String("reason", reason), zap.Error(err))
+		r.resyncs.WithLabelValues(reason).Inc()
+
+		var ok bool
+		if revision, ok = r.resyncWithBackoff(); !ok {
+			return
 		}
 	}
 }
 
-func (r *ServerRegistry) Close(_ context.Context) error {
-	close(r.closeCh)
+// registryWatchHandler applies the changes reported by the backend, it keeps the
+// handler methods out of the registry's API.
+type registryWatchHandler struct {
+	r *ServerRegistry
+}
+
+func (h registryWatchHandler) ServerUpdated(info ServerInfo) {
+	h.r.mu.Lock()
+	_, ok := h.r.servers[info.ID]
+	h.r.servers[info.ID] = info
+	h.r.mu.Unlock()
+
+	if ok {
+		h.r.i.Logger.Debug("event server info updated", zap.String("serverId", info.ID))
+	} else {
+		h.r.i.Logger.Info("new event server added", zap.String("serverId", info.ID))
+	}
+}
+
+func (h registryWatchHandler) ServerRemoved(serverID string) {
+	h.r.mu.Lock()
+	if _, ok := h.r.servers[serverID]; !ok {
+		h.r.mu.Unlock()
+		h.r.i.Logger.Warn("trying to remove server which was not present", zap.String("serverId", serverID))
+		return
+	}
+
+	delete(h.r.servers, serverID)
+	h.r.mu.Unlock()
+
+	h.r.i.Logger.Info("removed server", zap.String("serverId", serverID))
+	h.r.notifyRemoved(serverID)
+}
+
+func (h registryWatchHandler) ServersListed(servers []ServerInfo) {
+	h.r.replaceServers(servers)
+}
+
+func (h registryWatchHandler) Synced() {
+	h.r.markSynced()
+}
 
+// resyncWithBackoff lists the servers until it succeeds or the registry is closed.
+func (r *ServerRegistry) resyncWithBackoff() (int64, bool) {
+	backoff := minResyncBackoff
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), maxResyncBackoff)
+		revision, err := r.sync(ctx)
+		cancel()
+		if err == nil {
+			return revision, true
+		}
+
+		r.i.Logger.Warn("failed to re-sync servers, retrying", zap.Duration("backoff", backoff), zap.Error(err))
+
+		// jitter keeps gateways from hammering the backend in lockstep after an outage
+		timer := time.NewTimer(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)))
+		select {
+		case <-timer.C:
+		case <-r.closeCh:
+			timer.Stop()
+			return 0, false
+		}
+
+		if backoff *= 2; backoff > maxResyncBackoff {
+			backoff = maxResyncBackoff
+		}
+	}
+}
+
+// sync replaces the known servers with the ones listed from the backend and returns the revision of the listing.
+func (r *ServerRegistry) sync(ctx context.Context) (int64, error) {
+	servers, revision, err := r.backend.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	r.replaceServers(servers)
+	r.markSynced()
+
+	return revision, nil
+}
+
+// replaceServers swaps the known servers logging what has changed.
+func (r *ServerRegistry) replaceServers(listed []ServerInfo) {
+	servers := make(map[string]ServerInfo, len(listed))
+	for _, info := range listed {
+		servers[info.ID] = info
+	}
+
+	r.mu.Lock()
+	previous := r.servers
+	r.servers = servers
+	r.mu.Unlock()
+
+	for id := range servers {
+		if _, ok := previous[id]; !ok {
+			r.i.Logger.Info("new event server added", zap.String("serverId", id))
+		}
+	}
+	for id := range previous {
+		if _, ok := servers[id]; !ok {
+			r.i.Logger.Info("removed server", zap.String("serverId", id))
+			r.notifyRemoved(id)
+		}
+	}
+}
+
+// OnServerRemoved registers the function to be called with IDs of servers which have left
+// the registry. It is called synchronously with the updates, so it should not block.
+func (r *ServerRegistry) OnServerRemoved(fn func(serverID string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removalListeners = append(r.removalListeners, fn)
+}
+
+func (r *ServerRegistry) notifyRemoved(serverID string) {
+	r.mu.RLock()
+	listeners := r.removalListeners
+	r.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(serverID)
+	}
+}
+
+func (r *ServerRegistry) markSynced() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.syncedAt = r.now()
+}
+
+// Staleness returns how long ago the backend last confirmed the servers are up-to-date.
+func (r *ServerRegistry) Staleness() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.syncedAt.IsZero() {
+		return 0
+	}
+	return r.now().Sub(r.syncedAt)
+}
+
+// CheckHealth fails if the registry has not heard from the backend for too long, so
+// the servers it knows about might not exist anymore.
+func (r *ServerRegistry) CheckHealth() error {
+	if staleness := r.Staleness(); staleness > maxHealthyStaleness {
+		return fmt.Errorf("event server registry is stale for %v", staleness.Round(time.Second))
+	}
 	return nil
 }
+
+func (r *ServerRegistry) Close(ctx context.Context) error {
+	close(r.closeCh)
+
+	r.mu.RLock()
+	started := r.started
+	r.mu.RUnlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-r.doneCh:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the watch to stop: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,107 @@
+package membership
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestServerRegistry_AccountsSelectedConnections(t *testing.T) {
+	r := newTestRegistry(t, LeastConnections{})
+	r.servers = map[string]ServerInfo{
+		"a": {ID: "a", Load: ServerLoad{Connections: 1}},
+		"b": {ID: "b", Load: ServerLoad{Connections: 2}},
+		"d": {ID: "d", Draining: true},
+	}
+
+	// "a" is picked until its locally accounted connections catch up with "b"
+	var picked []string
+	for i := 0; i < 3; i++ {
+		info, err := r.SelectServerForConnection(context.Background(), pkgid.NewID())
+		require.NoError(t, err)
+		picked = append(picked, info.ID)
+	}
+	require.Equal(t, "a", picked[0])
+	require.NotContains(t, picked, "d")
+	require.Equal(t, 6, r.servers["a"].Load.Connections+r.servers["b"].Load.Connections)
+}
+
+func TestServerRegistry_ReplaceServers(t *testing.T) {
+	r := newTestRegistry(t, Random{})
+	r.servers = map[string]ServerInfo{
+		"kept":    {ID: "kept"},
+		"removed": {ID: "removed"},
+	}
+
+	r.replaceServers([]ServerInfo{
+		{ID: "kept", Draining: true},
+		{ID: "added"},
+	})
+
+	_, err := r.Resolve(context.Background(), "removed")
+	require.Error(t, err)
+
+	info, err := r.Resolve(context.Background(), "kept")
+	require.NoError(t, err)
+	require.True(t, info.Draining)
+
+	_, err = r.Resolve(context.Background(), "added")
+	require.NoError(t, err)
+}
+
+func TestServerRegistry_NotifiesRemovedServers(t *testing.T) {
+	r := newTestRegistry(t, Random{})
+	r.servers = map[string]ServerInfo{
+		"listed":  {ID: "listed"},
+		"watched": {ID: "watched"},
+		"kept":    {ID: "kept"},
+	}
+
+	var removed []string
+	r.OnServerRemoved(func(serverID string) {
+		removed = append(removed, serverID)
+	})
+
+	handler := registryWatchHandler{r: r}
+	handler.ServerRemoved("watched")
+	handler.ServerRemoved("unknown")
+	handler.ServersListed([]ServerInfo{{ID: "kept"}})
+
+	require.Equal(t, []string{"watched", "listed"}, removed)
+}
+
+func TestServerRegistry_Staleness(t *testing.T) {
+	var (
+		r   = newTestRegistry(t, Random{})
+		now = time.Now()
+	)
+	r.now = func() time.Time { return now }
+
+	r.markSynced()
+	require.NoError(t, r.CheckHealth())
+
+	now = now.Add(maxHealthyStaleness + time.Second)
+	require.Equal(t, maxHealthyStaleness+time.Second, r.Staleness())
+	require.Error(t, r.CheckHealth())
+
+	r.markSynced()
+	require.Zero(t, r.Staleness())
+}
+
+func newTestRegistry(t *testing.T, strategy SelectionStrategy) *ServerRegistry {
+	i := pkginstrument.Instrumentation{
+		Logger:     pkgtest.Instrumentation.Logger,
+		Registerer: prometheus.NewRegistry(),
+	}
+
+	r, err := NewServerRegistry(i, nil, strategy)
+	require.NoError(t, err)
+	return r
+}
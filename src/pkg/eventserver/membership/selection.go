@@ -0,0 +1,176 @@
+package membership
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+type SelectionStrategyType string
+
+const (
+	SelectionStrategyRandom           SelectionStrategyType = "random"
+	SelectionStrategyLeastConnections SelectionStrategyType = "leastConnections"
+	SelectionStrategyPowerOfTwo       SelectionStrategyType = "powerOfTwoChoices"
+	SelectionStrategyWeighted         SelectionStrategyType = "weightedByCapacity"
+	SelectionStrategyConsistentHash   SelectionStrategyType = "consistentHash"
+)
+
+// SelectionConfiguration chooses how servers are picked for new connections.
+type SelectionConfiguration struct {
+	Strategy SelectionStrategyType `yaml:"strategy"`
+}
+
+func (c SelectionConfiguration) Build() (SelectionStrategy, error) {
+	switch c.Strategy {
+	case "", SelectionStrategyPowerOfTwo:
+		return PowerOfTwoChoices{}, nil
+	case SelectionStrategyLeastConnections:
+		return LeastConnections{}, nil
+	case SelectionStrategyWeighted:
+		return WeightedByCapacity{}, nil
+	case SelectionStrategyRandom:
+		return Random{}, nil
+	case SelectionStrategyConsistentHash:
+		return ConsistentHash{}, nil
+	default:
+		return nil, fmt.Errorf("unknown selection strategy %q", c.Strategy)
+	}
+}
+
+// SelectionStrategy picks one of the candidate servers for the user. Candidates are never empty.
+type SelectionStrategy interface {
+	Select(userID pkgid.ID, candidates []ServerInfo) ServerInfo
+}
+
+type Random struct{}
+
+func (Random) Select(_ pkgid.ID, candidates []ServerInfo) ServerInfo {
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// LeastConnections picks the server with the fewest connections, ties are broken randomly.
+type LeastConnections struct{}
+
+func (LeastConnections) Select(_ pkgid.ID, candidates []ServerInfo) ServerInfo {
+	var (
+		best  ServerInfo
+		ties  int
+		found bool
+	)
+	for _, c := range candidates {
+		switch {
+		case !found || c.Load.Connections < best.Load.Connections:
+			best, ties, found = c, 1, true
+		case c.Load.Connections == best.Load.Connections:
+			// reservoir sampling keeps every tied server equally likely
+			ties++
+			if rand.Intn(ties) == 0 {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+// PowerOfTwoChoices picks the less loaded of two random servers. It is less prone
+// than LeastConnections to sending every new connection to the same server while
+// the published load is not refreshed yet.
+type PowerOfTwoChoices struct{}
+
+func (PowerOfTwoChoices) Select(_ pkgid.ID, candidates []ServerInfo) ServerInfo {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	first := rand.Intn(len(candidates))
+	second := rand.Intn(len(candidates) - 1)
+	if second >= first {
+		second++
+	}
+
+	a, b := candidates[first], candidates[second]
+	if lessLoaded(b.Load, a.Load) {
+		return b
+	}
+	return a
+}
+
+func lessLoaded(a, b ServerLoad) bool {
+	if a.Connections != b.Connections {
+		return a.Connections < b.Connections
+	}
+	return a.QueuedEvents < b.QueuedEvents
+}
+
+// WeightedByCapacity picks servers randomly with probability proportional to their
+// free capacity. Servers which do not publish capacity are weighted as having a single
+// free slot, if no server has free capacity the choice is uniform.
+type WeightedByCapacity struct{}
+
+func (WeightedByCapacity) Select(_ pkgid.ID, candidates []ServerInfo) ServerInfo {
+	weights := make([]int, len(candidates))
+	total := 0
+	for idx, c := range candidates {
+		weights[idx] = freeCapacity(c.Load)
+		total += weights[idx]
+	}
+
+	if total == 0 {
+		return Random{}.Select(pkgid.ID{}, candidates)
+	}
+
+	n := rand.Intn(total)
+	for idx, weight := range weights {
+		if n < weight {
+			return candidates[idx]
+		}
+		n -= weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+func freeCapacity(load ServerLoad) int {
+	if load.Capacity <= 0 {
+		return 1
+	}
+	if load.Connections >= load.Capacity {
+		return 0
+	}
+	return load.Capacity - load.Connections
+}
+
+// ConsistentHash keeps users on the same server using rendezvous hashing: every server
+// is scored by the hash of its ID together with the user's and the highest score wins.
+// When a server joins or leaves, only the users which it wins or has won move.
+type ConsistentHash struct{}
+
+func (ConsistentHash) Select(userID pkgid.ID, candidates []ServerInfo) ServerInfo {
+	var (
+		best      ServerInfo
+		bestScore uint64
+	)
+	for idx, c := range candidates {
+		if score := rendezvousScore(userID, c.ID); idx == 0 || score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+func rendezvousScore(userID pkgid.ID, serverID string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(serverID))
+	_, _ = h.Write(userID[:])
+
+	// FNV alone spreads similar server IDs poorly, the splitmix64 finalizer mixes the bits
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
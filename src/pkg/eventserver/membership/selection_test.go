@@ -0,0 +1,103 @@
+package membership
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+func TestSelectionConfiguration_Build(t *testing.T) {
+	strategy, err := SelectionConfiguration{}.Build()
+	require.NoError(t, err)
+	require.IsType(t, PowerOfTwoChoices{}, strategy)
+
+	strategy, err = SelectionConfiguration{Strategy: SelectionStrategyLeastConnections}.Build()
+	require.NoError(t, err)
+	require.IsType(t, LeastConnections{}, strategy)
+
+	_, err = SelectionConfiguration{Strategy: "roundRobin"}.Build()
+	require.Error(t, err)
+}
+
+func TestLeastConnections_Select(t *testing.T) {
+	candidates := []ServerInfo{
+		{ID: "a", Load: ServerLoad{Connections: 10}},
+		{ID: "b", Load: ServerLoad{Connections: 3}},
+		{ID: "c", Load: ServerLoad{Connections: 7}},
+	}
+
+	for i := 0; i < 20; i++ {
+		require.Equal(t, "b", LeastConnections{}.Select(pkgid.NewID(), candidates).ID)
+	}
+}
+
+func TestPowerOfTwoChoices_NeverPicksTheMostLoaded(t *testing.T) {
+	candidates := []ServerInfo{
+		{ID: "a", Load: ServerLoad{Connections: 1}},
+		{ID: "b", Load: ServerLoad{Connections: 2}},
+		{ID: "c", Load: ServerLoad{Connections: 100}},
+	}
+
+	for i := 0; i < 100; i++ {
+		require.NotEqual(t, "c", PowerOfTwoChoices{}.Select(pkgid.NewID(), candidates).ID)
+	}
+
+	require.Equal(t, "a", PowerOfTwoChoices{}.Select(pkgid.NewID(), candidates[:1]).ID)
+}
+
+func TestWeightedByCapacity_SkipsFullServers(t *testing.T) {
+	candidates := []ServerInfo{
+		{ID: "full", Load: ServerLoad{Connections: 10, Capacity: 10}},
+		{ID: "free", Load: ServerLoad{Connections: 2, Capacity: 10}},
+	}
+
+	for i := 0; i < 100; i++ {
+		require.Equal(t, "free", WeightedByCapacity{}.Select(pkgid.NewID(), candidates).ID)
+	}
+
+	// uniform choice when all servers are full
+	candidates[1].Load.Connections = 10
+	require.NotEmpty(t, WeightedByCapacity{}.Select(pkgid.NewID(), candidates).ID)
+}
+
+func TestConsistentHash_MovesOnlyUsersOfChangedServers(t *testing.T) {
+	var (
+		servers = []ServerInfo{{ID: "es-1"}, {ID: "es-2"}, {ID: "es-3"}, {ID: "es-4"}}
+		users   = make([]pkgid.ID, 1000)
+		before  = map[pkgid.ID]string{}
+		counts  = map[string]int{}
+	)
+	for idx := range users {
+		users[idx] = pkgid.NewID()
+		before[users[idx]] = ConsistentHash{}.Select(users[idx], servers).ID
+		counts[before[users[idx]]]++
+	}
+
+	for _, server := range servers {
+		require.Greater(t, counts[server.ID], 150, "users should be spread evenly")
+	}
+
+	// the same user is always placed on the same server
+	for _, userID := range users[:10] {
+		require.Equal(t, before[userID], ConsistentHash{}.Select(userID, servers).ID)
+	}
+
+	// only users of the removed server move
+	for _, userID := range users {
+		after := ConsistentHash{}.Select(userID, servers[1:]).ID
+		if before[userID] != "es-1" {
+			require.Equal(t, before[userID], after)
+		}
+	}
+
+	// a joined server only takes users over
+	joined := append(servers, ServerInfo{ID: "es-5"})
+	for _, userID := range users {
+		after := ConsistentHash{}.Select(userID, joined).ID
+		if after != "es-5" {
+			require.Equal(t, before[userID], after)
+		}
+	}
+}
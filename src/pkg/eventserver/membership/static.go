@@ -0,0 +1,83 @@
+package membership
+
+import (
+	"context"
+	"time"
+)
+
+// staticSyncInterval is how often the static backend confirms its servers are up-to-date.
+const staticSyncInterval = 5 * time.Second
+
+// staticBackend serves a fixed list of servers, it is meant for single-node deployments
+// and tests where running a coordination service is not worth it.
+type staticBackend struct {
+	servers []ServerInfo
+}
+
+func NewStaticBackend(servers []StaticServer) Backend {
+	infos := make([]ServerInfo, 0, len(servers))
+	for _, s := range servers {
+		infos = append(infos, ServerInfo{
+			ID:          s.ID,
+			GRPCAddress: s.GRPCAddress,
+			HTTPAddress: s.HTTPAddress,
+			Load:        ServerLoad{Capacity: s.Capacity},
+		})
+	}
+
+	return &staticBackend{servers: infos}
+}
+
+func (b *staticBackend) List(_ context.Context) ([]ServerInfo, int64, error) {
+	servers := make([]ServerInfo, len(b.servers))
+	copy(servers, b.servers)
+	return servers, 0, nil
+}
+
+// Watch reports no changes, the list never changes.
+func (b *staticBackend) Watch(ctx context.Context, _ int64, handler WatchHandler) error {
+	ticker := time.NewTicker(staticSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			handler.Synced()
+		}
+	}
+}
+
+func (b *staticBackend) NewRegistration() Registration {
+	return staticRegistration{}
+}
+
+func (b *staticBackend) Close(_ context.Context) error {
+	return nil
+}
+
+// staticRegistration does nothing, the server is expected to be in the configured list.
+// Epochs are not tracked, so records of restarted servers are never fenced off.
+type staticRegistration struct{}
+
+func (staticRegistration) Start(_ context.Context) error {
+	return nil
+}
+
+func (staticRegistration) Join(_ context.Context, _ ServerInfo) (int64, error) {
+	return 0, nil
+}
+
+func (staticRegistration) Publish(_ context.Context, _ ServerInfo) error {
+	return nil
+}
+
+// LostC returns a channel which is never closed.
+func (staticRegistration) LostC() <-chan struct{} {
+	return nil
+}
+
+func (staticRegistration) Close(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package membership
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pkgetcd "github.com/faustuzas/occa/src/pkg/etcd"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestConfiguration_Validate(t *testing.T) {
+	require.NoError(t, Configuration{}.Validate())
+	require.NoError(t, Configuration{Backend: BackendRedis}.Validate())
+	require.Error(t, Configuration{Backend: BackendStatic}.Validate())
+	require.Error(t, Configuration{Backend: "zookeeper"}.Validate())
+}
+
+func TestStaticBackend_ServesConfiguredServers(t *testing.T) {
+	backend, err := Configuration{
+		Backend: BackendStatic,
+		Static: []StaticServer{
+			{ID: "es-1", GRPCAddress: "localhost:9000", Capacity: 100},
+			{ID: "es-2", GRPCAddress: "localhost:9001"},
+		},
+	}.Build(pkgtest.Instrumentation, pkgetcd.Configuration{}, nil)
+	require.NoError(t, err)
+
+	r := newTestRegistry(t, LeastConnections{})
+	r.backend = backend
+
+	require.NoError(t, r.Start(context.Background()))
+	defer func() {
+		require.NoError(t, r.Close(context.Background()))
+	}()
+
+	info, err := r.Resolve(context.Background(), "es-1")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:9000", info.GRPCAddress)
+	require.Equal(t, 100, info.Load.Capacity)
+
+	_, err = r.Resolve(context.Background(), "es-2")
+	require.NoError(t, err)
+
+	// servers join the cluster without any coordination
+	registration := backend.NewRegistration()
+	require.NoError(t, registration.Start(context.Background()))
+
+	epoch, err := registration.Join(context.Background(), ServerInfo{ID: "es-1"})
+	require.NoError(t, err)
+	require.Zero(t, epoch)
+	require.NoError(t, registration.Close(context.Background()))
+}
@@ -0,0 +1,133 @@
+package pending
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+const (
+	pendingEventsCollection = "pending_events"
+
+	defaultMaxLength = 1000
+	defaultRetention = 7 * 24 * time.Hour
+)
+
+type Configuration struct {
+	// MaxLength is the maximum number of events kept for a single user. When it is
+	// exceeded, the oldest events are discarded.
+	MaxLength int `yaml:"maxLength"`
+
+	// Retention is how long an event is kept waiting for the user to connect.
+	Retention time.Duration `yaml:"retention"`
+}
+
+func (c Configuration) withDefaults() Configuration {
+	if c.MaxLength <= 0 {
+		c.MaxLength = defaultMaxLength
+	}
+
+	if c.Retention <= 0 {
+		c.Retention = defaultRetention
+	}
+
+	return c
+}
+
+func (c Configuration) Build(i pkginstrument.Instrumentation, store pkgmemstore.Store) Queue {
+	return NewMemStoreQueue(i, store, c)
+}
+
+// Queue keeps events for users which are not connected to any event server at the moment
+// so they could be delivered once the user connects.
+type Queue interface {
+	Enqueue(ctx context.Context, userID pkgid.ID, event *rteventspb.Event) error
+
+	// Drain removes and returns all non-expired events pending for the user, from the oldest to the newest.
+	Drain(ctx context.Context, userID pkgid.ID) ([]*rteventspb.Event, error)
+}
+
+type entry struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	Event     []byte    `json:"event"`
+}
+
+type memStoreQueue struct {
+	store  pkgmemstore.Store
+	config Configuration
+
+	i pkginstrument.Instrumentation
+}
+
+func NewMemStoreQueue(i pkginstrument.Instrumentation, store pkgmemstore.Store, config Configuration) Queue {
+	return &memStoreQueue{
+		store:  store,
+		config: config.withDefaults(),
+
+		i: i,
+	}
+}
+
+func (q *memStoreQueue) Enqueue(ctx context.Context, userID pkgid.ID, event *rteventspb.Event) error {
+	eventBytes, err := proto.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %w", err)
+	}
+
+	data, err := json.Marshal(entry{
+		ExpiresAt: time.Now().Add(q.config.Retention),
+		Event:     eventBytes,
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling entry: %w", err)
+	}
+
+	err = q.store.AppendToCollectionList(ctx, pendingEventsCollection, userID.String(), data, q.config.MaxLength, q.config.Retention)
+	if err != nil {
+		return fmt.Errorf("storing pending event: %w", err)
+	}
+
+	return nil
+}
+
+func (q *memStoreQueue) Drain(ctx context.Context, userID pkgid.ID) ([]*rteventspb.Event, error) {
+	values, err := q.store.PopCollectionList(ctx, pendingEventsCollection, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("fetching pending events: %w", err)
+	}
+
+	var (
+		now    = time.Now()
+		events = make([]*rteventspb.Event, 0, len(values))
+	)
+	for _, v := range values {
+		var e entry
+		if err = json.Unmarshal(v, &e); err != nil {
+			q.i.Logger.Error("dropping malformed pending event", zap.Stringer("userId", userID), zap.Error(err))
+			continue
+		}
+
+		if now.After(e.ExpiresAt) {
+			continue
+		}
+
+		var event rteventspb.Event
+		if err = proto.Unmarshal(e.Event, &event); err != nil {
+			q.i.Logger.Error("dropping malformed pending event", zap.Stringer("userId", userID), zap.Error(err))
+			continue
+		}
+
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,69 @@
+package pending
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestQueue_EnqueueRespectsLimits(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+
+		userID = pkgid.NewID()
+		queue  = NewMemStoreQueue(pkgtest.Instrumentation, store, Configuration{MaxLength: 5, Retention: time.Hour})
+	)
+
+	store.EXPECT().
+		AppendToCollectionList(gomock.Any(), pendingEventsCollection, userID.String(), gomock.Any(), 5, time.Hour).
+		Return(nil)
+
+	require.NoError(t, queue.Enqueue(context.Background(), userID, rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hi")))
+}
+
+func TestQueue_DrainSkipsExpiredEvents(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+
+		userID = pkgid.NewID()
+		queue  = NewMemStoreQueue(pkgtest.Instrumentation, store, Configuration{})
+
+		expired = rteventspb.NewDirectMessageEvent(pkgid.NewID(), "expired")
+		valid   = rteventspb.NewDirectMessageEvent(pkgid.NewID(), "valid")
+	)
+
+	store.EXPECT().
+		PopCollectionList(gomock.Any(), pendingEventsCollection, userID.String()).
+		Return([][]byte{
+			toEntry(t, expired, time.Now().Add(-time.Minute)),
+			[]byte("malformed"),
+			toEntry(t, valid, time.Now().Add(time.Minute)),
+		}, nil)
+
+	events, err := queue.Drain(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.True(t, proto.Equal(valid, events[0]))
+}
+
+func toEntry(t *testing.T, event *rteventspb.Event, expiresAt time.Time) []byte {
+	eventBytes, err := proto.Marshal(event)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(entry{ExpiresAt: expiresAt, Event: eventBytes})
+	require.NoError(t, err)
+
+	return data
+}
@@ -0,0 +1,149 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+const (
+	sequencesCollection = "event_sequences"
+	eventsCollection    = "replay_events"
+
+	defaultStoredEvents = 1000
+	defaultRetention    = 24 * time.Hour
+)
+
+type Configuration struct {
+	// StoredEvents is the number of the latest events of a user kept in the memstore,
+	// so the gap can be replayed by any server.
+	StoredEvents int `yaml:"storedEvents"`
+
+	// Retention is for how long events and sequence numbers are kept after the last
+	// event of the user. Sequences of users inactive for longer start over.
+	Retention time.Duration `yaml:"retention"`
+}
+
+func (c Configuration) withDefaults() Configuration {
+	if c.StoredEvents <= 0 {
+		c.StoredEvents = defaultStoredEvents
+	}
+
+	if c.Retention <= 0 {
+		c.Retention = defaultRetention
+	}
+
+	return c
+}
+
+func (c Configuration) Build(i pkginstrument.Instrumentation, store pkgmemstore.Store) Log {
+	return NewMemStoreLog(i, store, c)
+}
+
+// Log assigns per-user sequence numbers to events and keeps the latest of them, so clients
+// reconnecting after a short break can catch up. An event is recorded once before it is sent
+// to the servers of the user, so all of them deliver it with the same sequence number.
+type Log interface {
+	// Record returns a copy of the event with the next sequence number of the user assigned.
+	Record(ctx context.Context, userID pkgid.ID, event *rteventspb.Event) (*rteventspb.Event, error)
+
+	// Since returns recorded events of the user with sequence numbers greater than the given one,
+	// ordered by the sequence numbers.
+	Since(ctx context.Context, userID pkgid.ID, sequence uint64) ([]*rteventspb.Event, error)
+
+	// LastSequence returns the sequence number assigned to the latest event of the user,
+	// zero if none has been assigned.
+	LastSequence(ctx context.Context, userID pkgid.ID) (uint64, error)
+}
+
+type memStoreLog struct {
+	store  pkgmemstore.Store
+	config Configuration
+
+	i pkginstrument.Instrumentation
+}
+
+func NewMemStoreLog(i pkginstrument.Instrumentation, store pkgmemstore.Store, config Configuration) Log {
+	return &memStoreLog{
+		store:  store,
+		config: config.withDefaults(),
+
+		i: i,
+	}
+}
+
+func (l *memStoreLog) Record(ctx context.Context, userID pkgid.ID, event *rteventspb.Event) (*rteventspb.Event, error) {
+	sequence, err := l.store.IncrementCollectionCounter(ctx, sequencesCollection, userID.String(), l.config.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("assigning sequence number: %w", err)
+	}
+
+	sequenced := proto.Clone(event).(*rteventspb.Event)
+	sequenced.Sequence = uint64(sequence)
+
+	data, err := proto.Marshal(sequenced)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling event: %w", err)
+	}
+
+	// the event is still delivered live if it cannot be stored, only replay would miss it
+	err = l.store.AppendToCollectionList(ctx, eventsCollection, userID.String(), data, l.config.StoredEvents, l.config.Retention)
+	if err != nil {
+		l.i.Logger.Warn("failed to store event for replay", zap.Stringer("userId", userID), zap.Error(err))
+	}
+
+	return sequenced, nil
+}
+
+func (l *memStoreLog) Since(ctx context.Context, userID pkgid.ID, sequence uint64) ([]*rteventspb.Event, error) {
+	values, err := l.store.GetCollectionList(ctx, eventsCollection, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("getting stored events: %w", err)
+	}
+
+	var events []*rteventspb.Event
+	for _, data := range values {
+		var event rteventspb.Event
+		if err = proto.Unmarshal(data, &event); err != nil {
+			return nil, fmt.Errorf("unmarshalling stored event: %w", err)
+		}
+
+		if event.Sequence > sequence {
+			events = append(events, &event)
+		}
+	}
+
+	// concurrently recorded events might be stored slightly out of order
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Sequence < events[j].Sequence
+	})
+	return events, nil
+}
+
+func (l *memStoreLog) LastSequence(ctx context.Context, userID pkgid.ID) (uint64, error) {
+	// counters are kept as decimal numbers
+	value, err := l.store.GetCollectionItem(ctx, sequencesCollection, userID.String())
+	if errors.Is(err, pkgmemstore.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getting sequence number: %w", err)
+	}
+
+	sequence, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing sequence number: %w", err)
+	}
+	return sequence, nil
+}
@@ -0,0 +1,93 @@
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/faustuzas/occa/src/pkg/generated/proto/rteventspb"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestMemStoreLog_RecordsSequencedCopy(t *testing.T) {
+	var (
+		ctrl   = gomock.NewController(t)
+		store  = pkgmemstore.NewMockStore(ctrl)
+		log    = NewMemStoreLog(pkgtest.Instrumentation, store, Configuration{StoredEvents: 10})
+		userID = pkgid.NewID()
+		event  = rteventspb.NewDirectMessageEvent(pkgid.NewID(), "hi")
+	)
+
+	store.EXPECT().
+		IncrementCollectionCounter(gomock.Any(), sequencesCollection, userID.String(), defaultRetention).
+		Return(int64(7), nil)
+	store.EXPECT().
+		AppendToCollectionList(gomock.Any(), eventsCollection, userID.String(), gomock.Any(), 10, defaultRetention).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data []byte, _ int, _ time.Duration) error {
+			var stored rteventspb.Event
+			require.NoError(t, proto.Unmarshal(data, &stored))
+			require.Equal(t, uint64(7), stored.Sequence)
+			return nil
+		})
+
+	sequenced, err := log.Record(context.Background(), userID, event)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), sequenced.Sequence)
+	require.Zero(t, event.Sequence, "recorded event has to be a copy")
+}
+
+func TestMemStoreLog_SinceReturnsOrderedEvents(t *testing.T) {
+	var (
+		ctrl   = gomock.NewController(t)
+		store  = pkgmemstore.NewMockStore(ctrl)
+		log    = NewMemStoreLog(pkgtest.Instrumentation, store, Configuration{})
+		userID = pkgid.NewID()
+	)
+
+	// events recorded concurrently by several gateways
+	var stored [][]byte
+	for _, sequence := range []uint64{4, 6, 5} {
+		data, err := proto.Marshal(&rteventspb.Event{Sequence: sequence})
+		require.NoError(t, err)
+		stored = append(stored, data)
+	}
+
+	store.EXPECT().
+		GetCollectionList(gomock.Any(), eventsCollection, userID.String()).
+		Return(stored, nil)
+
+	events, err := log.Since(context.Background(), userID, 4)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(5), events[0].Sequence)
+	require.Equal(t, uint64(6), events[1].Sequence)
+}
+
+func TestMemStoreLog_LastSequence(t *testing.T) {
+	var (
+		ctrl   = gomock.NewController(t)
+		store  = pkgmemstore.NewMockStore(ctrl)
+		log    = NewMemStoreLog(pkgtest.Instrumentation, store, Configuration{})
+		userID = pkgid.NewID()
+	)
+
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), sequencesCollection, userID.String()).
+		Return(nil, pkgmemstore.ErrNotFound)
+	sequence, err := log.LastSequence(context.Background(), userID)
+	require.NoError(t, err)
+	require.Zero(t, sequence)
+
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), sequencesCollection, userID.String()).
+		Return([]byte("12"), nil)
+	sequence, err = log.LastSequence(context.Background(), userID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), sequence)
+}
@@ -23,33 +23,53 @@ const (
 	heartBeatInterval = 10 * time.Second
 )
 
+// HeartBeater keeps records about users connected to this server alive. Since a user
+// can be connected to several servers at once, every server maintains its own record.
+// Users are also kept online until they have no connections left.
 type HeartBeater interface {
 	pkgio.Closer
 
 	LaunchForUser(userID pkgid.ID) error
 	StopForUser(userId pkgid.ID) error
+
+	// AwaitRecord blocks until the launched heart of the user has stored its first record,
+	// so the user can be resolved to this server from then on.
+	AwaitRecord(ctx context.Context, userID pkgid.ID) error
 }
 
 type heart struct {
 	stopCh chan struct{}
+	doneCh chan struct{}
+	// storedCh is closed once the first record is stored
+	storedCh chan struct{}
 }
 
 type heartBeater struct {
 	mu sync.Mutex
 
 	serverID string
-	hearts   map[pkgid.ID]heart
+	// epoch of the server at the time of the launch is recorded in the connection
+	epoch  func() int64
+	hearts map[pkgid.ID]heart
+
+	// stopping holds done channels of stopped hearts which are still removing their records,
+	// so a heart relaunched right away does not get its fresh record removed
+	stopping map[pkgid.ID]chan struct{}
 
 	memstore memstore.Store
+	online   *OnlineUsers
 	i        pkginstrument.Instrumentation
 }
 
-func NewHeartBeater(i pkginstrument.Instrumentation, serverID string, store memstore.Store) HeartBeater {
+func NewHeartBeater(i pkginstrument.Instrumentation, serverID string, epoch func() int64, store memstore.Store) HeartBeater {
 	return &heartBeater{
 		serverID: serverID,
+		epoch:    epoch,
 		hearts:   map[pkgid.ID]heart{},
+		stopping: map[pkgid.ID]chan struct{}{},
 
 		memstore: store,
+		online:   NewOnlineUsers(i, store),
 		i:        i,
 	}
 }
@@ -62,39 +82,113 @@ func (b *heartBeater) LaunchForUser(userID pkgid.ID) error {
 		return fmt.Errorf("user already has heartbeat")
 	}
 
-	info := ConnectionInfo{ServerID: b.serverID}
+	info := ConnectionInfo{ServerID: b.serverID, Epoch: b.epoch()}
 	data, err := info.Marshall()
 	if err != nil {
 		return fmt.Errorf("failed to marshall connection info: %w", err)
 	}
 
-	h := heart{stopCh: make(chan struct{})}
+	h := heart{
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+		storedCh: make(chan struct{}),
+	}
 	b.hearts[userID] = h
 
-	go b.heartBeat(userID.String(), data, h.stopCh)
+	go b.heartBeat(userID, data, h, b.stopping[userID])
 
 	return nil
 }
 
-func (b *heartBeater) heartBeat(userID string, data []byte, stopCh chan struct{}) {
+func (b *heartBeater) heartBeat(userID pkgid.ID, data []byte, h heart, previousDoneCh chan struct{}) {
+	defer b.markDone(userID, h)
+
+	if previousDoneCh != nil {
+		<-previousDoneCh
+	}
+
 	ticker := time.NewTicker(addJitter(heartBeatInterval))
 	defer ticker.Stop()
 
 	ctx := context.Background()
-	for {
-		if err := b.memstore.SetCollectionItemWithTTL(ctx, connectionsNamespace, userID, data, heartBeatTTL); err != nil {
+	for stored := false; ; stored = true {
+		if err := b.memstore.AddToCollectionSet(ctx, connectionsNamespace, userID.String(), data, heartBeatTTL); err != nil {
 			b.i.Logger.Error("failed to store user connection information", zap.Error(err))
 			return
 		}
+		if !stored {
+			close(h.storedCh)
+		}
+
+		if err := b.online.MarkOnline(ctx, userID); err != nil {
+			b.i.Logger.Warn("failed to mark user online", zap.Stringer("userId", userID), zap.Error(err))
+		}
 
 		select {
 		case <-ticker.C:
-		case <-stopCh:
+		case <-h.stopCh:
+			// the record is removed right away instead of waiting for it to expire,
+			// otherwise the user looks reachable for a while after disconnecting
+			if err := b.memstore.RemoveFromCollectionSet(ctx, connectionsNamespace, userID.String(), data); err != nil {
+				b.i.Logger.Warn("failed to remove user connection information", zap.Stringer("userId", userID), zap.Error(err))
+			}
+			b.markOfflineIfDisconnected(ctx, userID)
 			return
 		}
 	}
 }
 
+// markOfflineIfDisconnected marks the user offline unless it is still connected to other servers.
+// Should the record removal have failed, the user stays online until the record expires.
+func (b *heartBeater) markOfflineIfDisconnected(ctx context.Context, userID pkgid.ID) {
+	records, err := b.memstore.ListCollectionSet(ctx, connectionsNamespace, userID.String())
+	if err != nil {
+		b.i.Logger.Warn("failed to list user connections", zap.Stringer("userId", userID), zap.Error(err))
+		return
+	}
+	if len(records) > 0 {
+		return
+	}
+
+	if err = b.online.MarkOffline(ctx, userID); err != nil {
+		b.i.Logger.Warn("failed to mark user offline", zap.Stringer("userId", userID), zap.Error(err))
+	}
+}
+
+func (b *heartBeater) AwaitRecord(ctx context.Context, userID pkgid.ID) error {
+	b.mu.Lock()
+	h, ok := b.hearts[userID]
+	b.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("user does not have heartbeat")
+	}
+
+	select {
+	case <-h.storedCh:
+		return nil
+	case <-h.doneCh:
+		select {
+		case <-h.storedCh:
+			return nil
+		default:
+			return fmt.Errorf("heartbeat stopped before storing the record")
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *heartBeater) markDone(userID pkgid.ID, h heart) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	close(h.doneCh)
+	if b.stopping[userID] == h.doneCh {
+		delete(b.stopping, userID)
+	}
+}
+
 func (b *heartBeater) StopForUser(userId pkgid.ID) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -110,13 +204,14 @@ func (b *heartBeater) stopForUserWithLock(userID pkgid.ID) error {
 
 	close(h.stopCh)
 	delete(b.hearts, userID)
+	b.stopping[userID] = h.doneCh
 
 	return nil
 }
 
-func (b *heartBeater) Close(_ context.Context) error {
+// Close stops all hearts and waits until their records are removed.
+func (b *heartBeater) Close(ctx context.Context) error {
 	b.mu.Lock()
-	defer b.mu.Unlock()
 
 	var multiErr error
 	for userID := range b.hearts {
@@ -124,6 +219,20 @@ func (b *heartBeater) Close(_ context.Context) error {
 			multiErr = multierr.Append(multiErr, err)
 		}
 	}
+
+	doneChs := make([]chan struct{}, 0, len(b.stopping))
+	for _, doneCh := range b.stopping {
+		doneChs = append(doneChs, doneCh)
+	}
+	b.mu.Unlock()
+
+	for _, doneCh := range doneChs {
+		select {
+		case <-doneCh:
+		case <-ctx.Done():
+			return multierr.Append(multiErr, ctx.Err())
+		}
+	}
 	return multiErr
 }
 
@@ -0,0 +1,90 @@
+package rtconn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestHeartBeater_StopRemovesConnectionRecord(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+
+		userID      = pkgid.NewID()
+		heartBeater = NewHeartBeater(pkgtest.Instrumentation, "server-1", func() int64 { return 1 }, store)
+	)
+
+	record, err := (&ConnectionInfo{ServerID: "server-1", Epoch: 1}).Marshall()
+	require.NoError(t, err)
+
+	var (
+		added   = make(chan struct{})
+		session []byte
+	)
+	store.EXPECT().
+		AddToCollectionSet(gomock.Any(), connectionsNamespace, userID.String(), record, heartBeatTTL).
+		Return(nil)
+	store.EXPECT().
+		GetCollectionItem(gomock.Any(), onlineNamespace, userID.String()).
+		Return(nil, pkgmemstore.ErrNotFound)
+	store.EXPECT().
+		SetCollectionItemIfAbsent(gomock.Any(), onlineNamespace, userID.String(), gomock.Any(), heartBeatTTL).
+		DoAndReturn(func(_ context.Context, _ string, _ string, value []byte, _ time.Duration) (bool, error) {
+			session = value
+			close(added)
+			return true, nil
+		})
+
+	// the user has no connections left, so it is marked offline
+	gomock.InOrder(
+		store.EXPECT().
+			RemoveFromCollectionSet(gomock.Any(), connectionsNamespace, userID.String(), record).
+			Return(nil),
+		store.EXPECT().
+			ListCollectionSet(gomock.Any(), connectionsNamespace, userID.String()).
+			Return(nil, nil),
+		store.EXPECT().
+			GetCollectionItem(gomock.Any(), onlineNamespace, userID.String()).
+			DoAndReturn(func(context.Context, string, string) ([]byte, error) {
+				return session, nil
+			}),
+		store.EXPECT().
+			CompareAndSwapCollectionItem(gomock.Any(), onlineNamespace, userID.String(), gomock.Any(), nil, time.Duration(0)).
+			Return(true, nil),
+	)
+
+	require.NoError(t, heartBeater.LaunchForUser(userID))
+	require.NoError(t, heartBeater.AwaitRecord(context.Background(), userID))
+	<-added
+
+	require.NoError(t, heartBeater.StopForUser(userID))
+	require.NoError(t, heartBeater.Close(context.Background()))
+}
+
+func TestHeartBeater_AwaitRecordFailsIfRecordIsNotStored(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = pkgmemstore.NewMockStore(ctrl)
+
+		userID      = pkgid.NewID()
+		heartBeater = NewHeartBeater(pkgtest.Instrumentation, "server-1", func() int64 { return 1 }, store)
+	)
+
+	store.EXPECT().
+		AddToCollectionSet(gomock.Any(), connectionsNamespace, userID.String(), gomock.Any(), heartBeatTTL).
+		Return(errors.New("memstore is down"))
+
+	require.Error(t, heartBeater.AwaitRecord(context.Background(), userID), "heartbeat is not launched")
+
+	require.NoError(t, heartBeater.LaunchForUser(userID))
+	require.Error(t, heartBeater.AwaitRecord(context.Background(), userID))
+}
@@ -0,0 +1,174 @@
+package rtconn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
+	"github.com/faustuzas/occa/src/pkg/memstore"
+)
+
+const (
+	// onlineNamespace holds a marker for every user connected to any server. The marker
+	// is refreshed together with the connection records and its value identifies the
+	// session, so refreshes can be told apart from the user coming online again.
+	onlineNamespace = "online-users"
+
+	// announcedNamespace holds the session of the user which has been announced online,
+	// it makes sure that every change is reported once even if many processes watch.
+	announcedNamespace = "online-users-announced"
+	announcedTTL       = 7 * 24 * time.Hour
+
+	maxOnlineUpdates = 3
+)
+
+// OnlineChange tells that the user has come online or gone offline.
+type OnlineChange struct {
+	UserID pkgid.ID
+	Online bool
+}
+
+// OnlineUsers tracks whether users are connected to any event server at all.
+type OnlineUsers struct {
+	store memstore.Store
+	i     pkginstrument.Instrumentation
+}
+
+func NewOnlineUsers(i pkginstrument.Instrumentation, store memstore.Store) *OnlineUsers {
+	return &OnlineUsers{
+		store: store,
+		i:     i,
+	}
+}
+
+// MarkOnline marks the user online as long as connection records live, it has to be called
+// again with every heartbeat to keep the user online.
+func (o *OnlineUsers) MarkOnline(ctx context.Context, userID pkgid.ID) error {
+	key := userID.String()
+
+	for attempt := 0; attempt < maxOnlineUpdates; attempt++ {
+		current, err := o.store.GetCollectionItem(ctx, onlineNamespace, key)
+		if err != nil && !errors.Is(err, memstore.ErrNotFound) {
+			return fmt.Errorf("fetching online marker: %w", err)
+		}
+
+		if current == nil {
+			set, err := o.store.SetCollectionItemIfAbsent(ctx, onlineNamespace, key, []byte(pkgid.NewID().String()), heartBeatTTL)
+			if err != nil {
+				return fmt.Errorf("storing online marker: %w", err)
+			}
+			if set {
+				return nil
+			}
+			continue
+		}
+
+		// the session is kept, only the expiration is extended
+		swapped, err := o.store.CompareAndSwapCollectionItem(ctx, onlineNamespace, key, current, current, heartBeatTTL)
+		if err != nil {
+			return fmt.Errorf("refreshing online marker: %w", err)
+		}
+		if swapped {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("online marker of the user is being updated concurrently")
+}
+
+// MarkOffline marks the user offline right away.
+func (o *OnlineUsers) MarkOffline(ctx context.Context, userID pkgid.ID) error {
+	key := userID.String()
+
+	current, err := o.store.GetCollectionItem(ctx, onlineNamespace, key)
+	if errors.Is(err, memstore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetching online marker: %w", err)
+	}
+
+	// if the marker has been refreshed meanwhile, some server still has the user connected
+	if _, err = o.store.CompareAndSwapCollectionItem(ctx, onlineNamespace, key, current, nil, 0); err != nil {
+		return fmt.Errorf("removing online marker: %w", err)
+	}
+	return nil
+}
+
+// Watch reports users coming online and going offline, including those whose servers have
+// stopped refreshing the markers. Every change is reported to a single watcher, no matter how
+// many of them there are. The channel is closed when the context is cancelled or the watch
+// breaks, changes happening until it is watched again are not reported.
+func (o *OnlineUsers) Watch(ctx context.Context) (<-chan OnlineChange, error) {
+	collectionChanges, err := o.store.WatchCollection(ctx, onlineNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("watching online markers: %w", err)
+	}
+
+	changes := make(chan OnlineChange)
+	go func() {
+		defer close(changes)
+
+		for collectionChange := range collectionChanges {
+			userID := pkgid.FromString(collectionChange.Key)
+
+			change, ok, err := o.claim(ctx, userID)
+			if err != nil {
+				o.i.Logger.Warn("failed to claim online change", zap.Stringer("userId", userID), zap.Error(err))
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// claim compares the session of the user with the announced one and records the new state.
+// The state is checked rather than trusting the kind of the change, since changes can come
+// late or out of order.
+func (o *OnlineUsers) claim(ctx context.Context, userID pkgid.ID) (OnlineChange, bool, error) {
+	key := userID.String()
+
+	session, err := o.store.GetCollectionItem(ctx, onlineNamespace, key)
+	if err != nil && !errors.Is(err, memstore.ErrNotFound) {
+		return OnlineChange{}, false, fmt.Errorf("fetching online marker: %w", err)
+	}
+
+	announced, err := o.store.GetCollectionItem(ctx, announcedNamespace, key)
+	if err != nil && !errors.Is(err, memstore.ErrNotFound) {
+		return OnlineChange{}, false, fmt.Errorf("fetching announced session: %w", err)
+	}
+
+	switch {
+	case session != nil && !bytes.Equal(session, announced):
+		swapped, err := o.store.CompareAndSwapCollectionItem(ctx, announcedNamespace, key, announced, session, announcedTTL)
+		if err != nil {
+			return OnlineChange{}, false, fmt.Errorf("storing announced session: %w", err)
+		}
+		return OnlineChange{UserID: userID, Online: true}, swapped, nil
+	case session == nil && announced != nil:
+		swapped, err := o.store.CompareAndSwapCollectionItem(ctx, announcedNamespace, key, announced, nil, 0)
+		if err != nil {
+			return OnlineChange{}, false, fmt.Errorf("removing announced session: %w", err)
+		}
+		return OnlineChange{UserID: userID, Online: false}, swapped, nil
+	default:
+		// a refresh or a change which has already been reported
+		return OnlineChange{}, false, nil
+	}
+}
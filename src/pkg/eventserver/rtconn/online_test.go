@@ -0,0 +1,149 @@
+package rtconn
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestOnlineUsers_ChangesAreReportedOnce(t *testing.T) {
+	var (
+		ctrl  = gomock.NewController(t)
+		store = newOnlineStore(ctrl)
+		ctx   = context.Background()
+
+		online = NewOnlineUsers(pkgtest.Instrumentation, store)
+		userID = pkgid.NewID()
+	)
+
+	first, err := online.Watch(ctx)
+	require.NoError(t, err)
+	second, err := online.Watch(ctx)
+	require.NoError(t, err)
+
+	// both watchers learn about the marker, but only one of them reports it
+	require.NoError(t, online.MarkOnline(ctx, userID))
+	store.broadcast(pkgmemstore.CollectionChange{Key: userID.String()})
+	require.Equal(t, []OnlineChange{{UserID: userID, Online: true}}, receive(first, second))
+
+	// refreshes are not changes
+	require.NoError(t, online.MarkOnline(ctx, userID))
+	store.broadcast(pkgmemstore.CollectionChange{Key: userID.String()})
+	require.Empty(t, receive(first, second))
+
+	require.NoError(t, online.MarkOffline(ctx, userID))
+	store.broadcast(pkgmemstore.CollectionChange{Key: userID.String(), Removed: true})
+	require.Equal(t, []OnlineChange{{UserID: userID, Online: false}}, receive(first, second))
+
+	// coming back starts a new session
+	require.NoError(t, online.MarkOnline(ctx, userID))
+	store.broadcast(pkgmemstore.CollectionChange{Key: userID.String()})
+	require.Equal(t, []OnlineChange{{UserID: userID, Online: true}}, receive(first, second))
+}
+
+// receive collects changes reported by the watchers until none come for a while.
+func receive(watchers ...<-chan OnlineChange) []OnlineChange {
+	var changes []OnlineChange
+	for _, w := range watchers {
+		select {
+		case change := <-w:
+			changes = append(changes, change)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return changes
+}
+
+// onlineStore keeps items in memory and lets the test decide when watchers learn about changes.
+type onlineStore struct {
+	*pkgmemstore.MockStore
+
+	mu       sync.Mutex
+	items    map[string][]byte
+	watchers []chan pkgmemstore.CollectionChange
+}
+
+func newOnlineStore(ctrl *gomock.Controller) *onlineStore {
+	s := &onlineStore{
+		MockStore: pkgmemstore.NewMockStore(ctrl),
+		items:     map[string][]byte{},
+	}
+
+	s.EXPECT().
+		GetCollectionItem(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, collection string, key string) ([]byte, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			if v, ok := s.items[collection+":"+key]; ok {
+				return v, nil
+			}
+			return nil, pkgmemstore.ErrNotFound
+		}).
+		AnyTimes()
+
+	s.EXPECT().
+		SetCollectionItemIfAbsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, collection string, key string, value []byte, _ time.Duration) (bool, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			if _, ok := s.items[collection+":"+key]; ok {
+				return false, nil
+			}
+			s.items[collection+":"+key] = value
+			return true, nil
+		}).
+		AnyTimes()
+
+	s.EXPECT().
+		CompareAndSwapCollectionItem(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, collection string, key string, expected, value []byte, _ time.Duration) (bool, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			current, ok := s.items[collection+":"+key]
+			if ok != (expected != nil) || !bytes.Equal(current, expected) {
+				return false, nil
+			}
+			if value == nil {
+				delete(s.items, collection+":"+key)
+			} else {
+				s.items[collection+":"+key] = value
+			}
+			return true, nil
+		}).
+		AnyTimes()
+
+	s.EXPECT().
+		WatchCollection(gomock.Any(), onlineNamespace).
+		DoAndReturn(func(context.Context, string) (<-chan pkgmemstore.CollectionChange, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			w := make(chan pkgmemstore.CollectionChange, 1)
+			s.watchers = append(s.watchers, w)
+			return w, nil
+		}).
+		AnyTimes()
+
+	return s
+}
+
+func (s *onlineStore) broadcast(change pkgmemstore.CollectionChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range s.watchers {
+		w <- change
+	}
+}
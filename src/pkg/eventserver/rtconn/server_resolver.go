@@ -3,52 +3,103 @@ package rtconn
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	"go.uber.org/zap"
+
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
 	pkgid "github.com/faustuzas/occa/src/pkg/id"
 	pkginstrument "github.com/faustuzas/occa/src/pkg/instrument"
 	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
 )
 
+// ErrUserNotConnected is returned when the user is not connected to any event server.
+var ErrUserNotConnected = errors.New("user is not connected")
+
 type ServerInformation struct {
 	ServerID string
+	// Epoch of the server when the user connected, zero if unknown.
+	Epoch int64
+
+	// Affine is set when the user's connection was not found and the server is only
+	// the one the user is placed on by consistent hashing, so the user might not be there.
+	Affine bool
 }
 
-// ServerResolver resolves which event server user is connected to.
+// AffinityLocator locates the server the user is expected to connect to.
+type AffinityLocator interface {
+	ServerForUser(ctx context.Context, userID pkgid.ID) (membership.ServerInfo, error)
+}
+
+// ServerResolver resolves which event servers user is connected to.
 type ServerResolver interface {
-	Resolve(ctx context.Context, userID pkgid.ID) (ServerInformation, error)
+	// Resolve returns every server the user has at least one session with.
+	Resolve(ctx context.Context, userID pkgid.ID) ([]ServerInformation, error)
 }
 
 type serverResolver struct {
-	store pkgmemstore.Store
-	i     pkginstrument.Instrumentation
+	store    pkgmemstore.Store
+	affinity AffinityLocator
+	i        pkginstrument.Instrumentation
 }
 
-func NewServerResolver(i pkginstrument.Instrumentation, store pkgmemstore.Store) ServerResolver {
+// NewServerResolver creates a resolver of user connections. If affinity is not nil,
+// users without a known connection are resolved to their consistent hash server.
+func NewServerResolver(i pkginstrument.Instrumentation, store pkgmemstore.Store, affinity AffinityLocator) ServerResolver {
 	return &serverResolver{
-		store: store,
-		i:     i,
+		store:    store,
+		affinity: affinity,
+		i:        i,
 	}
 }
 
-func (s *serverResolver) Resolve(ctx context.Context, userID pkgid.ID) (ServerInformation, error) {
-	data, err := s.store.GetCollectionItem(ctx, connectionsNamespace, userID.String())
+func (s *serverResolver) Resolve(ctx context.Context, userID pkgid.ID) ([]ServerInformation, error) {
+	members, err := s.store.ListCollectionSet(ctx, connectionsNamespace, userID.String())
 	if err != nil {
-		return ServerInformation{}, fmt.Errorf("getting user connection info: %w", err)
+		return nil, fmt.Errorf("getting user connection info: %w", err)
+	}
+	if len(members) == 0 {
+		return s.resolveByAffinity(ctx, userID)
+	}
+
+	servers := make([]ServerInformation, 0, len(members))
+	for _, data := range members {
+		var info ConnectionInfo
+		if err = info.Unmarshall(data); err != nil {
+			return nil, fmt.Errorf("unmarshalling conn info: %w", err)
+		}
+
+		servers = append(servers, ServerInformation{
+			ServerID: info.ServerID,
+			Epoch:    info.Epoch,
+		})
 	}
 
-	var info ConnectionInfo
-	if err = info.Unmarshall(data); err != nil {
-		return ServerInformation{}, fmt.Errorf("unmarshalling conn info: %w", err)
+	return servers, nil
+}
+
+// resolveByAffinity is used when the connection record is missing, e.g. it expired
+// between heartbeats or the memstore lost it, while the user is still connected.
+func (s *serverResolver) resolveByAffinity(ctx context.Context, userID pkgid.ID) ([]ServerInformation, error) {
+	if s.affinity == nil {
+		return nil, ErrUserNotConnected
+	}
+
+	server, err := s.affinity.ServerForUser(ctx, userID)
+	if err != nil {
+		s.i.Logger.Debug("failed to locate server by affinity", zap.Stringer("userId", userID), zap.Error(err))
+		return nil, ErrUserNotConnected
 	}
 
-	return ServerInformation{
-		ServerID: info.ServerID,
-	}, nil
+	return []ServerInformation{{ServerID: server.ID, Affine: true}}, nil
 }
 
+// ConnectionInfo is stored for every server the user is connected to. It has to be
+// serialized deterministically, so repeated heartbeats refresh the same record.
 type ConnectionInfo struct {
 	ServerID string `json:"serverID"`
+	Epoch    int64  `json:"epoch,omitempty"`
 }
 
 func (i *ConnectionInfo) Marshall() ([]byte, error) {
@@ -0,0 +1,43 @@
+package rtconn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/faustuzas/occa/src/pkg/eventserver/membership"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgmemstore "github.com/faustuzas/occa/src/pkg/memstore"
+	pkgtest "github.com/faustuzas/occa/src/pkg/test"
+)
+
+func TestServerResolver_FallsBackToAffinity(t *testing.T) {
+	var (
+		ctrl   = gomock.NewController(t)
+		store  = pkgmemstore.NewMockStore(ctrl)
+		userID = pkgid.NewID()
+	)
+
+	store.EXPECT().
+		ListCollectionSet(gomock.Any(), connectionsNamespace, userID.String()).
+		Return(nil, nil).
+		Times(2)
+
+	_, err := NewServerResolver(pkgtest.Instrumentation, store, nil).Resolve(context.Background(), userID)
+	require.ErrorIs(t, err, ErrUserNotConnected)
+
+	servers, err := NewServerResolver(pkgtest.Instrumentation, store, staticAffinity{serverID: "server-1"}).
+		Resolve(context.Background(), userID)
+	require.NoError(t, err)
+	require.Equal(t, []ServerInformation{{ServerID: "server-1", Affine: true}}, servers)
+}
+
+type staticAffinity struct {
+	serverID string
+}
+
+func (a staticAffinity) ServerForUser(context.Context, pkgid.ID) (membership.ServerInfo, error) {
+	return membership.ServerInfo{ID: a.serverID}, nil
+}
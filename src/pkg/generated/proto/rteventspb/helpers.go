@@ -1,6 +1,10 @@
 package rteventspb
 
-import pkgid "github.com/faustuzas/occa/src/pkg/id"
+import (
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
 
 func NewDirectMessageEvent(senderID pkgid.ID, message string) *Event {
 	return &Event{
@@ -12,3 +16,72 @@ func NewDirectMessageEvent(senderID pkgid.ID, message string) *Event {
 		},
 	}
 }
+
+func NewGroupMessageEvent(groupID, senderID pkgid.ID, message string) *Event {
+	return &Event{
+		Payload: &Event_GroupMessage{
+			GroupMessage: &GroupMessage{
+				GroupId:  groupID.String(),
+				SenderId: senderID.String(),
+				Message:  message,
+			},
+		},
+	}
+}
+
+func NewDeliveredEvent(messageID, recipientID pkgid.ID) *Event {
+	return &Event{
+		Payload: &Event_Delivered{
+			Delivered: &Delivered{
+				MessageId:   messageID.String(),
+				RecipientId: recipientID.String(),
+			},
+		},
+	}
+}
+
+func NewReadEvent(messageID, recipientID pkgid.ID) *Event {
+	return &Event{
+		Payload: &Event_Read{
+			Read: &Read{
+				MessageId:   messageID.String(),
+				RecipientId: recipientID.String(),
+			},
+		},
+	}
+}
+
+func NewTypingEvent(senderID pkgid.ID, typing bool, expiresIn time.Duration) *Event {
+	return &Event{
+		Payload: &Event_Typing{
+			Typing: &Typing{
+				SenderId:    senderID.String(),
+				Typing:      typing,
+				ExpiresInMs: uint32(expiresIn.Milliseconds()),
+			},
+		},
+	}
+}
+
+func NewPresenceChangedEvent(userID pkgid.ID, status string) *Event {
+	return &Event{
+		Payload: &Event_PresenceChanged{
+			PresenceChanged: &PresenceChanged{
+				UserId: userID.String(),
+				Status: status,
+			},
+		},
+	}
+}
+
+func NewReconnectEvent(serverID, grpcAddress, httpAddress string) *Event {
+	return &Event{
+		Payload: &Event_Reconnect{
+			Reconnect: &Reconnect{
+				ServerId:    serverID,
+				GrpcAddress: grpcAddress,
+				HttpAddress: httpAddress,
+			},
+		},
+	}
+}
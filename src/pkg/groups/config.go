@@ -0,0 +1,18 @@
+package groups
+
+import (
+	pkgdb "github.com/faustuzas/occa/src/pkg/db"
+)
+
+type Configuration struct {
+	DB pkgdb.Configuration `yaml:"db"`
+}
+
+func (c Configuration) Build() (Store, error) {
+	gormDB, err := c.DB.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDBStore(gormDB), nil
+}
@@ -0,0 +1,219 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	pkgdb "github.com/faustuzas/occa/src/pkg/db"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgslices "github.com/faustuzas/occa/src/pkg/slices"
+)
+
+var _ Store = (*DBStore)(nil)
+
+type group struct {
+	pkgdb.BaseModel
+
+	Name     string `gorm:"size:255;not null"`
+	Archived bool   `gorm:"not null;default:false"`
+}
+
+func (group) TableName() string {
+	return "chat_groups"
+}
+
+func (g group) toGroup() Group {
+	return Group{
+		ID:        pkgid.FromString(g.ID),
+		Name:      g.Name,
+		Archived:  g.Archived,
+		CreatedAt: g.CreatedAt,
+	}
+}
+
+type member struct {
+	pkgdb.BaseModel
+
+	GroupID string `gorm:"size:36;not null;uniqueIndex:idx_group_user,priority:1"`
+	// UserID is indexed on its own to find the groups of the user
+	UserID string `gorm:"size:36;not null;uniqueIndex:idx_group_user,priority:2;index"`
+	Role   Role   `gorm:"size:16;not null"`
+}
+
+func (member) TableName() string {
+	return "chat_group_members"
+}
+
+func (m member) toMember() Member {
+	return Member{
+		UserID:   pkgid.FromString(m.UserID),
+		Role:     m.Role,
+		JoinedAt: m.CreatedAt,
+	}
+}
+
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{
+		db: db,
+	}
+}
+
+func (s *DBStore) Create(ctx context.Context, name string, ownerID pkgid.ID) (Group, error) {
+	g := group{Name: name}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&g).Error; err != nil {
+			return fmt.Errorf("inserting group: %w", err)
+		}
+
+		owner := member{GroupID: g.ID, UserID: ownerID.String(), Role: RoleOwner}
+		if err := tx.Create(&owner).Error; err != nil {
+			return fmt.Errorf("inserting owner: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Group{}, err
+	}
+
+	return g.toGroup(), nil
+}
+
+func (s *DBStore) Get(ctx context.Context, groupID pkgid.ID) (Group, error) {
+	var g group
+	err := s.db.WithContext(ctx).Take(&g, "id = ?", groupID.String()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Group{}, fmt.Errorf("%w: %s", ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return Group{}, fmt.Errorf("querying group: %w", err)
+	}
+
+	return g.toGroup(), nil
+}
+
+func (s *DBStore) Rename(ctx context.Context, groupID pkgid.ID, name string) error {
+	return s.updateGroup(ctx, groupID, "name", name)
+}
+
+func (s *DBStore) Archive(ctx context.Context, groupID pkgid.ID) error {
+	return s.updateGroup(ctx, groupID, "archived", true)
+}
+
+func (s *DBStore) updateGroup(ctx context.Context, groupID pkgid.ID, column string, value any) error {
+	result := s.db.WithContext(ctx).Model(&group{}).Where("id = ?", groupID.String()).Update(column, value)
+	if result.Error != nil {
+		return fmt.Errorf("updating group: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// MySQL does not count rows which already had the value, so the group might exist
+		if _, err := s.Get(ctx, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DBStore) GroupsOf(ctx context.Context, userID pkgid.ID) ([]Group, error) {
+	var groups []group
+	err := s.db.WithContext(ctx).
+		Joins("JOIN chat_group_members ON chat_group_members.group_id = chat_groups.id").
+		Where("chat_group_members.user_id = ?", userID.String()).
+		Order("chat_groups.created_at").
+		Find(&groups).Error
+	if err != nil {
+		return nil, fmt.Errorf("querying groups: %w", err)
+	}
+
+	return pkgslices.Map(groups, group.toGroup), nil
+}
+
+func (s *DBStore) AddMember(ctx context.Context, groupID pkgid.ID, m Member) error {
+	if _, err := s.Member(ctx, groupID, m.UserID); err == nil {
+		return fmt.Errorf("%w: %s", ErrAlreadyMember, m.UserID)
+	} else if !errors.Is(err, ErrNotMember) {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Create(&member{
+		GroupID: groupID.String(),
+		UserID:  m.UserID.String(),
+		Role:    m.Role,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("inserting member: %w", err)
+	}
+	return nil
+}
+
+func (s *DBStore) SetMemberRole(ctx context.Context, groupID pkgid.ID, userID pkgid.ID, role Role) error {
+	result := s.db.WithContext(ctx).Model(&member{}).
+		Where("group_id = ? AND user_id = ?", groupID.String(), userID.String()).
+		Update("role", role)
+	if result.Error != nil {
+		return fmt.Errorf("updating member: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Member(ctx, groupID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DBStore) RemoveMember(ctx context.Context, groupID pkgid.ID, userID pkgid.ID) error {
+	result := s.db.WithContext(ctx).
+		Where("group_id = ? AND user_id = ?", groupID.String(), userID.String()).
+		Delete(&member{})
+	if result.Error != nil {
+		return fmt.Errorf("deleting member: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrNotMember, userID)
+	}
+	return nil
+}
+
+func (s *DBStore) Member(ctx context.Context, groupID pkgid.ID, userID pkgid.ID) (Member, error) {
+	var m member
+	err := s.db.WithContext(ctx).Take(&m, "group_id = ? AND user_id = ?", groupID.String(), userID.String()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Member{}, fmt.Errorf("%w: %s", ErrNotMember, userID)
+	}
+	if err != nil {
+		return Member{}, fmt.Errorf("querying member: %w", err)
+	}
+
+	return m.toMember(), nil
+}
+
+func (s *DBStore) Members(ctx context.Context, groupID pkgid.ID) ([]Member, error) {
+	var members []member
+	err := s.db.WithContext(ctx).
+		Where("group_id = ?", groupID.String()).
+		Order("created_at").
+		Find(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("querying members: %w", err)
+	}
+
+	return pkgslices.Map(members, member.toMember), nil
+}
+
+func (s *DBStore) Start(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(group{}, member{})
+}
+
+func (s *DBStore) Close(ctx context.Context) error {
+	if db, _ := s.db.WithContext(ctx).DB(); db != nil {
+		return db.Close()
+	}
+	return nil
+}
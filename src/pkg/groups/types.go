@@ -0,0 +1,89 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgio "github.com/faustuzas/occa/src/pkg/io"
+)
+
+//go:generate sh -c "mockgen -package=groups -destination=groups_mock.go . Store"
+
+var (
+	ErrGroupNotFound = errors.New("group not found")
+	ErrNotMember     = errors.New("user is not a member of the group")
+	ErrAlreadyMember = errors.New("user is already a member of the group")
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+func (r Role) Validate() error {
+	switch r {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return nil
+	default:
+		return fmt.Errorf("unknown role %q", r)
+	}
+}
+
+// AtLeast tells whether the role grants every permission the other role does.
+func (r Role) AtLeast(other Role) bool {
+	return r.rank() >= other.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type Group struct {
+	ID        pkgid.ID  `json:"id"`
+	Name      string    `json:"name"`
+	Archived  bool      `json:"archived"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Member struct {
+	UserID   pkgid.ID  `json:"userId"`
+	Role     Role      `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Store keeps groups and their members. It does not check permissions of the callers.
+type Store interface {
+	pkgio.Closer
+
+	// Create creates the group with the owner as its only member.
+	Create(ctx context.Context, name string, ownerID pkgid.ID) (Group, error)
+	Get(ctx context.Context, groupID pkgid.ID) (Group, error)
+	Rename(ctx context.Context, groupID pkgid.ID, name string) error
+	Archive(ctx context.Context, groupID pkgid.ID) error
+
+	// GroupsOf returns the groups the user is a member of, archived ones included.
+	GroupsOf(ctx context.Context, userID pkgid.ID) ([]Group, error)
+
+	AddMember(ctx context.Context, groupID pkgid.ID, member Member) error
+	SetMemberRole(ctx context.Context, groupID pkgid.ID, userID pkgid.ID, role Role) error
+	RemoveMember(ctx context.Context, groupID pkgid.ID, userID pkgid.ID) error
+	Member(ctx context.Context, groupID pkgid.ID, userID pkgid.ID) (Member, error)
+	Members(ctx context.Context, groupID pkgid.ID) ([]Member, error)
+
+	Start(ctx context.Context) error
+}
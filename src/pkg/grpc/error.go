@@ -22,6 +22,10 @@ func Error(err error) error {
 			statusCode = codes.InvalidArgument
 		case pkgerrors.TypeUnauthorized:
 			statusCode = codes.Unauthenticated
+		case pkgerrors.TypeForbidden:
+			statusCode = codes.PermissionDenied
+		case pkgerrors.TypeNotFound:
+			statusCode = codes.NotFound
 		case pkgerrors.TypeInternalServer:
 			statusCode = codes.Internal
 		}
@@ -30,6 +30,10 @@ func DetermineHTTPError(err error) Err {
 			statusCode = http.StatusBadRequest
 		case pkgerrors.TypeUnauthorized:
 			statusCode = http.StatusUnauthorized
+		case pkgerrors.TypeForbidden:
+			statusCode = http.StatusForbidden
+		case pkgerrors.TypeNotFound:
+			statusCode = http.StatusNotFound
 		case pkgerrors.TypeInternalServer:
 			statusCode = http.StatusInternalServerError
 		}
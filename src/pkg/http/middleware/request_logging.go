@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"bufio"
+	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -38,3 +41,22 @@ func (w capturingResponseWriter) WriteHeader(code int) {
 	*w.statusCode = code
 	w.ResponseWriter.WriteHeader(code)
 }
+
+// Hijack allows long-lived connections (e.g. WebSockets) to take over the underlying connection.
+func (w capturingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+func (w capturingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w capturingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
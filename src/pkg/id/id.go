@@ -12,6 +12,14 @@ func FromString(str string) ID {
 	return ID(uuid.MustParse(str))
 }
 
+func Parse(str string) (ID, error) {
+	u, err := uuid.Parse(str)
+	if err != nil {
+		return ID{}, err
+	}
+	return ID(u), nil
+}
+
 func (id ID) String() string {
 	return uuid.UUID(id).String()
 }
@@ -35,6 +35,34 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AddToCollectionSet mocks base method.
+func (m *MockStore) AddToCollectionSet(arg0 context.Context, arg1, arg2 string, arg3 []byte, arg4 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddToCollectionSet", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddToCollectionSet indicates an expected call of AddToCollectionSet.
+func (mr *MockStoreMockRecorder) AddToCollectionSet(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToCollectionSet", reflect.TypeOf((*MockStore)(nil).AddToCollectionSet), arg0, arg1, arg2, arg3, arg4)
+}
+
+// AppendToCollectionList mocks base method.
+func (m *MockStore) AppendToCollectionList(arg0 context.Context, arg1, arg2 string, arg3 []byte, arg4 int, arg5 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendToCollectionList", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AppendToCollectionList indicates an expected call of AppendToCollectionList.
+func (mr *MockStoreMockRecorder) AppendToCollectionList(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendToCollectionList", reflect.TypeOf((*MockStore)(nil).AppendToCollectionList), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
 // Close mocks base method.
 func (m *MockStore) Close() error {
 	m.ctrl.T.Helper()
@@ -49,6 +77,81 @@ func (mr *MockStoreMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStore)(nil).Close))
 }
 
+// CompareAndSwapCollectionItem mocks base method.
+func (m *MockStore) CompareAndSwapCollectionItem(arg0 context.Context, arg1, arg2 string, arg3, arg4 []byte, arg5 time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndSwapCollectionItem", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareAndSwapCollectionItem indicates an expected call of CompareAndSwapCollectionItem.
+func (mr *MockStoreMockRecorder) CompareAndSwapCollectionItem(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndSwapCollectionItem", reflect.TypeOf((*MockStore)(nil).CompareAndSwapCollectionItem), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// GetCollectionItem mocks base method.
+func (m *MockStore) GetCollectionItem(arg0 context.Context, arg1, arg2 string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCollectionItem", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCollectionItem indicates an expected call of GetCollectionItem.
+func (mr *MockStoreMockRecorder) GetCollectionItem(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollectionItem", reflect.TypeOf((*MockStore)(nil).GetCollectionItem), arg0, arg1, arg2)
+}
+
+// GetCollectionList mocks base method.
+func (m *MockStore) GetCollectionList(arg0 context.Context, arg1, arg2 string) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCollectionList", arg0, arg1, arg2)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCollectionList indicates an expected call of GetCollectionList.
+func (mr *MockStoreMockRecorder) GetCollectionList(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollectionList", reflect.TypeOf((*MockStore)(nil).GetCollectionList), arg0, arg1, arg2)
+}
+
+// IncrementCollectionCounter mocks base method.
+func (m *MockStore) IncrementCollectionCounter(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementCollectionCounter", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementCollectionCounter indicates an expected call of IncrementCollectionCounter.
+func (mr *MockStoreMockRecorder) IncrementCollectionCounter(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementCollectionCounter", reflect.TypeOf((*MockStore)(nil).IncrementCollectionCounter), arg0, arg1, arg2, arg3)
+}
+
+// ListCollection mocks base method.
+func (m *MockStore) ListCollection(arg0 context.Context, arg1 string) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCollection", arg0, arg1)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCollection indicates an expected call of ListCollection.
+func (mr *MockStoreMockRecorder) ListCollection(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCollection", reflect.TypeOf((*MockStore)(nil).ListCollection), arg0, arg1)
+}
+
 // ListCollectionKeys mocks base method.
 func (m *MockStore) ListCollectionKeys(arg0 context.Context, arg1 string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -64,8 +167,67 @@ func (mr *MockStoreMockRecorder) ListCollectionKeys(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCollectionKeys", reflect.TypeOf((*MockStore)(nil).ListCollectionKeys), arg0, arg1)
 }
 
+// ListCollectionSet mocks base method.
+func (m *MockStore) ListCollectionSet(arg0 context.Context, arg1, arg2 string) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCollectionSet", arg0, arg1, arg2)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCollectionSet indicates an expected call of ListCollectionSet.
+func (mr *MockStoreMockRecorder) ListCollectionSet(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCollectionSet", reflect.TypeOf((*MockStore)(nil).ListCollectionSet), arg0, arg1, arg2)
+}
+
+// PopCollectionList mocks base method.
+func (m *MockStore) PopCollectionList(arg0 context.Context, arg1, arg2 string) ([][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PopCollectionList", arg0, arg1, arg2)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PopCollectionList indicates an expected call of PopCollectionList.
+func (mr *MockStoreMockRecorder) PopCollectionList(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PopCollectionList", reflect.TypeOf((*MockStore)(nil).PopCollectionList), arg0, arg1, arg2)
+}
+
+// RemoveFromCollectionSet mocks base method.
+func (m *MockStore) RemoveFromCollectionSet(arg0 context.Context, arg1, arg2 string, arg3 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFromCollectionSet", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFromCollectionSet indicates an expected call of RemoveFromCollectionSet.
+func (mr *MockStoreMockRecorder) RemoveFromCollectionSet(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFromCollectionSet", reflect.TypeOf((*MockStore)(nil).RemoveFromCollectionSet), arg0, arg1, arg2, arg3)
+}
+
+// SetCollectionItemIfAbsent mocks base method.
+func (m *MockStore) SetCollectionItemIfAbsent(arg0 context.Context, arg1, arg2 string, arg3 []byte, arg4 time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCollectionItemIfAbsent", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetCollectionItemIfAbsent indicates an expected call of SetCollectionItemIfAbsent.
+func (mr *MockStoreMockRecorder) SetCollectionItemIfAbsent(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCollectionItemIfAbsent", reflect.TypeOf((*MockStore)(nil).SetCollectionItemIfAbsent), arg0, arg1, arg2, arg3, arg4)
+}
+
 // SetCollectionItemWithTTL mocks base method.
-func (m *MockStore) SetCollectionItemWithTTL(arg0 context.Context, arg1, arg2, arg3 string, arg4 time.Duration) error {
+func (m *MockStore) SetCollectionItemWithTTL(arg0 context.Context, arg1, arg2 string, arg3 []byte, arg4 time.Duration) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetCollectionItemWithTTL", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(error)
@@ -77,3 +239,18 @@ func (mr *MockStoreMockRecorder) SetCollectionItemWithTTL(arg0, arg1, arg2, arg3
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCollectionItemWithTTL", reflect.TypeOf((*MockStore)(nil).SetCollectionItemWithTTL), arg0, arg1, arg2, arg3, arg4)
 }
+
+// WatchCollection mocks base method.
+func (m *MockStore) WatchCollection(arg0 context.Context, arg1 string) (<-chan CollectionChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchCollection", arg0, arg1)
+	ret0, _ := ret[0].(<-chan CollectionChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchCollection indicates an expected call of WatchCollection.
+func (mr *MockStoreMockRecorder) WatchCollection(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchCollection", reflect.TypeOf((*MockStore)(nil).WatchCollection), arg0, arg1)
+}
@@ -1,8 +1,12 @@
 package memstore
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +16,10 @@ import (
 
 const (
 	separator = ":"
+
+	// keyspaceEvents are the notifications WatchCollection relies on: keyspace events (K)
+	// of generic commands (g), string commands ($) and expirations (x)
+	keyspaceEvents = "Kg$x"
 )
 
 type RedisClient struct {
@@ -28,6 +36,9 @@ func NewRedisClient(c *redis.Client, prefix string) RedisClient {
 
 func (c RedisClient) GetCollectionItem(ctx context.Context, collection string, key string) ([]byte, error) {
 	strResult, err := c.c.Get(ctx, c.collectionKey(collection, key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("getting item: %w", err)
 	}
@@ -39,6 +50,137 @@ func (c RedisClient) SetCollectionItemWithTTL(ctx context.Context, collection st
 	return c.c.Set(ctx, c.collectionKey(collection, key), value, ttl).Err()
 }
 
+func (c RedisClient) SetCollectionItemIfAbsent(ctx context.Context, collection string, key string, value []byte, ttl time.Duration) (bool, error) {
+	ok, err := c.c.SetNX(ctx, c.collectionKey(collection, key), value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("setting item: %w", err)
+	}
+	return ok, nil
+}
+
+func (c RedisClient) CompareAndSwapCollectionItem(ctx context.Context, collection string, key string, expected, value []byte, ttl time.Duration) (bool, error) {
+	itemKey := c.collectionKey(collection, key)
+
+	swapped := false
+	err := c.c.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, itemKey).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if !bytes.Equal(current, expected) || (errors.Is(err, redis.Nil) != (expected == nil)) {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			if value == nil {
+				p.Del(ctx, itemKey)
+			} else {
+				p.Set(ctx, itemKey, value, ttl)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		swapped = true
+		return nil
+	}, itemKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("swapping item: %w", err)
+	}
+
+	return swapped, nil
+}
+
+func (c RedisClient) WatchCollection(ctx context.Context, collection string) (<-chan CollectionChange, error) {
+	if err := c.enableKeyspaceEvents(ctx); err != nil {
+		return nil, err
+	}
+
+	keyPrefix := c.collectionKey(collection, "")
+
+	pubSub := c.c.PSubscribe(ctx, "__keyspace@*__:"+keyPrefix+"*")
+	if _, err := pubSub.Receive(ctx); err != nil {
+		_ = pubSub.Close()
+		return nil, fmt.Errorf("subscribing to keyspace events: %w", err)
+	}
+
+	changes := make(chan CollectionChange)
+	go func() {
+		defer close(changes)
+		defer func() {
+			_ = pubSub.Close()
+		}()
+
+		messages := pubSub.Channel()
+		for {
+			var msg *redis.Message
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-messages:
+				if !ok {
+					return
+				}
+				msg = m
+			}
+
+			// channels look like __keyspace@0__:<key>, payloads are names of the commands
+			_, itemKey, ok := strings.Cut(msg.Channel, "__:")
+			if !ok || !strings.HasPrefix(itemKey, keyPrefix) {
+				continue
+			}
+
+			change := CollectionChange{Key: itemKey[len(keyPrefix):]}
+			switch msg.Payload {
+			case "set":
+			case "del", "expired", "evicted":
+				change.Removed = true
+			default:
+				continue
+			}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// enableKeyspaceEvents makes sure Redis publishes the notifications needed by WatchCollection.
+func (c RedisClient) enableKeyspaceEvents(ctx context.Context) error {
+	current, err := c.c.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("getting keyspace events configuration: %w", err)
+	}
+
+	flags := current["notify-keyspace-events"]
+
+	missing := ""
+	for _, flag := range keyspaceEvents {
+		// A is an alias for all the event classes
+		if !strings.ContainsRune(flags, flag) && (flag == 'K' || !strings.ContainsRune(flags, 'A')) {
+			missing += string(flag)
+		}
+	}
+	if missing == "" {
+		return nil
+	}
+
+	if err = c.c.ConfigSet(ctx, "notify-keyspace-events", flags+missing).Err(); err != nil {
+		return fmt.Errorf("enabling keyspace events %q: %w", missing, err)
+	}
+	return nil
+}
+
 func (c RedisClient) ListCollectionKeys(ctx context.Context, collection string) ([]string, error) {
 	strResult, err := c.c.Keys(ctx, c.collectionKey(collection, "*")).Result()
 	if err != nil {
@@ -86,6 +228,112 @@ func (c RedisClient) ListCollection(ctx context.Context, collection string) ([][
 	}), nil
 }
 
+func (c RedisClient) AppendToCollectionList(ctx context.Context, collection string, key string, value []byte, maxLen int, ttl time.Duration) error {
+	listKey := c.collectionKey(collection, key)
+
+	_, err := c.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.RPush(ctx, listKey, value)
+		if maxLen > 0 {
+			p.LTrim(ctx, listKey, int64(-maxLen), -1)
+		}
+		if ttl > 0 {
+			p.Expire(ctx, listKey, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("appending to list: %w", err)
+	}
+
+	return nil
+}
+
+func (c RedisClient) PopCollectionList(ctx context.Context, collection string, key string) ([][]byte, error) {
+	listKey := c.collectionKey(collection, key)
+
+	var rangeCmd *redis.StringSliceCmd
+	_, err := c.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		rangeCmd = p.LRange(ctx, listKey, 0, -1)
+		p.Del(ctx, listKey)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("popping list: %w", err)
+	}
+
+	return pkgslices.Map(rangeCmd.Val(), func(v string) []byte {
+		return []byte(v)
+	}), nil
+}
+
+func (c RedisClient) GetCollectionList(ctx context.Context, collection string, key string) ([][]byte, error) {
+	values, err := c.c.LRange(ctx, c.collectionKey(collection, key), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting list: %w", err)
+	}
+
+	return pkgslices.Map(values, func(v string) []byte {
+		return []byte(v)
+	}), nil
+}
+
+func (c RedisClient) IncrementCollectionCounter(ctx context.Context, collection string, key string, ttl time.Duration) (int64, error) {
+	counterKey := c.collectionKey(collection, key)
+
+	var incrCmd *redis.IntCmd
+	_, err := c.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incrCmd = p.Incr(ctx, counterKey)
+		if ttl > 0 {
+			p.Expire(ctx, counterKey, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("incrementing counter: %w", err)
+	}
+
+	return incrCmd.Val(), nil
+}
+
+// AddToCollectionSet keeps members in a sorted set scored by their expiration time.
+func (c RedisClient) AddToCollectionSet(ctx context.Context, collection string, key string, member []byte, ttl time.Duration) error {
+	setKey := c.collectionKey(collection, key)
+	now := time.Now()
+
+	_, err := c.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZAdd(ctx, setKey, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: member})
+		p.ZRemRangeByScore(ctx, setKey, "-inf", fmt.Sprintf("(%d", now.UnixMilli()))
+		p.PExpire(ctx, setKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("adding to set: %w", err)
+	}
+
+	return nil
+}
+
+func (c RedisClient) RemoveFromCollectionSet(ctx context.Context, collection string, key string, member []byte) error {
+	if err := c.c.ZRem(ctx, c.collectionKey(collection, key), member).Err(); err != nil {
+		return fmt.Errorf("removing from set: %w", err)
+	}
+	return nil
+}
+
+func (c RedisClient) ListCollectionSet(ctx context.Context, collection string, key string) ([][]byte, error) {
+	members, err := c.c.ZRangeByScore(ctx, c.collectionKey(collection, key), &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("listing set: %w", err)
+	}
+
+	return pkgslices.Map(members, func(v string) []byte {
+		return []byte(v)
+	}), nil
+}
+
 func (c RedisClient) Close() error {
 	return c.c.Close()
 }
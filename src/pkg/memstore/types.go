@@ -2,17 +2,65 @@ package memstore
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotFound is returned when the requested item does not exist in the store.
+var ErrNotFound = errors.New("item not found")
+
+// CollectionChange describes an item of a collection which has been set or removed.
+type CollectionChange struct {
+	Key     string
+	Removed bool
+}
+
 //go:generate sh -c "mockgen -package=memstore -destination=memstore_mock.go . Store"
 
 type Store interface {
 	GetCollectionItem(ctx context.Context, collection string, key string) ([]byte, error)
 	SetCollectionItemWithTTL(ctx context.Context, collection string, key string, value []byte, ttl time.Duration) error
 
+	// SetCollectionItemIfAbsent sets the item only if it does not exist. Returns whether it was set.
+	SetCollectionItemIfAbsent(ctx context.Context, collection string, key string, value []byte, ttl time.Duration) (bool, error)
+
+	// CompareAndSwapCollectionItem atomically replaces the item with the value only if its current
+	// value is equal to the expected one, nil expected value means the item must not exist and
+	// nil value removes the item. Returns whether it was replaced.
+	CompareAndSwapCollectionItem(ctx context.Context, collection string, key string, expected, value []byte, ttl time.Duration) (bool, error)
+
+	// WatchCollection reports items of the collection which are set or removed, including expired ones.
+	// The channel is closed when the context is cancelled or the subscription breaks. Changes are
+	// delivered on a best effort basis, they are lost if the subscription is reconnecting.
+	WatchCollection(ctx context.Context, collection string) (<-chan CollectionChange, error)
+
 	ListCollectionKeys(ctx context.Context, collection string) ([]string, error)
 	ListCollection(ctx context.Context, collection string) ([][]byte, error)
 
+	// AppendToCollectionList appends the value to the end of the list stored under the key. The list
+	// is trimmed to contain at most maxLen newest values and its expiration is reset to ttl.
+	// Zero maxLen or ttl means no limit.
+	AppendToCollectionList(ctx context.Context, collection string, key string, value []byte, maxLen int, ttl time.Duration) error
+
+	// PopCollectionList atomically removes the whole list stored under the key and returns its values.
+	PopCollectionList(ctx context.Context, collection string, key string) ([][]byte, error)
+
+	// GetCollectionList returns all values of the list stored under the key, from the oldest to the newest.
+	GetCollectionList(ctx context.Context, collection string, key string) ([][]byte, error)
+
+	// IncrementCollectionCounter atomically increments the counter stored under the key and returns
+	// its new value. Missing counters start from zero. The expiration is reset to ttl, zero means no limit.
+	IncrementCollectionCounter(ctx context.Context, collection string, key string, ttl time.Duration) (int64, error)
+
+	// AddToCollectionSet adds the member to the set stored under the key. The member expires after
+	// ttl unless it is added again, the whole set expires together with its last member.
+	AddToCollectionSet(ctx context.Context, collection string, key string, member []byte, ttl time.Duration) error
+
+	// RemoveFromCollectionSet removes the member from the set stored under the key.
+	RemoveFromCollectionSet(ctx context.Context, collection string, key string, member []byte) error
+
+	// ListCollectionSet returns not expired members of the set stored under the key.
+	ListCollectionSet(ctx context.Context, collection string, key string) ([][]byte, error)
+
 	Close() error
 }
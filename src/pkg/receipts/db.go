@@ -0,0 +1,136 @@
+package receipts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	pkgdb "github.com/faustuzas/occa/src/pkg/db"
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+)
+
+var _ Store = (*DBStore)(nil)
+
+type receiptState struct {
+	pkgdb.BaseModel
+
+	SenderID    string `gorm:"size:36;not null;uniqueIndex:idx_sender_recipient,priority:1"`
+	RecipientID string `gorm:"size:36;not null;uniqueIndex:idx_sender_recipient,priority:2"`
+
+	DeliveredMessageID *string `gorm:"size:36"`
+	DeliveredSentAt    *time.Time
+	DeliveredAt        *time.Time
+	ReadMessageID      *string `gorm:"size:36"`
+	ReadSentAt         *time.Time
+	ReadAt             *time.Time
+}
+
+func (receiptState) TableName() string {
+	return "receipt_states"
+}
+
+func (r receiptState) toState() State {
+	state := State{
+		SenderID:    pkgid.FromString(r.SenderID),
+		RecipientID: pkgid.FromString(r.RecipientID),
+	}
+	if r.DeliveredMessageID != nil && r.DeliveredAt != nil {
+		state.Delivered = &Mark{MessageID: pkgid.FromString(*r.DeliveredMessageID), At: *r.DeliveredAt}
+	}
+	if r.ReadMessageID != nil && r.ReadAt != nil {
+		state.Read = &Mark{MessageID: pkgid.FromString(*r.ReadMessageID), At: *r.ReadAt}
+	}
+	return state
+}
+
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{
+		db: db,
+	}
+}
+
+func (s *DBStore) Record(ctx context.Context, receipt Receipt) error {
+	var (
+		messageID = receipt.MessageID.String()
+		sentAt    = receipt.MessageSentAt.UTC()
+		at        = receipt.At.UTC()
+		state     = receiptState{
+			SenderID:           receipt.SenderID.String(),
+			RecipientID:        receipt.RecipientID.String(),
+			DeliveredMessageID: &messageID,
+			DeliveredSentAt:    &sentAt,
+			DeliveredAt:        &at,
+		}
+		updates = append(forwardOnly("delivered"), clause.Assignment{
+			Column: clause.Column{Name: "updated_at"},
+			Value:  gorm.Expr("VALUES(updated_at)"),
+		})
+	)
+	if receipt.Kind == KindRead {
+		state.ReadMessageID, state.ReadSentAt, state.ReadAt = &messageID, &sentAt, &at
+		updates = append(updates, forwardOnly("read")...)
+	}
+
+	err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "sender_id"}, {Name: "recipient_id"}},
+			DoUpdates: updates,
+		}).
+		Create(&state).Error
+	if err != nil {
+		return fmt.Errorf("storing receipt: %w", err)
+	}
+	return nil
+}
+
+// forwardOnly updates the mark of the kind only if the new message was sent after the
+// current one. MySQL assigns columns from left to right, so the column compared has to
+// be the last one updated.
+func forwardOnly(kind string) clause.Set {
+	var (
+		sentAtColumn = kind + "_sent_at"
+		newer        = fmt.Sprintf("%[1]s IS NULL OR VALUES(%[1]s) > %[1]s", sentAtColumn)
+	)
+
+	set := make(clause.Set, 0, 3)
+	for _, column := range []string{kind + "_message_id", kind + "_at", sentAtColumn} {
+		set = append(set, clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr(fmt.Sprintf("IF(%s, VALUES(%s), %s)", newer, column, column)),
+		})
+	}
+	return set
+}
+
+func (s *DBStore) State(ctx context.Context, senderID, recipientID pkgid.ID) (State, error) {
+	var state receiptState
+	err := s.db.WithContext(ctx).
+		Take(&state, "sender_id = ? AND recipient_id = ?", senderID.String(), recipientID.String()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return State{SenderID: senderID, RecipientID: recipientID}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("querying receipt state: %w", err)
+	}
+
+	return state.toState(), nil
+}
+
+func (s *DBStore) Start(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(receiptState{})
+}
+
+func (s *DBStore) Close(ctx context.Context) error {
+	if db, _ := s.db.WithContext(ctx).DB(); db != nil {
+		return db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package receipts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pkgid "github.com/faustuzas/occa/src/pkg/id"
+	pkgio "github.com/faustuzas/occa/src/pkg/io"
+)
+
+//go:generate sh -c "mockgen -package=receipts -destination=receipts_mock.go . Store"
+
+type Kind string
+
+const (
+	KindDelivered Kind = "delivered"
+	KindRead      Kind = "read"
+)
+
+func (k Kind) Validate() error {
+	switch k {
+	case KindDelivered, KindRead:
+		return nil
+	default:
+		return fmt.Errorf("unknown receipt kind %q", k)
+	}
+}
+
+// Receipt is sent back by the recipient of the message.
+type Receipt struct {
+	Kind      Kind
+	MessageID pkgid.ID
+
+	// MessageSentAt orders receipts of the conversation, so a late receipt of an older
+	// message does not move the state back.
+	MessageSentAt time.Time
+
+	SenderID    pkgid.ID
+	RecipientID pkgid.ID
+	At          time.Time
+}
+
+// Mark points to the latest message a receipt of some kind was reported for.
+type Mark struct {
+	MessageID pkgid.ID  `json:"messageId"`
+	At        time.Time `json:"at"`
+}
+
+// State is the latest receipt state of the messages the sender has sent to the recipient.
+type State struct {
+	SenderID    pkgid.ID `json:"senderId"`
+	RecipientID pkgid.ID `json:"recipientId"`
+
+	// Delivered and Read are nil until the recipient reports the first receipt of the kind.
+	Delivered *Mark `json:"delivered,omitempty"`
+	Read      *Mark `json:"read,omitempty"`
+}
+
+// Store keeps the latest receipt state per direction of every conversation.
+type Store interface {
+	pkgio.Closer
+
+	// Record moves the state to the message of the receipt unless it already points to a
+	// newer message. A read message is considered delivered as well.
+	Record(ctx context.Context, receipt Receipt) error
+
+	State(ctx context.Context, senderID, recipientID pkgid.ID) (State, error)
+
+	Start(ctx context.Context) error
+}